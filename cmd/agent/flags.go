@@ -1,8 +1,11 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/Chystik/runtime-metrics/config"
 )
@@ -16,6 +19,8 @@ func parseFlags(cfg *config.AgentConfig) error {
 	_ = flag.Value(cfg)
 	_ = flag.Value(&cfg.PollInterval)
 	_ = flag.Value(&cfg.ReportInterval)
+	_ = flag.Value(&cfg.Labels)
+	_ = flag.Value(&cfg.Collectors)
 
 	var configFileShort, conigFile string
 
@@ -29,6 +34,52 @@ func parseFlags(cfg *config.AgentConfig) error {
 	flag.StringVar(&cfg.SHAkey, "k", "", "sha key")
 	flag.StringVar(&cfg.CryptoKey, "crypto-key", "", "public key (CRT) file path")
 	flag.IntVar(&cfg.RateLimit, "l", 1, "report metrics rate limiter")
+	flag.Var(&cfg.Labels, "labels", "labels attached to every metric in a form k1=v1,k2=v2")
+	flag.StringVar(&cfg.Instance, "instance", "", "instance label attached to every metric")
+	flag.BoolVar(&cfg.HostLabel, "host-label", false, "attach host label with the hostname to every metric")
+	flag.Func("gc-pause-buckets", "GC pause histogram bucket bounds in nanoseconds, comma separated", func(s string) error {
+		b, err := parseBuckets(s)
+		if err != nil {
+			return err
+		}
+		cfg.GCPauseBuckets = b
+		return nil
+	})
+	flag.Var(&cfg.Collectors, "collectors", "collectors config in a form name1:off,name2:5s,name3:on, only memstats and system are enabled by default")
+	flag.Func("process", "watched process in a form pid:123, pidfile:/run/app.pid or name:app, can be repeated", func(s string) error {
+		cfg.Processes = append(cfg.Processes, s)
+		return nil
+	})
+	flag.StringVar(&cfg.CgroupPath, "cgroup-path", cfg.CgroupPath, "cgroup v2 directory of the container")
+	flag.Func("scrape", "URL of Prometheus text or expvar JSON metrics to scrape, can be repeated", func(s string) error {
+		cfg.ScrapeTargets = append(cfg.ScrapeTargets, s)
+		return nil
+	})
+	flag.Func("exec", "command in a form name=command run by the shell, its output is parsed into metrics, can be repeated", func(s string) error {
+		c, err := config.ParseExecCommand(s)
+		if err != nil {
+			return err
+		}
+		cfg.Exec = append(cfg.Exec, c)
+		return nil
+	})
+	flag.Func("probe", "probe target in a form http://host/path, https://host/path or tcp://host:port, can be repeated", func(s string) error {
+		cfg.Probes = append(cfg.Probes, s)
+		return nil
+	})
+	flag.StringVar(&cfg.SpoolDir, "spool-dir", "", "directory where batches failed to be sent are kept and replayed from")
+	flag.Int64Var(&cfg.SpoolMaxSize, "spool-max-size", cfg.SpoolMaxSize, "spool size limit in bytes, the oldest batches are dropped above it")
+	flag.Func("spool-max-age", "spooled batches older than this duration like 24h are dropped", func(s string) error {
+		return cfg.SpoolMaxAge.UnmarshalText([]byte(s))
+	})
+	flag.Func("destination", "destination in a form name=http://host:port or name=grpc://host:port, can be repeated, the agent address is used if none", func(s string) error {
+		d, err := config.ParseDestination(s)
+		if err != nil {
+			return err
+		}
+		cfg.Destinations = append(cfg.Destinations, d)
+		return nil
+	})
 	flag.StringVar(&cfg.ProfileConfig.CPUFilePath, "cpu", "", "pprof CPU out profile")
 	flag.StringVar(&cfg.ProfileConfig.MemFilePath, "mem", "", "pprof Memory out profile")
 
@@ -44,3 +95,18 @@ func parseFlags(cfg *config.AgentConfig) error {
 
 	return nil
 }
+
+func parseBuckets(s string) ([]float64, error) {
+	parts := strings.Split(s, ",")
+	res := make([]float64, len(parts))
+
+	for i := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(parts[i]), 64)
+		if err != nil {
+			return nil, errors.New("only numbers allowed for bucket bounds")
+		}
+		res[i] = v
+	}
+
+	return res, nil
+}
@@ -30,6 +30,21 @@ func parseFlags(cfg *config.ServerConfig) error {
 	flag.Var(cfg, "a", "Net address host:port of http server")
 	flag.StringVar(&cfg.AddressGRPC, "g", "", "Net address host:port of grpc server")
 	flag.StringVar(&cfg.TrustedSubnet, "t", "", "trusted subnet in CIDR format")
+	flag.StringVar(&cfg.StatsDAddress, "statsd", "", "Net address host:port of StatsD UDP listener, disabled if empty")
+	flag.Func("statsd-flush-interval", "how often aggregated StatsD metrics are stored, like 10s", func(s string) error {
+		return cfg.StatsDFlushInterval.UnmarshalText([]byte(s))
+	})
+	flag.StringVar(&cfg.GraphiteAddress, "graphite", "", "Net address host:port of Graphite TCP listener, disabled if empty")
+	flag.Func("graphite-template", "Graphite template \"[filter ]pattern\", can be repeated", func(s string) error {
+		cfg.GraphiteTemplates = append(cfg.GraphiteTemplates, s)
+		return nil
+	})
+	flag.Func("batch-retention", "how long batch IDs are remembered to ignore resent batches, like 24h", func(s string) error {
+		return cfg.BatchRetention.UnmarshalText([]byte(s))
+	})
+	flag.Func("samples-retention", "how long metric samples are kept, like 24h, 0 keeps them", func(s string) error {
+		return cfg.SamplesRetention.UnmarshalText([]byte(s))
+	})
 	flag.StringVar(&cfg.ProfileConfig.CPUFilePath, "cpu", "", "pprof CPU out profile")
 	flag.StringVar(&cfg.ProfileConfig.MemFilePath, "mem", "", "pprof Memory out profile")
 
@@ -1,11 +1,14 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
 )
 
 const (
@@ -24,6 +27,23 @@ type (
 		SHAkey         string         `env:"KEY"`
 		CryptoKey      string         `env:"CRYPTO_KEY" json:"crypto_key"`
 		RateLimit      int            `env:"RATE_LIMIT"`
+		Labels         Labels         `env:"LABELS" json:"labels"`
+		Instance       string         `env:"INSTANCE" json:"instance"`
+		HostLabel      bool           `env:"HOST_LABEL" json:"host_label"`
+		GCPauseBuckets []float64      `env:"GC_PAUSE_BUCKETS" json:"gc_pause_buckets"`
+		Collectors     Collectors     `env:"COLLECTORS" json:"collectors"`
+		Processes      []string       `env:"PROCESSES" json:"processes"`
+		CgroupPath     string         `env:"CGROUP_PATH" json:"cgroup_path"`
+		ScrapeTargets  []string       `env:"SCRAPE_TARGETS" json:"scrape_targets"`
+		Exec           []ExecCommand  `json:"exec"`
+		LogFiles       []LogFile      `json:"log_files"`
+		Probes         []string       `env:"PROBES" json:"probes"`
+		SpoolDir       string         `env:"SPOOL_DIR" json:"spool_dir"`
+		SpoolMaxSize   int64          `env:"SPOOL_MAX_SIZE" json:"spool_max_size"`
+		// SpoolMaxAge must not exceed the batch retention of the server, a batch replayed
+		// after the server forgot its ID is applied twice
+		SpoolMaxAge  Duration      `env:"SPOOL_MAX_AGE" json:"spool_max_age"`
+		Destinations []Destination `json:"destinations"`
 		CollectableMetrics
 		ProfileConfig ProfileConfig
 	}
@@ -35,7 +55,12 @@ type (
 		time.Duration `env:"REPORT_INTERVAL"`
 	}
 
+	// CollectableMetrics are runtime.MemStats field names and runtime/metrics sample
+	// patterns starting with a slash like /sched/*, where * matches any characters
 	CollectableMetrics []string
+
+	// Labels are attached to every reported metric
+	Labels map[string]string
 )
 
 func NewAgentCfg() *AgentConfig {
@@ -45,6 +70,10 @@ func NewAgentCfg() *AgentConfig {
 		PollInterval:       PollInterval{Duration: 2 * time.Second},
 		ReportInterval:     ReportInterval{Duration: 10 * time.Second},
 		CollectableMetrics: []string{"Alloc", "BuckHashSys", "Frees", "GCCPUFraction", "GCSys", "HeapAlloc", "HeapIdle", "HeapInuse", "HeapObjects", "HeapReleased", "HeapSys", "LastGC", "Lookups", "MCacheInuse", "MCacheSys", "MSpanInuse", "MSpanSys", "Mallocs", "NextGC", "NumForcedGC", "NumGC", "OtherSys", "PauseTotalNs", "StackInuse", "StackSys", "Sys", "TotalAlloc"},
+		GCPauseBuckets:     []float64{1e4, 5e4, 1e5, 2.5e5, 5e5, 1e6, 2.5e6, 5e6, 1e7, 5e7}, // nanoseconds
+		CgroupPath:         "/sys/fs/cgroup",
+		SpoolMaxSize:       64 << 20, // bytes
+		SpoolMaxAge:        Duration{Duration: DefaultBatchRetention},
 		ProfileConfig:      ProfileConfig{},
 	}
 
@@ -107,3 +136,46 @@ func (ri *ReportInterval) UnmarshalJSON(b []byte) (err error) {
 	ri.Duration = t
 	return
 }
+
+func (l Labels) String() string {
+	return models.Labels(l).String()
+}
+
+// Set adds labels in a form k1=v1,k2=v2
+func (l *Labels) Set(s string) error {
+	parsed, err := models.ParseLabels(s)
+	if err != nil {
+		return err
+	}
+	if *l == nil {
+		*l = make(Labels, len(parsed))
+	}
+	for k, v := range parsed {
+		(*l)[k] = v
+	}
+	return nil
+}
+
+func (l *Labels) UnmarshalText(b []byte) error {
+	return l.Set(string(b))
+}
+
+// UnmarshalJSON accepts labels as an object or as a string in a form k1=v1,k2=v2
+func (l *Labels) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		return l.Set(s)
+	}
+
+	var m map[string]string
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	if *l == nil {
+		*l = make(Labels, len(m))
+	}
+	for k, v := range m {
+		(*l)[k] = v
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+type (
+	// Collectors configure agent collectors by name, a collector that is not listed
+	// keeps its default state, enabled collectors are polled every poll interval
+	Collectors map[string]CollectorConfig
+
+	CollectorConfig struct {
+		Disabled bool     `json:"disabled"`
+		Interval Duration `json:"interval"`
+	}
+
+	// Duration is parsed from a string like 1m30s
+	Duration struct {
+		time.Duration
+	}
+)
+
+// Enabled reports whether the listed collector is not disabled, def is returned for
+// a collector that is not listed
+func (c Collectors) Enabled(name string, def bool) bool {
+	cc, ok := c[name]
+	if !ok {
+		return def
+	}
+	return !cc.Disabled
+}
+
+// Interval returns the configured collector interval or def if it is not set
+func (c Collectors) Interval(name string, def time.Duration) time.Duration {
+	if d := c[name].Interval.Duration; d > 0 {
+		return d
+	}
+	return def
+}
+
+func (c Collectors) String() string {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		switch {
+		case c[name].Disabled:
+			names[i] += ":off"
+		case c[name].Interval.Duration > 0:
+			names[i] += ":" + c[name].Interval.String()
+		}
+	}
+
+	return strings.Join(names, ",")
+}
+
+// Set configures collectors in a form name1:off,name2:5s,name3:on
+func (c *Collectors) Set(s string) error {
+	if *c == nil {
+		*c = make(Collectors)
+	}
+
+	for _, p := range strings.Split(s, ",") {
+		name, opt, _ := strings.Cut(strings.TrimSpace(p), ":")
+		if name == "" {
+			return fmt.Errorf("expect collectors in a form name:off,name:5s, got %q", s)
+		}
+
+		cc := (*c)[name]
+		switch opt {
+		case "", "on":
+			cc.Disabled = false
+		case "off":
+			cc.Disabled = true
+		default:
+			d, err := time.ParseDuration(opt)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("bad interval of collector %s: %q", name, opt)
+			}
+			cc.Disabled = false
+			cc.Interval.Duration = d
+		}
+		(*c)[name] = cc
+	}
+
+	return nil
+}
+
+func (c *Collectors) UnmarshalText(b []byte) error {
+	return c.Set(string(b))
+}
+
+// UnmarshalJSON accepts collectors as an object or as a string in a form name1:off,name2:5s
+func (c *Collectors) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		return c.Set(s)
+	}
+
+	var m map[string]CollectorConfig
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	if *c == nil {
+		*c = make(Collectors, len(m))
+	}
+	for k, v := range m {
+		(*c)[k] = v
+	}
+	return nil
+}
+
+func (d *Duration) UnmarshalText(b []byte) (err error) {
+	d.Duration, err = time.ParseDuration(string(b))
+	return
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return d.UnmarshalText([]byte(s))
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultDestination names the destination of the agent address when no destinations are configured
+const DefaultDestination = "default"
+
+// Destination is a server the agent reports to, empty keys, zero spool limits and
+// rate limit are taken from the agent config
+type Destination struct {
+	Name          string        `json:"name"`
+	Address       string        `json:"address"`
+	TransportType TransportType `json:"transport_type"`
+	SHAkey        string        `json:"key"`
+	CryptoKey     string        `json:"crypto_key"`
+	RateLimit     int           `json:"rate_limit"`
+	SpoolDir      string        `json:"spool_dir"`
+	SpoolMaxSize  int64         `json:"spool_max_size"`
+	SpoolMaxAge   Duration      `json:"spool_max_age"`
+}
+
+// ParseDestination parses a destination in a form name=transport://host:port
+func ParseDestination(s string) (Destination, error) {
+	name, target, ok := strings.Cut(s, "=")
+	transport, address, hasScheme := strings.Cut(target, "://")
+	if !ok || !hasScheme || name == "" || address == "" {
+		return Destination{}, fmt.Errorf("expect destination in a form name=http://host:port, got %q", s)
+	}
+
+	tt := TransportType(transport)
+	if tt != HTTP && tt != GRPC {
+		return Destination{}, fmt.Errorf("unknown transport type of destination %s: %s", name, transport)
+	}
+
+	return Destination{Name: name, Address: address, TransportType: tt}, nil
+}
+
+// ReportDestinations returns the configured destinations or the default one with the
+// agent address, transport, keys and spool when there are none
+func (cfg *AgentConfig) ReportDestinations() ([]Destination, error) {
+	if len(cfg.Destinations) == 0 {
+		return []Destination{{
+			Name:          DefaultDestination,
+			Address:       cfg.Address,
+			TransportType: cfg.TransportType,
+			SHAkey:        cfg.SHAkey,
+			CryptoKey:     cfg.CryptoKey,
+			RateLimit:     cfg.RateLimit,
+			SpoolDir:      cfg.SpoolDir,
+			SpoolMaxSize:  cfg.SpoolMaxSize,
+			SpoolMaxAge:   cfg.SpoolMaxAge,
+		}}, nil
+	}
+
+	names := make(map[string]bool, len(cfg.Destinations))
+	spoolDirs := make(map[string]string, len(cfg.Destinations))
+
+	for _, d := range cfg.Destinations {
+		if d.Name == "" || d.Address == "" {
+			return nil, fmt.Errorf("destination name and address are required, got %+v", d)
+		}
+		if names[d.Name] {
+			return nil, fmt.Errorf("duplicate destination %s", d.Name)
+		}
+		names[d.Name] = true
+
+		// batches of different destinations must not be mixed in one spool
+		if d.SpoolDir != "" {
+			if other, ok := spoolDirs[d.SpoolDir]; ok {
+				return nil, fmt.Errorf("destinations %s and %s share spool dir %s", other, d.Name, d.SpoolDir)
+			}
+			spoolDirs[d.SpoolDir] = d.Name
+		}
+	}
+
+	return cfg.Destinations, nil
+}
+
+// WithDestination returns a copy of the agent config reporting to the destination, empty
+// keys of the destination are taken from the agent config
+func (cfg AgentConfig) WithDestination(d Destination) *AgentConfig {
+	cfg.Address = d.Address
+	cfg.TransportType = d.TransportType
+	if cfg.TransportType == "" {
+		cfg.TransportType = HTTP
+	}
+	cfg.SpoolDir = d.SpoolDir
+
+	if d.SHAkey != "" {
+		cfg.SHAkey = d.SHAkey
+	}
+	if d.CryptoKey != "" {
+		cfg.CryptoKey = d.CryptoKey
+	}
+	if d.RateLimit > 0 {
+		cfg.RateLimit = d.RateLimit
+	}
+	if d.SpoolMaxSize > 0 {
+		cfg.SpoolMaxSize = d.SpoolMaxSize
+	}
+	if d.SpoolMaxAge.Duration > 0 {
+		cfg.SpoolMaxAge = d.SpoolMaxAge
+	}
+	cfg.Destinations = nil
+
+	return &cfg
+}
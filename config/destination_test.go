@@ -0,0 +1,69 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAgentConfig_WithDestination(t *testing.T) {
+	agent := AgentConfig{
+		SHAkey:       "agent-key",
+		CryptoKey:    "agent.pem",
+		RateLimit:    2,
+		SpoolMaxSize: 100,
+		SpoolMaxAge:  Duration{Duration: time.Hour},
+	}
+
+	tests := []struct {
+		name string
+		d    Destination
+		want AgentConfig
+	}{
+		{
+			name: "takes empty keys and limits from agent config",
+			d:    Destination{Name: "primary", Address: "localhost:8080"},
+			want: AgentConfig{
+				Address:       "localhost:8080",
+				TransportType: HTTP,
+				SHAkey:        "agent-key",
+				CryptoKey:     "agent.pem",
+				RateLimit:     2,
+				SpoolMaxSize:  100,
+				SpoolMaxAge:   Duration{Duration: time.Hour},
+			},
+		},
+		{
+			name: "overrides keys and limits of agent config",
+			d: Destination{
+				Name:          "secondary",
+				Address:       "localhost:3200",
+				TransportType: GRPC,
+				SHAkey:        "secondary-key",
+				CryptoKey:     "secondary.pem",
+				RateLimit:     4,
+				SpoolDir:      "/tmp/secondary",
+				SpoolMaxSize:  200,
+				SpoolMaxAge:   Duration{Duration: time.Minute},
+			},
+			want: AgentConfig{
+				Address:       "localhost:3200",
+				TransportType: GRPC,
+				SHAkey:        "secondary-key",
+				CryptoKey:     "secondary.pem",
+				RateLimit:     4,
+				SpoolDir:      "/tmp/secondary",
+				SpoolMaxSize:  200,
+				SpoolMaxAge:   Duration{Duration: time.Minute},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := agent.WithDestination(tt.d)
+
+			assert.Equal(t, &tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	ExecFormatAuto  = ""
+	ExecFormatJSON  = "json"
+	ExecFormatLines = "lines"
+)
+
+// ExecCommand is an external command run by the agent, its stdout is parsed into metrics
+type ExecCommand struct {
+	Name    string `json:"name"`
+	Command string `json:"command"`
+	// Timeout defaults to the exec collector interval and must not exceed it
+	Timeout Duration `json:"timeout"`
+	// Format is json for a JSON array of metrics or lines for name value lines,
+	// it is detected by the output when empty
+	Format string `json:"format"`
+}
+
+// ParseExecCommand parses a command in a form name=command
+func ParseExecCommand(s string) (ExecCommand, error) {
+	name, command, ok := strings.Cut(s, "=")
+	name, command = strings.TrimSpace(name), strings.TrimSpace(command)
+	if !ok || name == "" || command == "" {
+		return ExecCommand{}, fmt.Errorf("expect command in a form name=command, got %q", s)
+	}
+
+	return ExecCommand{Name: name, Command: command}, nil
+}
@@ -0,0 +1,25 @@
+package config
+
+const (
+	LogRuleCounter = "counter"
+	LogRuleGauge   = "gauge"
+)
+
+type (
+	// LogFile is a log file tailed by the agent, its new lines are matched against rules
+	LogFile struct {
+		Path  string    `json:"path"`
+		Rules []LogRule `json:"rules"`
+	}
+
+	// LogRule turns lines matching the pattern into a counter of matches or a gauge of the
+	// captured number. Named groups except the value one become metric labels.
+	LogRule struct {
+		Metric  string `json:"metric"`
+		Pattern string `json:"pattern"`
+		// Type is counter or gauge, counter when empty
+		Type string `json:"type"`
+		// Value is the name of the group captured by a gauge, the first group when empty
+		Value string `json:"value"`
+	}
+)
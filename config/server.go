@@ -8,6 +8,9 @@ import (
 	"time"
 )
 
+// DefaultBatchRetention is not less than the default agent spool max age
+const DefaultBatchRetention = 24 * time.Hour
+
 type (
 	ServerConfig struct {
 		Address         string        `env:"ADDRESS" json:"address"`
@@ -20,7 +23,18 @@ type (
 		SHAkey          string        `env:"KEY"`
 		CryptoKey       string        `env:"CRYPTO_KEY" json:"crypto_key"`
 		TrustedSubnet   string        `env:"TRUSTED_SUBNET" json:"trusted_subnet"`
-		ProfileConfig   ProfileConfig
+		StatsDAddress   string        `env:"STATSD_ADDRESS" json:"statsd_address"`
+		// StatsDFlushInterval is how often aggregated StatsD metrics are stored
+		StatsDFlushInterval Duration `env:"STATSD_FLUSH_INTERVAL" json:"statsd_flush_interval"`
+		GraphiteAddress     string   `env:"GRAPHITE_ADDRESS" json:"graphite_address"`
+		GraphiteTemplates   []string `env:"GRAPHITE_TEMPLATES" json:"graphite_templates"`
+		// BatchRetention is how long batch IDs are remembered, it must not be less than
+		// the spool max age of agents, so a replayed batch is not applied twice. Without
+		// the database IDs are kept in memory only and are forgotten on restart
+		BatchRetention Duration `env:"BATCH_RETENTION" json:"batch_retention"`
+		// SamplesRetention is how long metric samples are kept, zero keeps them
+		SamplesRetention Duration `env:"SAMPLES_RETENTION" json:"samples_retention"`
+		ProfileConfig    ProfileConfig
 	}
 
 	StoreInterval struct {
@@ -30,13 +44,16 @@ type (
 
 func NewServerCfg() *ServerConfig {
 	cfg := &ServerConfig{
-		Address:         ":8080",
-		AddressGRPC:     ":8081",
-		LogLevel:        "info",
-		StoreInterval:   StoreInterval{Duration: 300 * time.Second},
-		FileStoragePath: "/tmp/metrics-db.json",
-		Restore:         true,
-		ProfileConfig:   ProfileConfig{},
+		Address:             ":8080",
+		AddressGRPC:         ":8081",
+		LogLevel:            "info",
+		StoreInterval:       StoreInterval{Duration: 300 * time.Second},
+		FileStoragePath:     "/tmp/metrics-db.json",
+		Restore:             true,
+		StatsDFlushInterval: Duration{Duration: 10 * time.Second},
+		BatchRetention:      Duration{Duration: DefaultBatchRetention},
+		SamplesRetention:    Duration{Duration: 24 * time.Hour},
+		ProfileConfig:       ProfileConfig{},
 	}
 
 	return cfg
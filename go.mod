@@ -3,6 +3,7 @@ module github.com/Chystik/runtime-metrics
 go 1.20
 
 require (
+	github.com/golang/snappy v0.0.4
 	github.com/jackc/pgerrcode v0.0.0-20220416144525-469b46aa5efa
 	github.com/stretchr/testify v1.8.4
 	google.golang.org/grpc v1.53.0
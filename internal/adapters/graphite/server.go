@@ -0,0 +1,224 @@
+package graphite
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+	"github.com/Chystik/runtime-metrics/internal/service"
+)
+
+// maxBatchSize limits the number of lines stored at once
+const maxBatchSize = 1000
+
+var (
+	ErrServerClosed = errors.New("graphite: server closed")
+	ErrInvalidLine  = errors.New("invalid graphite line")
+)
+
+// Server accepts the Graphite plaintext protocol over TCP and stores every value as a gauge.
+type Server struct {
+	addr      string
+	ms        service.MetricsService
+	logger    service.AppLogger
+	templates []Template
+
+	mu     sync.Mutex
+	ln     net.Listener
+	conns  map[net.Conn]struct{}
+	wg     sync.WaitGroup
+	closed chan struct{}
+}
+
+// batch collects the lines read from a connection, the latest value of a metric wins
+type batch struct {
+	metrics    []models.Metric
+	timestamps []time.Time
+	idx        map[string]int
+}
+
+func NewServer(addr string, ms service.MetricsService, logger service.AppLogger, templates []Template) *Server {
+	return &Server{
+		addr:      addr,
+		ms:        ms,
+		logger:    logger,
+		templates: templates,
+		conns:     make(map[net.Conn]struct{}),
+		closed:    make(chan struct{}),
+	}
+}
+
+// ListenAndServe accepts connections until Shutdown, it always returns a non-nil error
+func (s *Server) ListenAndServe() error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.ln = ln
+	s.mu.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			select {
+			case <-s.closed:
+				return ErrServerClosed
+			default:
+				return err
+			}
+		}
+
+		s.mu.Lock()
+		select {
+		case <-s.closed:
+			// accepted concurrently with Shutdown
+			s.mu.Unlock()
+			conn.Close()
+			continue
+		default:
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.handleConn(conn)
+	}
+}
+
+// Shutdown stops accepting connections, closes the open ones and waits
+// until the lines already read are stored
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	select {
+	case <-s.closed:
+	default:
+		close(s.closed)
+	}
+
+	var err error
+	if s.ln != nil {
+		err = s.ln.Close()
+	}
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// handleConn reads lines from the connection and stores them every time
+// the received data is read out or the batch is full
+func (s *Server) handleConn(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+		s.wg.Done()
+	}()
+
+	r := bufio.NewReader(conn)
+	b := newBatch()
+
+	for {
+		line, err := r.ReadString('\n')
+		if line = strings.TrimSpace(line); line != "" {
+			m, ts, e := s.parseLine(line, time.Now())
+			if e != nil {
+				s.logger.Error(e.Error())
+			} else {
+				b.add(m, ts)
+			}
+		}
+
+		if err != nil || r.Buffered() == 0 || len(b.metrics) >= maxBatchSize {
+			s.flush(b)
+			b = newBatch()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) flush(b *batch) {
+	if len(b.metrics) == 0 {
+		return
+	}
+
+	if err := s.ms.UpdateList(context.Background(), b.metrics); err != nil {
+		s.logger.Error(err.Error())
+	}
+}
+
+// parseLine parses a line in the form path value [timestamp], timestamp is
+// in unix seconds, -1 or no timestamp means now
+func (s *Server) parseLine(line string, now time.Time) (models.Metric, time.Time, error) {
+	var m models.Metric
+
+	fields := strings.Fields(line)
+	if len(fields) < 2 || len(fields) > 3 {
+		return m, now, fmt.Errorf("%w: %q", ErrInvalidLine, line)
+	}
+
+	v, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return m, now, fmt.Errorf("%w: bad value in %q", ErrInvalidLine, line)
+	}
+
+	ts := now
+	if len(fields) == 3 && fields[2] != "-1" {
+		sec, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return m, now, fmt.Errorf("%w: bad timestamp in %q", ErrInvalidLine, line)
+		}
+		ts = time.Unix(0, int64(sec*1e9))
+	}
+
+	m.ID, m.Labels = metricName(s.templates, fields[0])
+	m.MType = "gauge"
+	m.Value = &v
+
+	return m, ts, nil
+}
+
+func newBatch() *batch {
+	return &batch{idx: make(map[string]int)}
+}
+
+func (b *batch) add(m models.Metric, ts time.Time) {
+	i, ok := b.idx[m.Key()]
+	if !ok {
+		b.idx[m.Key()] = len(b.metrics)
+		b.metrics = append(b.metrics, m)
+		b.timestamps = append(b.timestamps, ts)
+		return
+	}
+
+	if !ts.Before(b.timestamps[i]) {
+		b.metrics[i] = m
+		b.timestamps[i] = ts
+	}
+}
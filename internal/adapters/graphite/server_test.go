@@ -0,0 +1,107 @@
+package graphite
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+	"github.com/Chystik/runtime-metrics/internal/service/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_parseLine(t *testing.T) {
+	t.Parallel()
+	s := NewServer(":0", &mocks.MetricsService{}, &mocks.Logger{}, nil)
+	now := time.Now()
+
+	m, ts, err := s.parseLine("cron.backup.duration 12.5 1700000000", now)
+	require.NoError(t, err)
+	assert.Equal(t, models.Metric{ID: "cron.backup.duration", MType: "gauge", Value: createValue(12.5)}, m)
+	assert.Equal(t, time.Unix(1700000000, 0), ts)
+
+	_, ts, err = s.parseLine("cron.backup.duration 12.5 -1", now)
+	require.NoError(t, err)
+	assert.Equal(t, now, ts)
+
+	_, ts, err = s.parseLine("cron.backup.duration 12.5", now)
+	require.NoError(t, err)
+	assert.Equal(t, now, ts)
+
+	for _, line := range []string{
+		"cron.backup.duration",
+		"cron.backup.duration twelve",
+		"cron.backup.duration NaN",
+		"cron.backup.duration 12.5 yesterday",
+		"cron.backup.duration 12.5 1700000000 extra",
+	} {
+		_, _, err = s.parseLine(line, now)
+		assert.ErrorIs(t, err, ErrInvalidLine, line)
+	}
+}
+
+func TestServer_ListenAndServe(t *testing.T) {
+	t.Parallel()
+	ms := &mocks.MetricsService{}
+	logger := &mocks.Logger{}
+
+	templates, err := ParseTemplates([]string{"servers.* .host.measurement*"})
+	require.NoError(t, err)
+
+	s := NewServer("127.0.0.1:0", ms, logger, templates)
+
+	done := make(chan error)
+	go func() {
+		done <- s.ListenAndServe()
+	}()
+
+	var addr net.Addr
+	require.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.ln == nil {
+			return false
+		}
+		addr = s.ln.Addr()
+		return true
+	}, time.Second, 10*time.Millisecond)
+
+	stored := make(chan []models.Metric, 1)
+	ms.EXPECT().UpdateList(mock.Anything, mock.Anything).Run(func(_ context.Context, m []models.Metric) {
+		stored <- m
+	}).Return(nil).Once()
+	logger.EXPECT().Error(mock.Anything).Return().Once()
+
+	conn, err := net.Dial("tcp", addr.String())
+	require.NoError(t, err)
+
+	_, err = conn.Write([]byte(
+		"servers.web01.cpu.load 2 1700000010\n" +
+			"servers.web01.cpu.load 3 1700000000\n" +
+			"bad line\n" +
+			"servers.web02.cpu.load 1 1700000000\n"))
+	require.NoError(t, err)
+
+	select {
+	case m := <-stored:
+		assert.Equal(t, []models.Metric{
+			{ID: "cpu.load", MType: "gauge", Value: createValue(2), Labels: models.Labels{"host": "web01"}},
+			{ID: "cpu.load", MType: "gauge", Value: createValue(1), Labels: models.Labels{"host": "web02"}},
+		}, m)
+	case <-time.After(time.Second):
+		t.Fatal("metrics are not stored")
+	}
+
+	assert.NoError(t, s.Shutdown(context.Background()))
+	assert.ErrorIs(t, <-done, ErrServerClosed)
+	ms.AssertExpectations(t)
+	logger.AssertExpectations(t)
+}
+
+func createValue(x float64) *float64 {
+	return &x
+}
@@ -0,0 +1,155 @@
+package graphite
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+)
+
+const (
+	partMeasurement     = "measurement"
+	partMeasurementRest = "measurement*"
+)
+
+var (
+	ErrInvalidTemplate = errors.New("invalid graphite template")
+)
+
+// Template maps parts of a dotted path to the metric ID and labels. It is
+// written as "[filter ]pattern", e.g. "servers.* .host.measurement*" turns
+// servers.web01.cpu.load into cpu.load{host="web01"}.
+//
+// Pattern parts are:
+//   - measurement: the part is added to the metric ID;
+//   - measurement*: the part and all the following ones are added to the metric ID;
+//   - any other name: the part becomes the value of the label with this name;
+//   - empty: the part is dropped.
+//
+// Filter parts are glob patterns, the filter matches paths starting with them.
+type Template struct {
+	filter  []string
+	pattern []string
+}
+
+// ParseTemplates parses templates in the order of priority. Templates with a filter
+// are tried first, the first template without a filter is used for the rest paths.
+func ParseTemplates(ts []string) ([]Template, error) {
+	res := make([]Template, 0, len(ts))
+
+	for _, s := range ts {
+		t, err := parseTemplate(s)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, t)
+	}
+
+	return res, nil
+}
+
+func parseTemplate(s string) (Template, error) {
+	var t Template
+
+	fields := strings.Fields(s)
+	switch len(fields) {
+	case 1:
+		t.pattern = strings.Split(fields[0], ".")
+	case 2:
+		t.filter = strings.Split(fields[0], ".")
+		for _, f := range t.filter {
+			if _, err := path.Match(f, ""); err != nil {
+				return t, fmt.Errorf("%w %q: bad filter", ErrInvalidTemplate, s)
+			}
+		}
+		t.pattern = strings.Split(fields[1], ".")
+	default:
+		return t, fmt.Errorf("%w %q: expected [filter ]pattern", ErrInvalidTemplate, s)
+	}
+
+	var hasMeasurement bool
+	for i, p := range t.pattern {
+		switch p {
+		case partMeasurementRest:
+			if i != len(t.pattern)-1 {
+				return t, fmt.Errorf("%w %q: %s must be the last part", ErrInvalidTemplate, s, partMeasurementRest)
+			}
+			hasMeasurement = true
+		case partMeasurement:
+			hasMeasurement = true
+		}
+	}
+	if !hasMeasurement {
+		return t, fmt.Errorf("%w %q: pattern has no measurement part", ErrInvalidTemplate, s)
+	}
+
+	return t, nil
+}
+
+// match reports whether the filter matches the path parts
+func (t Template) match(parts []string) bool {
+	if len(t.filter) > len(parts) {
+		return false
+	}
+	for i, f := range t.filter {
+		if ok, _ := path.Match(f, parts[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// apply returns the metric ID and labels of the path parts, pattern parts beyond the path are ignored
+func (t Template) apply(parts []string) (string, models.Labels) {
+	var (
+		id     []string
+		labels models.Labels
+	)
+
+	for i := 0; i < len(parts) && i < len(t.pattern); i++ {
+		switch p := t.pattern[i]; p {
+		case "":
+		case partMeasurement:
+			id = append(id, parts[i])
+		case partMeasurementRest:
+			id = append(id, parts[i:]...)
+		default:
+			if labels == nil {
+				labels = make(models.Labels)
+			}
+			labels[p] = parts[i]
+		}
+	}
+
+	return strings.Join(id, "."), labels
+}
+
+// metricName returns the metric ID and labels of the path using the first matching template,
+// the path itself is the metric ID when there is no matching template
+func metricName(templates []Template, p string) (string, models.Labels) {
+	parts := strings.Split(p, ".")
+
+	var def *Template
+	for i := range templates {
+		if templates[i].filter == nil {
+			if def == nil {
+				def = &templates[i]
+			}
+			continue
+		}
+		if templates[i].match(parts) {
+			def = &templates[i]
+			break
+		}
+	}
+
+	if def != nil {
+		if id, labels := def.apply(parts); id != "" {
+			return id, labels
+		}
+	}
+
+	return p, nil
+}
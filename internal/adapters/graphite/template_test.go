@@ -0,0 +1,75 @@
+package graphite
+
+import (
+	"testing"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTemplates_WhenTemplateIsInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{
+		"",
+		"servers.* .host.measurement* extra",
+		".host.region",
+		"measurement*.host",
+		"servers.[ .host.measurement",
+	} {
+		_, err := ParseTemplates([]string{s})
+		assert.ErrorIs(t, err, ErrInvalidTemplate, s)
+	}
+}
+
+func Test_metricName(t *testing.T) {
+	t.Parallel()
+
+	templates, err := ParseTemplates([]string{
+		"servers.* .host.measurement*",
+		"cron.*.*.duration .job.host.measurement",
+		"measurement.measurement.region",
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		path       string
+		wantID     string
+		wantLabels models.Labels
+	}{
+		{
+			path:       "servers.web01.cpu.load",
+			wantID:     "cpu.load",
+			wantLabels: models.Labels{"host": "web01"},
+		},
+		{
+			path:       "cron.backup.db01.duration",
+			wantID:     "duration",
+			wantLabels: models.Labels{"job": "backup", "host": "db01"},
+		},
+		{
+			path:       "disk.free.eu",
+			wantID:     "disk.free",
+			wantLabels: models.Labels{"region": "eu"},
+		},
+		{
+			// the default template, the extra part is dropped
+			path:       "disk.free.eu.sda",
+			wantID:     "disk.free",
+			wantLabels: models.Labels{"region": "eu"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			id, labels := metricName(templates, tt.path)
+			assert.Equal(t, tt.wantID, id)
+			assert.Equal(t, tt.wantLabels, labels)
+		})
+	}
+
+	id, labels := metricName(nil, "servers.web01.cpu.load")
+	assert.Equal(t, "servers.web01.cpu.load", id)
+	assert.Nil(t, labels)
+}
@@ -2,7 +2,10 @@ package grpcapihandlers
 
 import (
 	"github.com/Chystik/runtime-metrics/internal/models"
+	"github.com/Chystik/runtime-metrics/pkg/ddsketch"
 	pb "github.com/Chystik/runtime-metrics/protobuf"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func toDomainMetrics(m []*pb.Metric) []models.Metric {
@@ -17,18 +20,148 @@ func toDomainMetrics(m []*pb.Metric) []models.Metric {
 
 func toDomainMetric(m *pb.Metric) models.Metric {
 	return models.Metric{
-		ID:    m.Id,
-		MType: m.Type,
-		Delta: &m.Delta,
-		Value: &m.Value,
+		ID:        m.Id,
+		MType:     m.Type,
+		Delta:     &m.Delta,
+		Value:     &m.Value,
+		Histogram: toDomainHistogram(m.Histogram),
+		Summary:   toDomainSummary(m.Summary),
+		Labels:    m.Labels,
 	}
 }
 
 func fromDomainMetric(m models.Metric) *pb.Metric {
-	return &pb.Metric{
-		Id:    m.ID,
-		Type:  m.MType,
-		Delta: *m.Delta,
-		Value: *m.Value,
+	res := &pb.Metric{
+		Id:        m.ID,
+		Type:      m.MType,
+		Histogram: fromDomainHistogram(m.Histogram),
+		Summary:   fromDomainSummary(m.Summary),
+		Labels:    m.Labels,
+	}
+
+	if m.Delta != nil {
+		res.Delta = *m.Delta
+	}
+	if m.Value != nil {
+		res.Value = *m.Value
+	}
+
+	return res
+}
+
+func toDomainHistogram(h *pb.Histogram) *models.Histogram {
+	if h == nil {
+		return nil
+	}
+
+	res := &models.Histogram{
+		Buckets: make([]models.Bucket, len(h.Buckets)),
+		Sum:     h.Sum,
+		Count:   h.Count,
+	}
+	for i := range h.Buckets {
+		res.Buckets[i] = models.Bucket{UpperBound: h.Buckets[i].UpperBound, Count: h.Buckets[i].Count}
+	}
+
+	return res
+}
+
+func fromDomainHistogram(h *models.Histogram) *pb.Histogram {
+	if h == nil {
+		return nil
+	}
+
+	res := &pb.Histogram{
+		Buckets: make([]*pb.Bucket, len(h.Buckets)),
+		Sum:     h.Sum,
+		Count:   h.Count,
+	}
+	for i := range h.Buckets {
+		res.Buckets[i] = &pb.Bucket{UpperBound: h.Buckets[i].UpperBound, Count: h.Buckets[i].Count}
+	}
+
+	return res
+}
+
+func toDomainSummary(s *pb.Summary) *models.Summary {
+	if s == nil || s.Sketch == nil {
+		return nil
 	}
+
+	res := &models.Summary{
+		Sketch: &ddsketch.Sketch{
+			RelativeAccuracy: s.Sketch.RelativeAccuracy,
+			Positive:         make(map[int]uint64, len(s.Sketch.Positive)),
+			Negative:         make(map[int]uint64, len(s.Sketch.Negative)),
+			Zero:             s.Sketch.Zero,
+			Count:            s.Sketch.Count,
+			Sum:              s.Sketch.Sum,
+			Min:              s.Sketch.Min,
+			Max:              s.Sketch.Max,
+		},
+	}
+	for i, c := range s.Sketch.Positive {
+		res.Sketch.Positive[int(i)] = c
+	}
+	for i, c := range s.Sketch.Negative {
+		res.Sketch.Negative[int(i)] = c
+	}
+
+	return res
+}
+
+func fromDomainSummary(s *models.Summary) *pb.Summary {
+	if s == nil || s.Sketch == nil {
+		return nil
+	}
+
+	res := &pb.Summary{
+		Sketch: &pb.Sketch{
+			RelativeAccuracy: s.Sketch.RelativeAccuracy,
+			Positive:         make(map[int32]uint64, len(s.Sketch.Positive)),
+			Negative:         make(map[int32]uint64, len(s.Sketch.Negative)),
+			Zero:             s.Sketch.Zero,
+			Count:            s.Sketch.Count,
+			Sum:              s.Sketch.Sum,
+			Min:              s.Sketch.Min,
+			Max:              s.Sketch.Max,
+		},
+		Quantiles: make([]*pb.Quantile, len(s.Quantiles)),
+	}
+	for i, c := range s.Sketch.Positive {
+		res.Sketch.Positive[int32(i)] = c
+	}
+	for i, c := range s.Sketch.Negative {
+		res.Sketch.Negative[int32(i)] = c
+	}
+	for i := range s.Quantiles {
+		res.Quantiles[i] = &pb.Quantile{Quantile: s.Quantiles[i].Quantile, Value: s.Quantiles[i].Value}
+	}
+
+	return res
+}
+
+func toDomainRangeQuery(q *pb.QueryRangeRequest) models.RangeQuery {
+	return models.RangeQuery{
+		ID:          q.Id,
+		MType:       q.Type,
+		Labels:      q.Labels,
+		Start:       q.Start.AsTime(),
+		End:         q.End.AsTime(),
+		Step:        q.Step.AsDuration(),
+		Aggregation: q.Aggregation,
+	}
+}
+
+func fromDomainPoints(p []models.Point) []*pb.Point {
+	res := make([]*pb.Point, len(p))
+
+	for i := range p {
+		res[i] = &pb.Point{
+			Timestamp: timestamppb.New(p[i].Timestamp),
+			Value:     p[i].Value,
+		}
+	}
+
+	return res
 }
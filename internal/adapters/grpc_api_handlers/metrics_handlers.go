@@ -2,7 +2,9 @@ package grpcapihandlers
 
 import (
 	"context"
+	"errors"
 
+	"github.com/Chystik/runtime-metrics/internal/models"
 	"github.com/Chystik/runtime-metrics/internal/service"
 	pb "github.com/Chystik/runtime-metrics/protobuf"
 	"google.golang.org/grpc/codes"
@@ -20,11 +22,24 @@ func NewMetricsHandlers(ms service.MetricsService) *metricsHandlers {
 	}
 }
 
+// UpdateMetrics stores the list of metrics, a list with a batch ID is stored once per ID. A resent
+// list with a stored ID gets an empty response like the first one, an ID longer than 64 characters
+// gets InvalidArgument. IDs are kept in the database, without it they are kept in memory and a list
+// resent after a server restart is stored again.
 func (mh *metricsHandlers) UpdateMetrics(ctx context.Context, m *pb.UpdateMetricsRequest) (*pb.UpdateMetricsResponse, error) {
 	var response pb.UpdateMetricsResponse
 
-	err := mh.metricsService.UpdateList(ctx, toDomainMetrics(m.Metrics))
+	var err error
+	if m.BatchId != "" {
+		err = mh.metricsService.UpdateBatch(ctx, m.BatchId, toDomainMetrics(m.Metrics))
+	} else {
+		err = mh.metricsService.UpdateList(ctx, toDomainMetrics(m.Metrics))
+	}
 	if err != nil {
+		if errors.Is(err, models.ErrInvalidHistogram) || errors.Is(err, models.ErrInvalidSummary) ||
+			errors.Is(err, models.ErrInvalidBatchID) {
+			return nil, status.Errorf(codes.InvalidArgument, "%s", err.Error())
+		}
 		return nil, status.Errorf(codes.Internal, "update list error: %s", err.Error())
 	}
 
@@ -40,6 +55,16 @@ func (mh *metricsHandlers) UpdateMetric(ctx context.Context, m *pb.UpdateMetricR
 		err = mh.metricsService.UpdateGauge(ctx, toDomainMetric(m.Metric))
 	case "counter":
 		err = mh.metricsService.UpdateCounter(ctx, toDomainMetric(m.Metric))
+	case "histogram":
+		err = mh.metricsService.UpdateHistogram(ctx, toDomainMetric(m.Metric))
+		if errors.Is(err, models.ErrInvalidHistogram) {
+			return nil, status.Errorf(codes.InvalidArgument, "%s", err.Error())
+		}
+	case "summary":
+		err = mh.metricsService.UpdateSummary(ctx, toDomainMetric(m.Metric))
+		if errors.Is(err, models.ErrInvalidSummary) {
+			return nil, status.Errorf(codes.InvalidArgument, "%s", err.Error())
+		}
 	default:
 		return nil, status.Errorf(codes.NotFound, "unknown metric type: %s", m.Metric.Type)
 	}
@@ -63,3 +88,23 @@ func (mh *metricsHandlers) GetMetric(ctx context.Context, req *pb.GetMetricReque
 
 	return &response, nil
 }
+
+func (mh *metricsHandlers) QueryRange(ctx context.Context, req *pb.QueryRangeRequest) (*pb.QueryRangeResponse, error) {
+	var response pb.QueryRangeResponse
+
+	q := toDomainRangeQuery(req)
+	if q.Aggregation == "" {
+		q.Aggregation = models.AggregationAvg
+	}
+
+	points, err := mh.metricsService.QueryRange(ctx, q)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidQuery) {
+			return nil, status.Errorf(codes.InvalidArgument, "%s", err.Error())
+		}
+		return nil, status.Errorf(codes.NotFound, "can't find samples for metric with id %s", req.Id)
+	}
+	response.Points = fromDomainPoints(points)
+
+	return &response, nil
+}
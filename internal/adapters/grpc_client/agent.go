@@ -3,6 +3,7 @@ package grpcclient
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
 	"syscall"
 
@@ -36,15 +37,19 @@ func New(addr string) (*agentAPIClient, error) {
 	}, nil
 }
 
-func (ac *agentAPIClient) ReportMetricsBatch(ctx context.Context, m map[string]models.Metric) error {
+func (ac *agentAPIClient) ReportMetricsBatch(ctx context.Context, batchID string, m map[string]models.Metric) error {
 	req := &pb.UpdateMetricsRequest{
 		Metrics: fromDomainMetrics(m),
+		BatchId: batchID,
 	}
 
 	res, err := ac.client.UpdateMetrics(ctx, req)
 	if err != nil {
-		if status.Code(err) == codes.Unavailable {
+		switch status.Code(err) {
+		case codes.Unavailable:
 			return &net.OpError{Err: syscall.ECONNREFUSED}
+		case codes.InvalidArgument:
+			return fmt.Errorf("%w: %w", service.ErrBatchRejected, err)
 		}
 		return err
 	}
@@ -17,8 +17,11 @@ func fromDomainMetrics(m map[string]models.Metric) []*pb.Metric {
 
 func fromDomainMetric(m models.Metric) *pb.Metric {
 	res := &pb.Metric{
-		Id:   m.ID,
-		Type: m.MType,
+		Id:        m.ID,
+		Type:      m.MType,
+		Histogram: fromDomainHistogram(m.Histogram),
+		Summary:   fromDomainSummary(m.Summary),
+		Labels:    m.Labels,
 	}
 
 	if m.Delta != nil {
@@ -30,3 +33,47 @@ func fromDomainMetric(m models.Metric) *pb.Metric {
 
 	return res
 }
+
+func fromDomainHistogram(h *models.Histogram) *pb.Histogram {
+	if h == nil {
+		return nil
+	}
+
+	res := &pb.Histogram{
+		Buckets: make([]*pb.Bucket, len(h.Buckets)),
+		Sum:     h.Sum,
+		Count:   h.Count,
+	}
+	for i := range h.Buckets {
+		res.Buckets[i] = &pb.Bucket{UpperBound: h.Buckets[i].UpperBound, Count: h.Buckets[i].Count}
+	}
+
+	return res
+}
+
+func fromDomainSummary(s *models.Summary) *pb.Summary {
+	if s == nil || s.Sketch == nil {
+		return nil
+	}
+
+	res := &pb.Summary{
+		Sketch: &pb.Sketch{
+			RelativeAccuracy: s.Sketch.RelativeAccuracy,
+			Positive:         make(map[int32]uint64, len(s.Sketch.Positive)),
+			Negative:         make(map[int32]uint64, len(s.Sketch.Negative)),
+			Zero:             s.Sketch.Zero,
+			Count:            s.Sketch.Count,
+			Sum:              s.Sketch.Sum,
+			Min:              s.Sketch.Min,
+			Max:              s.Sketch.Max,
+		},
+	}
+	for i, c := range s.Sketch.Positive {
+		res.Sketch.Positive[int32(i)] = c
+	}
+	for i, c := range s.Sketch.Negative {
+		res.Sketch.Negative[int32(i)] = c
+	}
+
+	return res
+}
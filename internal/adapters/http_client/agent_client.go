@@ -127,7 +127,7 @@ func (ac *agentAPIClient) ReportMetricsJSON(ctx context.Context, metrics map[str
 
 // ReportMetricsJSONBatch sends all metrics in batch to the metrics server using JSON data format.
 // It also compresses all data sent.
-func (ac *agentAPIClient) ReportMetricsBatch(ctx context.Context, metrics map[string]models.Metric) error {
+func (ac *agentAPIClient) ReportMetricsBatch(ctx context.Context, batchID string, metrics map[string]models.Metric) error {
 	var ms []models.Metric
 	var buf, reqBody bytes.Buffer
 
@@ -172,6 +172,9 @@ func (ac *agentAPIClient) ReportMetricsBatch(ctx context.Context, metrics map[st
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept-Encoding", "gzip")
 	req.Header.Set("Content-Encoding", "gzip")
+	if batchID != "" {
+		req.Header.Set("Idempotency-Key", batchID)
+	}
 	resp, err := ac.client.Do(req)
 	if err != nil {
 		return err
@@ -179,8 +182,22 @@ func (ac *agentAPIClient) ReportMetricsBatch(ctx context.Context, metrics map[st
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf(errBadStatusCode, resp.Status)
+		err = fmt.Errorf(errBadStatusCode, resp.Status)
+		if rejected(resp.StatusCode) {
+			return fmt.Errorf("%w: %w", service.ErrBatchRejected, err)
+		}
+		return err
 	}
 
 	return nil
 }
+
+// rejected reports whether the status means the request itself is wrong, so resending
+// it does not help. Timeouts and rate limits are retried.
+func rejected(statusCode int) bool {
+	switch statusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return statusCode >= 400 && statusCode < 500
+}
@@ -2,6 +2,7 @@ package agentapiclient
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/Chystik/runtime-metrics/config"
 	"github.com/Chystik/runtime-metrics/internal/models"
+	"github.com/Chystik/runtime-metrics/internal/service"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -216,6 +218,7 @@ func Test_agentAPIClient_ReportMetricsJSONBatch(t *testing.T) {
 			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
 				// Test request parameters
 				assert.Equal(t, req.URL.Path, expURL)
+				assert.Equal(t, "batch1", req.Header.Get("Idempotency-Key"))
 				// Send response to be tested
 				b, err := io.ReadAll(req.Body)
 				assert.NoError(t, err)
@@ -228,7 +231,7 @@ func Test_agentAPIClient_ReportMetricsJSONBatch(t *testing.T) {
 			cfg := config.AgentConfig{Address: server.URL[7:]}
 			client := New(server.Client(), &cfg)
 
-			err := client.ReportMetricsBatch(context.Background(), metrics)
+			err := client.ReportMetricsBatch(context.Background(), "batch1", metrics)
 
 			if tt.wantErr {
 				assert.Error(t, err)
@@ -239,3 +242,30 @@ func Test_agentAPIClient_ReportMetricsJSONBatch(t *testing.T) {
 		})
 	}
 }
+
+func Test_agentAPIClient_ReportMetricsBatch_WhenServerRejectsBatch(t *testing.T) {
+	tests := []struct {
+		status       int
+		wantRejected bool
+	}{
+		{status: http.StatusBadRequest, wantRejected: true},
+		{status: http.StatusTooManyRequests, wantRejected: false},
+		{status: http.StatusInternalServerError, wantRejected: false},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			cfg := config.AgentConfig{Address: server.URL[7:]}
+			client := New(server.Client(), &cfg)
+
+			err := client.ReportMetricsBatch(context.Background(), "batch1", map[string]models.Metric{})
+
+			assert.Error(t, err)
+			assert.Equal(t, tt.wantRejected, errors.Is(err, service.ErrBatchRejected))
+		})
+	}
+}
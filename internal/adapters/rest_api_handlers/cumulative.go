@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+	"github.com/Chystik/runtime-metrics/internal/service"
+)
+
+// cumulativeTTL is how long the last value of a cumulative series is kept after it was
+// received, a series received again after that starts from the stored value as a baseline
+const cumulativeTTL = time.Hour
+
+// cumulativePoint is a received value of a cumulative counter or histogram, the metric
+// has the ID, type and labels of the series
+type cumulativePoint struct {
+	metric    models.Metric
+	counter   float64
+	histogram *models.Histogram
+}
+
+func (p cumulativePoint) key() string {
+	return p.metric.MType + ":" + p.metric.Key()
+}
+
+// cumulativeValue is the last received value of a series
+type cumulativeValue struct {
+	counter   float64
+	histogram *models.Histogram
+	seen      time.Time
+}
+
+// cumulativeState keeps the last received values of cumulative series to store increments
+// since them. The service is never called while the lock is held.
+type cumulativeState struct {
+	mu        sync.Mutex
+	last      map[string]cumulativeValue
+	ttl       time.Duration
+	evictedAt time.Time
+	now       func() time.Time
+}
+
+func newCumulativeState() *cumulativeState {
+	return &cumulativeState{
+		last: make(map[string]cumulativeValue),
+		ttl:  cumulativeTTL,
+		now:  time.Now,
+	}
+}
+
+// increments returns a metric with the increment since the previous value for every point,
+// points of the same series must be in the order of their values. All the value of a series
+// that is not stored is its increment, the first value of other series is used only as
+// a baseline. The last values are updated at once, rollback restores them if the increments
+// were not stored, unless newer values were received since.
+func (cs *cumulativeState) increments(ctx context.Context, ms service.MetricsService, points []cumulativePoint) ([]models.Metric, func(), error) {
+	notStored, err := cs.newSeries(ctx, ms, points)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	now := cs.now()
+
+	type change struct {
+		prev    cumulativeValue
+		existed bool
+		cur     cumulativeValue
+	}
+	changes := make(map[string]change)
+	metrics := make([]models.Metric, 0, len(points))
+
+	for _, p := range points {
+		key := p.key()
+
+		prev, ok := cs.last[key]
+		if !ok && !notStored[key] {
+			prev = cumulativeValue{counter: p.counter, histogram: p.histogram}
+		}
+
+		m := p.metric
+		switch m.MType {
+		case "counter":
+			d := counterIncrease(prev.counter, p.counter)
+			m.Delta = &d
+		case "histogram":
+			m.Histogram = p.histogram.Diff(prev.histogram)
+		}
+		metrics = append(metrics, m)
+
+		cur := cumulativeValue{counter: p.counter, histogram: p.histogram, seen: now}
+		c, seen := changes[key]
+		if !seen {
+			c = change{prev: cs.last[key], existed: ok}
+		}
+		c.cur = cur
+		changes[key] = c
+		cs.last[key] = cur
+	}
+
+	rollback := func() {
+		cs.mu.Lock()
+		defer cs.mu.Unlock()
+
+		for key, c := range changes {
+			if cs.last[key] != c.cur {
+				continue
+			}
+			if c.existed {
+				cs.last[key] = c.prev
+			} else {
+				delete(cs.last, key)
+			}
+		}
+	}
+
+	return metrics, rollback, nil
+}
+
+// newSeries returns series without the last value that are not stored, other errors
+// of the service than ErrNotFoundMetric are returned
+func (cs *cumulativeState) newSeries(ctx context.Context, ms service.MetricsService, points []cumulativePoint) (map[string]bool, error) {
+	missing := make(map[string]models.Metric)
+
+	cs.mu.Lock()
+	cs.evict(cs.now())
+	for _, p := range points {
+		if _, ok := cs.last[p.key()]; !ok {
+			missing[p.key()] = p.metric
+		}
+	}
+	cs.mu.Unlock()
+
+	notStored := make(map[string]bool, len(missing))
+	for key, m := range missing {
+		_, err := ms.Get(ctx, m)
+		switch {
+		case err == nil:
+		case errors.Is(err, models.ErrNotFoundMetric):
+			notStored[key] = true
+		default:
+			return nil, err
+		}
+	}
+
+	return notStored, nil
+}
+
+// evict drops values not received for the ttl, the map is scanned once per ttl,
+// the caller must hold the lock
+func (cs *cumulativeState) evict(now time.Time) {
+	if now.Sub(cs.evictedAt) < cs.ttl {
+		return
+	}
+	cs.evictedAt = now
+
+	for key, v := range cs.last {
+		if now.Sub(v.seen) > cs.ttl {
+			delete(cs.last, key)
+		}
+	}
+}
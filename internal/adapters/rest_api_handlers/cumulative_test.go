@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+	"github.com/Chystik/runtime-metrics/internal/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_cumulativeState_EvictsStaleValues(t *testing.T) {
+	t.Parallel()
+
+	ms := &mocks.MetricsService{}
+	cs := newCumulativeState()
+	now := time.Now()
+	cs.now = func() time.Time { return now }
+
+	point := func(v float64) []cumulativePoint {
+		return []cumulativePoint{{metric: models.Metric{ID: "requests", MType: "counter"}, counter: v}}
+	}
+
+	ms.EXPECT().Get(mock.Anything, mock.Anything).Return(models.Metric{}, models.ErrNotFoundMetric).Once()
+	metrics, _, err := cs.increments(context.Background(), ms, point(10))
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), *metrics[0].Delta)
+
+	now = now.Add(cumulativeTTL / 2)
+	metrics, _, err = cs.increments(context.Background(), ms, point(15))
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), *metrics[0].Delta)
+
+	// the last value is evicted, the stored counter gets a new baseline
+	now = now.Add(cumulativeTTL + time.Second)
+	ms.EXPECT().Get(mock.Anything, mock.Anything).Return(models.Metric{}, nil).Once()
+	metrics, _, err = cs.increments(context.Background(), ms, point(40))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), *metrics[0].Delta)
+	assert.Len(t, cs.last, 1)
+
+	ms.AssertExpectations(t)
+}
+
+func Test_cumulativeState_Rollback(t *testing.T) {
+	t.Parallel()
+
+	ms := &mocks.MetricsService{}
+	cs := newCumulativeState()
+	points := []cumulativePoint{{metric: models.Metric{ID: "requests", MType: "counter"}, counter: 10}}
+
+	ms.EXPECT().Get(mock.Anything, mock.Anything).Return(models.Metric{}, models.ErrNotFoundMetric).Twice()
+	_, rollback, err := cs.increments(context.Background(), ms, points)
+	require.NoError(t, err)
+	rollback()
+	assert.Empty(t, cs.last)
+
+	metrics, _, err := cs.increments(context.Background(), ms, points)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), *metrics[0].Delta)
+
+	ms.AssertExpectations(t)
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"bytes"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+)
+
+const expositionContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Exposition writes all metrics in the Prometheus text exposition format 0.0.4
+func (mh *metricsHandlers) Exposition(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+
+	m, err := mh.metricsService.GetAll(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	type series struct {
+		name   string
+		metric models.Metric
+	}
+
+	s := make([]series, 0, len(m))
+	for i := range m {
+		name := sanitizeMetricName(m[i].ID)
+		if name == "" {
+			continue
+		}
+		s = append(s, series{name: name, metric: m[i]})
+	}
+
+	sort.Slice(s, func(i, j int) bool {
+		if s[i].name != s[j].name {
+			return s[i].name < s[j].name
+		}
+		return s[i].metric.Labels.String() < s[j].metric.Labels.String()
+	})
+
+	types := make(map[string]string, len(s))
+	for _, v := range s {
+		lines := formatSeries(v.name, v.metric)
+		if lines == "" {
+			continue
+		}
+
+		t, ok := types[v.name]
+		if ok && t != v.metric.MType {
+			// the same name can't be exposed with different types
+			continue
+		}
+		if !ok {
+			types[v.name] = v.metric.MType
+			buf.WriteString("# TYPE " + v.name + " " + v.metric.MType + "\n")
+		}
+		buf.WriteString(lines)
+	}
+
+	w.Header().Set("Content-Type", expositionContentType)
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(buf.Bytes())
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// sanitizeMetricName replaces characters that are not allowed in Prometheus
+// metric names ([a-zA-Z_:][a-zA-Z0-9_:]*) with underscores
+func sanitizeMetricName(name string) string {
+	if name == "" {
+		return ""
+	}
+
+	var sb strings.Builder
+
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':':
+			sb.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				sb.WriteRune('_')
+			}
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('_')
+		}
+	}
+
+	return sb.String()
+}
+
+// formatSeries returns exposition lines of the metric, empty if the metric has no value
+func formatSeries(name string, m models.Metric) string {
+	switch {
+	case m.MType == "gauge" && m.Value != nil:
+		return name + formatLabels(m.Labels) + " " + formatFloat(*m.Value) + "\n"
+	case m.MType == "counter" && m.Delta != nil:
+		return name + formatLabels(m.Labels) + " " + strconv.FormatInt(*m.Delta, 10) + "\n"
+	case m.MType == "histogram" && m.Histogram != nil:
+		return formatHistogramSeries(name, m.Labels, m.Histogram)
+	case m.MType == "summary" && m.Summary != nil && m.Summary.Sketch != nil:
+		return formatSummarySeries(name, m.Labels, m.Summary)
+	}
+	return ""
+}
+
+// formatHistogramSeries returns cumulative _bucket lines followed by _sum and _count
+func formatHistogramSeries(name string, l models.Labels, h *models.Histogram) string {
+	var (
+		sb         strings.Builder
+		cumulative uint64
+	)
+
+	labels := make(models.Labels, len(l)+1)
+	for k, v := range l {
+		labels[k] = v
+	}
+
+	for _, b := range h.Buckets {
+		cumulative += b.Count
+		labels["le"] = formatFloat(b.UpperBound)
+		sb.WriteString(name + "_bucket" + formatLabels(labels) + " " + strconv.FormatUint(cumulative, 10) + "\n")
+	}
+	labels["le"] = "+Inf"
+	sb.WriteString(name + "_bucket" + formatLabels(labels) + " " + strconv.FormatUint(h.Count, 10) + "\n")
+	sb.WriteString(name + "_sum" + formatLabels(l) + " " + formatFloat(h.Sum) + "\n")
+	sb.WriteString(name + "_count" + formatLabels(l) + " " + strconv.FormatUint(h.Count, 10) + "\n")
+
+	return sb.String()
+}
+
+// formatSummarySeries returns quantile lines followed by _sum and _count
+func formatSummarySeries(name string, l models.Labels, s *models.Summary) string {
+	var sb strings.Builder
+
+	labels := make(models.Labels, len(l)+1)
+	for k, v := range l {
+		labels[k] = v
+	}
+
+	for _, q := range models.DefaultQuantiles {
+		v, err := s.Sketch.Quantile(q)
+		if err != nil {
+			continue
+		}
+		labels["quantile"] = formatFloat(q)
+		sb.WriteString(name + formatLabels(labels) + " " + formatFloat(v) + "\n")
+	}
+	sb.WriteString(name + "_sum" + formatLabels(l) + " " + formatFloat(s.Sketch.Sum) + "\n")
+	sb.WriteString(name + "_count" + formatLabels(l) + " " + strconv.FormatUint(s.Sketch.Count, 10) + "\n")
+
+	return sb.String()
+}
+
+// formatLabels returns labels in the exposition form {k1="v1",k2="v2"} sorted by name
+func formatLabels(l models.Labels) string {
+	if len(l) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(l))
+	for k := range l {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+
+	sb.WriteByte('{')
+	for i, k := range names {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		// label names don't allow colons, unlike metric names
+		sb.WriteString(strings.ReplaceAll(sanitizeMetricName(k), ":", "_"))
+		sb.WriteString(`="`)
+		sb.WriteString(labelValueReplacer.Replace(l[k]))
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+
+	return sb.String()
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
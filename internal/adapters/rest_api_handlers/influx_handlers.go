@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+)
+
+// maxLineSize limits a single line of the line protocol body
+const maxLineSize = 1 << 20
+
+var (
+	ErrInvalidLineProtocol = errors.New("invalid line protocol")
+)
+
+// precisions maps the precision query parameter of InfluxDB v1 and v2 APIs to the timestamp unit
+var precisions = map[string]time.Duration{
+	"":   time.Nanosecond,
+	"ns": time.Nanosecond,
+	"n":  time.Nanosecond,
+	"us": time.Microsecond,
+	"u":  time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+}
+
+// point is a parsed line of the line protocol
+type point struct {
+	metrics   []models.Metric
+	timestamp time.Time
+}
+
+// counterPoint is a cumulative counter value with the position of the counter in the batch
+type counterPoint struct {
+	cumulativePoint
+	pos       int
+	timestamp time.Time
+}
+
+// WriteLineProtocol accepts metrics in the InfluxDB line protocol, e.g.:
+//
+//	POST /write?precision=s
+//	cpu,host=a usage=0.5,cores=8i,requests=10u 1700000000
+//
+// Every field becomes a metric named measurement_field with tags as labels.
+// Float, integer and boolean fields are stored as gauges, unsigned integer fields
+// as counters, string fields are skipped. Unsigned fields are cumulative totals,
+// so the increment since the last received value is stored, the first value of
+// a counter that is already stored is used only as a baseline. The batch is stored
+// only if all lines are valid. Timestamps order the points of the same series in
+// the batch, the latest gauge value wins.
+func (mh *metricsHandlers) WriteLineProtocol(w http.ResponseWriter, r *http.Request) {
+	unit, ok := precisions[r.URL.Query().Get("precision")]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unsupported precision %q", r.URL.Query().Get("precision")), http.StatusBadRequest)
+		return
+	}
+
+	var points []point
+
+	now := time.Now()
+	sc := bufio.NewScanner(r.Body)
+	sc.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		p, err := parsePoint(line, unit, now)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("line %d: %s", n, err.Error()), http.StatusBadRequest)
+			return
+		}
+		points = append(points, p)
+	}
+	if err := sc.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	metrics, counters, pos := aggregatePoints(points)
+
+	increments, rollback, err := mh.influx.increments(r.Context(), mh.metricsService, counters)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	for i, m := range increments {
+		*metrics[pos[i]].Delta += *m.Delta
+	}
+
+	if len(metrics) > 0 {
+		err = mh.metricsService.UpdateList(r.Context(), metrics)
+		if err != nil {
+			rollback()
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// aggregatePoints merges the points of the same series keeping the order of the first appearance.
+// Counters get zero deltas, their cumulative values are returned in the order of timestamps
+// with positions of the counters in the result.
+func aggregatePoints(points []point) ([]models.Metric, []cumulativePoint, []int) {
+	var (
+		res      []models.Metric
+		idx      = make(map[string]int)
+		gaugesTS = make(map[string]time.Time)
+		counters []counterPoint
+	)
+
+	for _, p := range points {
+		for _, m := range p.metrics {
+			key := m.MType + ":" + m.Key()
+
+			if m.MType == "counter" {
+				cp := counterPoint{cumulativePoint: cumulativePoint{metric: m, counter: float64(*m.Delta)}, timestamp: p.timestamp}
+				cp.metric.Delta = nil
+
+				i, ok := idx[key]
+				if !ok {
+					var d int64
+					m.Delta = &d
+					i = len(res)
+					idx[key] = i
+					res = append(res, m)
+				}
+				cp.pos = i
+				counters = append(counters, cp)
+				continue
+			}
+
+			i, ok := idx[key]
+			if !ok {
+				idx[key] = len(res)
+				res = append(res, m)
+				gaugesTS[key] = p.timestamp
+				continue
+			}
+			if !p.timestamp.Before(gaugesTS[key]) {
+				res[i] = m
+				gaugesTS[key] = p.timestamp
+			}
+		}
+	}
+
+	sort.SliceStable(counters, func(i, j int) bool {
+		return counters[i].timestamp.Before(counters[j].timestamp)
+	})
+
+	cumulative := make([]cumulativePoint, len(counters))
+	pos := make([]int, len(counters))
+	for i, c := range counters {
+		cumulative[i] = c.cumulativePoint
+		pos[i] = c.pos
+	}
+
+	return res, cumulative, pos
+}
+
+// parsePoint parses a line in the form measurement[,tag=value...] field=value[,field=value...] [timestamp]
+func parsePoint(line string, unit time.Duration, now time.Time) (point, error) {
+	var p point
+
+	// quotes are allowed only in string field values
+	head, rest, _ := cutUnescaped(line, ' ')
+	sections := splitUnescaped(rest, ' ', true)
+	if sections[0] == "" || len(sections) > 2 {
+		return p, fmt.Errorf("%w: expected measurement, fields and optional timestamp", ErrInvalidLineProtocol)
+	}
+
+	series := splitUnescaped(head, ',', false)
+	measurement := unescape(series[0])
+	if measurement == "" {
+		return p, fmt.Errorf("%w: measurement is empty", ErrInvalidLineProtocol)
+	}
+
+	var labels models.Labels
+	for _, t := range series[1:] {
+		kv := splitUnescaped(t, '=', false)
+		if len(kv) != 2 || kv[0] == "" {
+			return p, fmt.Errorf("%w: bad tag %q", ErrInvalidLineProtocol, t)
+		}
+		if labels == nil {
+			labels = make(models.Labels)
+		}
+		labels[unescape(kv[0])] = unescape(kv[1])
+	}
+
+	p.timestamp = now
+	if len(sections) == 2 {
+		ts, err := strconv.ParseInt(sections[1], 10, 64)
+		if err != nil {
+			return p, fmt.Errorf("%w: bad timestamp %q", ErrInvalidLineProtocol, sections[1])
+		}
+		p.timestamp = time.Unix(0, ts*int64(unit))
+	}
+
+	for _, f := range splitUnescaped(sections[0], ',', true) {
+		k, v, ok := cutUnescaped(f, '=')
+		if !ok || k == "" || v == "" {
+			return p, fmt.Errorf("%w: bad field %q", ErrInvalidLineProtocol, f)
+		}
+
+		m := models.Metric{ID: measurement + "_" + unescape(k), Labels: labels}
+
+		if err := parseFieldValue(&m, v); err != nil {
+			return p, err
+		}
+		if m.MType != "" {
+			p.metrics = append(p.metrics, m)
+		}
+	}
+
+	return p, nil
+}
+
+// parseFieldValue sets the metric type and value from the field value, the delta of a counter is
+// its cumulative value, string fields leave the type empty
+func parseFieldValue(m *models.Metric, v string) error {
+	switch {
+	case strings.HasPrefix(v, `"`):
+		if len(v) < 2 || !strings.HasSuffix(v, `"`) {
+			return fmt.Errorf("%w: unterminated string %s", ErrInvalidLineProtocol, v)
+		}
+		return nil
+	case strings.HasSuffix(v, "u"):
+		u, err := strconv.ParseUint(v[:len(v)-1], 10, 64)
+		if err != nil || u > math.MaxInt64 {
+			return fmt.Errorf("%w: bad unsigned integer %q", ErrInvalidLineProtocol, v)
+		}
+		d := int64(u)
+		m.MType = "counter"
+		m.Delta = &d
+		return nil
+	case strings.HasSuffix(v, "i"):
+		i, err := strconv.ParseInt(v[:len(v)-1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: bad integer %q", ErrInvalidLineProtocol, v)
+		}
+		f := float64(i)
+		m.MType = "gauge"
+		m.Value = &f
+		return nil
+	}
+
+	var f float64
+	switch v {
+	case "t", "T", "true", "True", "TRUE":
+		f = 1
+	case "f", "F", "false", "False", "FALSE":
+		f = 0
+	default:
+		var err error
+		f, err = strconv.ParseFloat(v, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("%w: bad field value %q", ErrInvalidLineProtocol, v)
+		}
+	}
+	m.MType = "gauge"
+	m.Value = &f
+
+	return nil
+}
+
+// splitUnescaped splits s by sep ignoring separators escaped with a backslash,
+// with quotes set separators inside double quotes are ignored too
+func splitUnescaped(s string, sep byte, quotes bool) []string {
+	var (
+		res     []string
+		start   int
+		escaped bool
+		quoted  bool
+	)
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case s[i] == '\\':
+			escaped = true
+		case quotes && s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			res = append(res, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(res, s[start:])
+}
+
+// cutUnescaped slices s around the first unescaped sep
+func cutUnescaped(s string, sep byte) (before, after string, found bool) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			return s[:i], s[i+1:], true
+		}
+	}
+
+	return s, "", false
+}
+
+// unescape removes backslashes before escaped characters
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
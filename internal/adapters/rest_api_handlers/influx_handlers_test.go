@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_metricsHandlers_WriteLineProtocol(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		query          string
+		body           string
+		expStatus      int
+		expMetrics     []models.Metric
+		wantServiceErr bool
+	}{
+		{
+			name:  "fields of all types",
+			query: "?precision=s",
+			body: "# comment\n" +
+				`cpu,host=a,dc=eu usage=0.5,cores=8i,busy=t,requests=10u,state="idle" 1700000000` + "\n\n",
+			expStatus: http.StatusNoContent,
+			expMetrics: []models.Metric{
+				{ID: "cpu_usage", MType: "gauge", Value: createValue(0.5), Labels: models.Labels{"host": "a", "dc": "eu"}},
+				{ID: "cpu_cores", MType: "gauge", Value: createValue(8), Labels: models.Labels{"host": "a", "dc": "eu"}},
+				{ID: "cpu_busy", MType: "gauge", Value: createValue(1), Labels: models.Labels{"host": "a", "dc": "eu"}},
+				{ID: "cpu_requests", MType: "counter", Delta: createDelta(10), Labels: models.Labels{"host": "a", "dc": "eu"}},
+			},
+		},
+		{
+			name: "batch of lines of the same series",
+			body: "mem free=3 2000\n" +
+				"mem free=1 3000\n" +
+				"mem free=2 1000\n" +
+				"net,host=a rx=2u 5000\n" +
+				"net,host=a rx=1u 4000\n",
+			expStatus: http.StatusNoContent,
+			expMetrics: []models.Metric{
+				{ID: "mem_free", MType: "gauge", Value: createValue(1)},
+				{ID: "net_rx", MType: "counter", Delta: createDelta(2), Labels: models.Labels{"host": "a"}},
+			},
+		},
+		{
+			name:      "escaped characters",
+			body:      `disk\ io,path=/var\,log,name=a\=b read\ bytes=1,comment="a, b=c \"d\""`,
+			expStatus: http.StatusNoContent,
+			expMetrics: []models.Metric{
+				{ID: "disk io_read bytes", MType: "gauge", Value: createValue(1), Labels: models.Labels{"path": "/var,log", "name": "a=b"}},
+			},
+		},
+		{
+			name:      "only string fields",
+			body:      `log msg="hello world"`,
+			expStatus: http.StatusNoContent,
+		},
+		{
+			name:      "no fields",
+			body:      "cpu,host=a",
+			expStatus: http.StatusBadRequest,
+		},
+		{
+			name:      "bad field value",
+			body:      "cpu usage=0.5\ncpu usage=high",
+			expStatus: http.StatusBadRequest,
+		},
+		{
+			name:      "bad tag",
+			body:      "cpu,host usage=0.5",
+			expStatus: http.StatusBadRequest,
+		},
+		{
+			name:      "bad timestamp",
+			body:      "cpu usage=0.5 yesterday",
+			expStatus: http.StatusBadRequest,
+		},
+		{
+			name:      "unsupported precision",
+			query:     "?precision=d",
+			body:      "cpu usage=0.5",
+			expStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "metricsService.UpdateList returns error",
+			body:           "cpu usage=0.5",
+			expStatus:      http.StatusInternalServerError,
+			expMetrics:     []models.Metric{{ID: "cpu_usage", MType: "gauge", Value: createValue(0.5)}},
+			wantServiceErr: true,
+		},
+	}
+	for _, tt := range tests {
+		handlers, mks := getMetricsHandlersMocks()
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/write"+tt.query, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			var err error
+			if tt.wantServiceErr {
+				err = errors.New("some error")
+			}
+			mks.metricsService.EXPECT().Get(mock.Anything, mock.Anything).Return(models.Metric{}, models.ErrNotFoundMetric).Maybe()
+			if tt.expMetrics != nil {
+				mks.metricsService.EXPECT().UpdateList(mock.Anything, tt.expMetrics).Return(err).Once()
+			}
+
+			handlers.WriteLineProtocol(rec, req)
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.expStatus, res.StatusCode)
+			mks.metricsService.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_metricsHandlers_WriteLineProtocol_AddsCounters(t *testing.T) {
+	t.Parallel()
+
+	handlers, ms := getStoredMetricsHandlers()
+
+	// unsigned fields are cumulative, the same total doesn't increase the counter
+	for _, total := range []int64{10, 10, 25} {
+		req := httptest.NewRequest(http.MethodPost, "/write", strings.NewReader(fmt.Sprintf("app requests=%du", total)))
+		rec := httptest.NewRecorder()
+
+		handlers.WriteLineProtocol(rec, req)
+
+		res := rec.Result()
+		res.Body.Close()
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+		assert.Equal(t, total, storedDelta(t, ms, "app_requests"))
+	}
+}
+
+func Test_parsePoint_Precision(t *testing.T) {
+	t.Parallel()
+
+	p, err := parsePoint("cpu usage=1 1700000000123", time.Millisecond, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, time.UnixMilli(1700000000123), p.timestamp)
+
+	now := time.Now()
+	p, err = parsePoint("cpu usage=1", time.Millisecond, now)
+	assert.NoError(t, err)
+	assert.Equal(t, now, p.timestamp)
+}
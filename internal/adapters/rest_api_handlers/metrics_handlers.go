@@ -3,6 +3,7 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"sort"
@@ -35,10 +36,18 @@ const tplStr = `<table>
 
 type metricsHandlers struct {
 	metricsService service.MetricsService
+	remoteWrite    *remoteWriteState
+	otlp           *cumulativeState
+	influx         *cumulativeState
 }
 
 func NewMetricsHandlers(ms service.MetricsService) *metricsHandlers {
-	h := &metricsHandlers{metricsService: ms}
+	h := &metricsHandlers{
+		metricsService: ms,
+		remoteWrite:    newRemoteWriteState(),
+		otlp:           newCumulativeState(),
+		influx:         newCumulativeState(),
+	}
 	return h
 }
 
@@ -97,6 +106,38 @@ func (mh *metricsHandlers) UpdateMetric(w http.ResponseWriter, r *http.Request)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+	case "histogram":
+		var v float64
+
+		v, err = strconv.ParseFloat(path[2], 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		metric.Histogram = models.NewHistogram(models.DefaultBuckets)
+		metric.Histogram.Observe(v)
+		err = mh.metricsService.UpdateHistogram(r.Context(), metric)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	case "summary":
+		var v float64
+
+		v, err = strconv.ParseFloat(path[2], 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		metric.Summary = models.NewSummary()
+		metric.Summary.Observe(v)
+		err = mh.metricsService.UpdateSummary(r.Context(), metric)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 	default:
 		w.WriteHeader(http.StatusBadRequest)
 		return
@@ -144,6 +185,10 @@ func (mh *metricsHandlers) GetMetric(w http.ResponseWriter, r *http.Request) {
 		result = strconv.FormatFloat(*metric.Value, 'f', -1, 64)
 	case "counter":
 		result = strconv.FormatInt(*metric.Delta, 10)
+	case "histogram":
+		result = formatHistogram(metric.Histogram)
+	case "summary":
+		result = formatSummary(metric.Summary)
 	default:
 		w.WriteHeader(http.StatusBadRequest)
 		return
@@ -175,8 +220,16 @@ func (mh *metricsHandlers) UpdateMetricJSON(w http.ResponseWriter, r *http.Reque
 		err = mh.metricsService.UpdateGauge(r.Context(), metric)
 	case "counter":
 		err = mh.metricsService.UpdateCounter(r.Context(), metric)
+	case "histogram":
+		err = mh.metricsService.UpdateHistogram(r.Context(), metric)
+	case "summary":
+		err = mh.metricsService.UpdateSummary(r.Context(), metric)
 	}
 	if err != nil {
+		if errors.Is(err, models.ErrInvalidHistogram) || errors.Is(err, models.ErrInvalidSummary) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -195,6 +248,10 @@ func (mh *metricsHandlers) UpdateMetricJSON(w http.ResponseWriter, r *http.Reque
 	}
 }
 
+// UpdateMetricsJSON stores the list of metrics, a list with an Idempotency-Key header is stored
+// once per key. A resent list with a stored key gets 200 OK without the result of the first
+// request, a key longer than 64 characters gets 400 Bad Request. Keys are kept in the database,
+// without it they are kept in memory and a list resent after a server restart is stored again.
 func (mh *metricsHandlers) UpdateMetricsJSON(w http.ResponseWriter, r *http.Request) {
 	var (
 		metrics []models.Metric
@@ -207,8 +264,18 @@ func (mh *metricsHandlers) UpdateMetricsJSON(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	err = mh.metricsService.UpdateList(r.Context(), metrics)
+	// a resent batch with the same key is not applied twice
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		err = mh.metricsService.UpdateBatch(r.Context(), key, metrics)
+	} else {
+		err = mh.metricsService.UpdateList(r.Context(), metrics)
+	}
 	if err != nil {
+		if errors.Is(err, models.ErrInvalidHistogram) || errors.Is(err, models.ErrInvalidSummary) ||
+			errors.Is(err, models.ErrInvalidBatchID) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -276,8 +343,14 @@ func (mh *metricsHandlers) AllMetrics(w http.ResponseWriter, r *http.Request) {
 		if m[i].Delta != nil {
 			v = strconv.FormatInt(*m[i].Delta, 10)
 		}
+		if m[i].Histogram != nil {
+			v = formatHistogram(m[i].Histogram)
+		}
+		if m[i].Summary != nil {
+			v = formatSummary(m[i].Summary)
+		}
 
-		fm = append(fm, formatMetrics{Name: m[i].ID, Type: m[i].MType, Value: v})
+		fm = append(fm, formatMetrics{Name: m[i].Key(), Type: m[i].MType, Value: v})
 	}
 
 	sort.Slice(fm, func(i, j int) bool {
@@ -291,3 +364,31 @@ func (mh *metricsHandlers) AllMetrics(w http.ResponseWriter, r *http.Request) {
 		log.Println(err)
 	}
 }
+
+// formatHistogram returns a short text form of the histogram: count=3 sum=1.5
+func formatHistogram(h *models.Histogram) string {
+	if h == nil {
+		return ""
+	}
+	return "count=" + strconv.FormatUint(h.Count, 10) + " sum=" + strconv.FormatFloat(h.Sum, 'f', -1, 64)
+}
+
+// formatSummary returns a short text form of the summary: count=3 sum=1.5 p50=0.5 p90=0.9 p99=0.99
+func formatSummary(s *models.Summary) string {
+	if s == nil || s.Sketch == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString("count=" + strconv.FormatUint(s.Sketch.Count, 10) + " sum=" + strconv.FormatFloat(s.Sketch.Sum, 'f', -1, 64))
+	for _, q := range models.DefaultQuantiles {
+		v, err := s.Sketch.Quantile(q)
+		if err != nil {
+			continue
+		}
+		sb.WriteString(" p" + strconv.FormatFloat(q*100, 'f', -1, 64) + "=" + strconv.FormatFloat(v, 'f', -1, 64))
+	}
+
+	return sb.String()
+}
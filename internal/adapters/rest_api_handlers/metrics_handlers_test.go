@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,11 +10,16 @@ import (
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/Chystik/runtime-metrics/config"
+	"github.com/Chystik/runtime-metrics/internal/infrastructure/repository/inmemory"
 	"github.com/Chystik/runtime-metrics/internal/models"
+	"github.com/Chystik/runtime-metrics/internal/service"
 	"github.com/Chystik/runtime-metrics/internal/service/mocks"
+	metricsservice "github.com/Chystik/runtime-metrics/internal/service/server"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -116,6 +122,44 @@ func Test_metricsHandlers_UpdateMetric(t *testing.T) {
 			expStatus:      http.StatusOK,
 			expContentType: "text/plain",
 		},
+		{
+			name: "histogram",
+			metric: metric{
+				mType: "histogram",
+				mName: "Histogram",
+				name:  "test3",
+				value: "0.3",
+			},
+			pathPattern:    "/update/%s/%s/%s",
+			reqMethod:      http.MethodPost,
+			expStatus:      http.StatusOK,
+			expContentType: "text/plain",
+		},
+		{
+			name: "histogram not float",
+			metric: metric{
+				mType: "histogram",
+				mName: "Histogram",
+				name:  "test3",
+				value: "fast",
+			},
+			pathPattern: "/update/%s/%s/%s",
+			reqMethod:   http.MethodPost,
+			expStatus:   http.StatusBadRequest,
+		},
+		{
+			name: "summary",
+			metric: metric{
+				mType: "summary",
+				mName: "Summary",
+				name:  "test4",
+				value: "-1.5",
+			},
+			pathPattern:    "/update/%s/%s/%s",
+			reqMethod:      http.MethodPost,
+			expStatus:      http.StatusOK,
+			expContentType: "text/plain",
+		},
 		{
 			name:        "wrong method",
 			pathPattern: "/update/%s/%s/%s",
@@ -367,6 +411,53 @@ func Test_metricsHandlers_UpdateMetricsJSON(t *testing.T) {
 	}
 }
 
+func Test_metricsHandlers_UpdateMetricsJSON_WithIdempotencyKey(t *testing.T) {
+	t.Parallel()
+
+	handlers, mks := getMetricsHandlersMocks()
+
+	var buf bytes.Buffer
+	err := json.NewEncoder(&buf).Encode(generateMetrics(3))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/updates", &buf)
+	req.Header.Set("Idempotency-Key", "batch1")
+	rec := httptest.NewRecorder()
+
+	mks.metricsService.On("UpdateBatch", mock.Anything, "batch1", mock.Anything).Return(nil)
+	handlers.UpdateMetricsJSON(rec, req)
+
+	res := rec.Result()
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	mks.metricsService.AssertNotCalled(t, "UpdateList", mock.Anything, mock.Anything)
+}
+
+func Test_metricsHandlers_UpdateMetricsJSON_WhenIdempotencyKeyIsTooLong(t *testing.T) {
+	t.Parallel()
+
+	handlers, ms := getStoredMetricsHandlers()
+
+	var buf bytes.Buffer
+	err := json.NewEncoder(&buf).Encode(generateMetrics(3))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/updates", &buf)
+	req.Header.Set("Idempotency-Key", strings.Repeat("a", 65))
+	rec := httptest.NewRecorder()
+
+	handlers.UpdateMetricsJSON(rec, req)
+
+	res := rec.Result()
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	all, err := ms.GetAll(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, all)
+}
+
 func Test_metricsHandlers_GetMetricJSON(t *testing.T) {
 	t.Parallel()
 
@@ -574,6 +665,174 @@ func Test_metricsHandlers_AllMetrics(t *testing.T) {
 	}
 }
 
+func Test_metricsHandlers_QueryRange(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		target     string
+		serviceErr error
+		expStatus  int
+	}{
+		{
+			name:      "unix time and duration step",
+			target:    "/query_range?id=HeapAlloc&type=gauge&start=1700000000&end=1700003600&step=60s",
+			expStatus: http.StatusOK,
+		},
+		{
+			name:      "rfc3339 time and seconds step",
+			target:    "/query_range?id=HeapAlloc&start=2023-11-14T22:13:20Z&end=2023-11-14T23:13:20Z&step=15&aggregation=max",
+			expStatus: http.StatusOK,
+		},
+		{
+			name:      "with labels",
+			target:    "/query_range?id=HeapAlloc&type=gauge&labels=host%3Da,dc%3Deu&start=1700000000&end=1700003600&step=60s",
+			expStatus: http.StatusOK,
+		},
+		{
+			name:      "bad labels",
+			target:    "/query_range?id=HeapAlloc&labels=host&start=1700000000&end=1700003600&step=60s",
+			expStatus: http.StatusBadRequest,
+		},
+		{
+			name:      "bad start",
+			target:    "/query_range?id=HeapAlloc&start=yesterday&end=1700003600&step=60s",
+			expStatus: http.StatusBadRequest,
+		},
+		{
+			name:      "missing step",
+			target:    "/query_range?id=HeapAlloc&start=1700000000&end=1700003600",
+			expStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid query",
+			target:     "/query_range?id=HeapAlloc&start=1700000000&end=1700003600&step=60s&aggregation=median",
+			serviceErr: models.ErrInvalidQuery,
+			expStatus:  http.StatusBadRequest,
+		},
+		{
+			name:       "not found",
+			target:     "/query_range?id=HeapAlloc&start=1700000000&end=1700003600&step=60s",
+			serviceErr: errors.New("not found"),
+			expStatus:  http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlers, mks := getMetricsHandlersMocks()
+			points := []models.Point{{Timestamp: time.Unix(1700000000, 0), Value: 1}}
+
+			mks.metricsService.EXPECT().QueryRange(mock.Anything, mock.Anything).Return(points, tt.serviceErr)
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			rec := httptest.NewRecorder()
+			handlers.QueryRange(rec, req)
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.expStatus, res.StatusCode)
+			if tt.expStatus == http.StatusOK {
+				var body rangeResponse
+				require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+				assert.Equal(t, "HeapAlloc", body.ID)
+				assert.Len(t, body.Points, 1)
+			}
+		})
+	}
+}
+
+func Test_metricsHandlers_Exposition(t *testing.T) {
+	t.Parallel()
+	handlers, mks := getMetricsHandlersMocks()
+
+	metrics := []models.Metric{
+		{ID: "PollCount", MType: "counter", Delta: createDelta(5)},
+		{ID: "HeapAlloc", MType: "gauge", Value: createValue(1.5)},
+		{ID: "9lives.total-count", MType: "gauge", Value: createValue(2)},
+		{ID: "NoValue", MType: "gauge"},
+		{ID: "HeapAlloc", MType: "gauge", Value: createValue(2.5), Labels: models.Labels{"host": "b", "app.name": `say "hi"`}},
+		{ID: "HeapAlloc", MType: "gauge", Value: createValue(3.5), Labels: models.Labels{"host": "a"}},
+		{ID: "PauseNs", MType: "histogram", Histogram: &models.Histogram{
+			Buckets: []models.Bucket{{UpperBound: 100, Count: 1}, {UpperBound: 1000, Count: 2}},
+			Sum:     2500,
+			Count:   4,
+		}},
+	}
+	expBody := "# TYPE HeapAlloc gauge\n" +
+		"HeapAlloc 1.5\n" +
+		"HeapAlloc{app_name=\"say \\\"hi\\\"\",host=\"b\"} 2.5\n" +
+		"HeapAlloc{host=\"a\"} 3.5\n" +
+		"# TYPE PauseNs histogram\n" +
+		"PauseNs_bucket{le=\"100\"} 1\n" +
+		"PauseNs_bucket{le=\"1000\"} 3\n" +
+		"PauseNs_bucket{le=\"+Inf\"} 4\n" +
+		"PauseNs_sum 2500\n" +
+		"PauseNs_count 4\n" +
+		"# TYPE PollCount counter\n" +
+		"PollCount 5\n" +
+		"# TYPE _9lives_total_count gauge\n" +
+		"_9lives_total_count 2\n"
+
+	mks.metricsService.EXPECT().GetAll(mock.Anything).Return(metrics, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	handlers.Exposition(rec, req)
+
+	res := rec.Result()
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, expositionContentType, res.Header.Get("Content-Type"))
+	assert.Equal(t, expBody, string(body))
+}
+
+func Test_metricsHandlers_Exposition_Summary(t *testing.T) {
+	t.Parallel()
+	handlers, mks := getMetricsHandlersMocks()
+
+	m := models.Metric{ID: "Latency", MType: "summary", Summary: models.NewSummary()}
+	m.Summary.Observe(2)
+	expBody := "# TYPE Latency summary\n" +
+		"Latency{quantile=\"0.5\"} 2\n" +
+		"Latency{quantile=\"0.9\"} 2\n" +
+		"Latency{quantile=\"0.99\"} 2\n" +
+		"Latency_sum 2\n" +
+		"Latency_count 1\n"
+
+	mks.metricsService.EXPECT().GetAll(mock.Anything).Return([]models.Metric{m}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	handlers.Exposition(rec, req)
+
+	res := rec.Result()
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, expBody, string(body))
+}
+
+func Test_metricsHandlers_Exposition_ServiceReturnsError(t *testing.T) {
+	t.Parallel()
+	handlers, mks := getMetricsHandlersMocks()
+
+	mks.metricsService.EXPECT().GetAll(mock.Anything).Return(nil, errors.New("error"))
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	handlers.Exposition(rec, req)
+
+	res := rec.Result()
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+}
+
 type metricsHandlersMocks struct {
 	metricsService *mocks.MetricsService
 }
@@ -587,6 +846,26 @@ func getMetricsHandlersMocks() (*metricsHandlers, *metricsHandlersMocks) {
 	return handlers, m
 }
 
+// getStoredMetricsHandlers returns handlers of the service with the in-memory repository
+func getStoredMetricsHandlers() (*metricsHandlers, service.MetricsService) {
+	ms := metricsservice.New(inmemory.NewMetricsRepo(&config.ServerConfig{}))
+	return NewMetricsHandlers(ms), ms
+}
+
+// storedDelta returns the sum of deltas of stored counters with the id
+func storedDelta(t *testing.T, ms service.MetricsService, id string) int64 {
+	metrics, err := ms.GetAll(context.Background())
+	require.NoError(t, err)
+
+	var d int64
+	for _, m := range metrics {
+		if m.ID == id && m.MType == "counter" {
+			d += *m.Delta
+		}
+	}
+	return d
+}
+
 func generateMetrics(count int) []models.Metric {
 	m := make([]models.Metric, count)
 
@@ -623,3 +902,11 @@ func generateMetric(metricType string, metricName string) models.Metric {
 
 	return m
 }
+
+func createValue(x float64) *float64 {
+	return &x
+}
+
+func createDelta(x int64) *int64 {
+	return &x
+}
@@ -0,0 +1,353 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+	"github.com/Chystik/runtime-metrics/protobuf/otlp"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	otlpProtobufContentType = "application/x-protobuf"
+	otlpJSONContentType     = "application/json"
+)
+
+// otlpResourceLabels maps resource attributes to labels the same way Prometheus does
+var otlpResourceLabels = map[string]string{
+	"service.name":        "job",
+	"service.instance.id": "instance",
+}
+
+// otlpBatch collects metrics of the request, values of the same series are merged,
+// cumulative values are converted to increments after all of them are collected
+type otlpBatch struct {
+	metrics []models.Metric
+	idx     map[string]int
+	times   map[string]uint64
+	points  []cumulativePoint
+}
+
+// ExportMetrics receives metrics with OTLP/HTTP in protobuf or JSON encoding on POST /v1/metrics.
+//
+// Data point attributes become labels, service.name and service.instance.id resource
+// attributes become job and instance labels. Gauges and non-monotonic cumulative sums
+// are stored as gauges with the latest value, monotonic sums as counters and explicit
+// bucket histograms as histograms. Cumulative values are converted to increments since
+// the last received value, the first value of a series that is already stored is used
+// only as a baseline. Float increments of counters are rounded. Non-monotonic delta sums
+// are skipped, since the absolute value is unknown.
+func (mh *metricsHandlers) ExportMetrics(w http.ResponseWriter, r *http.Request) {
+	var req otlp.ExportMetricsServiceRequest
+
+	ct, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (ct != otlpProtobufContentType && ct != otlpJSONContentType) {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if ct == otlpJSONContentType {
+		err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(b, &req)
+	} else {
+		err = proto.Unmarshal(b, &req)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	batch := &otlpBatch{
+		idx:   make(map[string]int),
+		times: make(map[string]uint64),
+	}
+
+	for _, rm := range req.GetResourceMetrics() {
+		resLabels := resourceLabels(rm.GetResource())
+
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				if err = batch.addMetric(m, resLabels); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+		}
+	}
+
+	increments, rollback, err := mh.otlp.increments(r.Context(), mh.metricsService, batch.points)
+	if err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	for _, m := range increments {
+		if m.MType == "histogram" {
+			batch.addHistogram(m)
+		} else {
+			batch.addCounter(m)
+		}
+	}
+
+	if len(batch.metrics) > 0 {
+		err = mh.metricsService.UpdateList(r.Context(), batch.metrics)
+		if err != nil {
+			rollback()
+			if errors.Is(err, models.ErrInvalidHistogram) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			// exporters retry only on 429, 502, 503 and 504
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	var resp []byte
+	if ct == otlpJSONContentType {
+		resp, err = protojson.Marshal(&otlp.ExportMetricsServiceResponse{})
+	} else {
+		resp, err = proto.Marshal(&otlp.ExportMetricsServiceResponse{})
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ct)
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(resp)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// addMetric adds data points of the metric to the batch
+func (b *otlpBatch) addMetric(m *otlp.Metric, resLabels models.Labels) error {
+	if m.GetName() == "" {
+		return errors.New("metric without name")
+	}
+
+	switch data := m.GetData().(type) {
+	case *otlp.Metric_Gauge:
+		for _, dp := range data.Gauge.GetDataPoints() {
+			if v, ok := numberValue(dp); ok {
+				b.addGauge(gaugeMetric(m.GetName(), dp.GetAttributes(), resLabels, v), dp.GetTimeUnixNano())
+			}
+		}
+	case *otlp.Metric_Sum:
+		cumulative := data.Sum.GetAggregationTemporality() == otlp.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+		for _, dp := range data.Sum.GetDataPoints() {
+			v, ok := numberValue(dp)
+			switch {
+			case !ok:
+				// no recorded value
+			case !data.Sum.GetIsMonotonic() && cumulative:
+				b.addGauge(gaugeMetric(m.GetName(), dp.GetAttributes(), resLabels, v), dp.GetTimeUnixNano())
+			case !data.Sum.GetIsMonotonic():
+				// the change of an up-down counter can't be stored without the absolute value
+			case cumulative:
+				b.addCumulative(cumulativePoint{
+					metric:  models.Metric{ID: m.GetName(), MType: "counter", Labels: attributesLabels(dp.GetAttributes(), resLabels)},
+					counter: v,
+				})
+			default:
+				d := int64(math.Round(v))
+				b.addCounter(models.Metric{ID: m.GetName(), MType: "counter", Labels: attributesLabels(dp.GetAttributes(), resLabels), Delta: &d})
+			}
+		}
+	case *otlp.Metric_Histogram:
+		cumulative := data.Histogram.GetAggregationTemporality() == otlp.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+		for _, dp := range data.Histogram.GetDataPoints() {
+			if dp.GetFlags()&uint32(otlp.DataPointFlags_DATA_POINT_FLAGS_NO_RECORDED_VALUE_MASK) != 0 {
+				continue
+			}
+
+			h, err := histogramFromOTLP(dp)
+			if err != nil {
+				return fmt.Errorf("metric %s: %w", m.GetName(), err)
+			}
+
+			hm := models.Metric{ID: m.GetName(), MType: "histogram", Labels: attributesLabels(dp.GetAttributes(), resLabels)}
+			if cumulative {
+				b.addCumulative(cumulativePoint{metric: hm, histogram: h})
+				continue
+			}
+			hm.Histogram = h
+			b.addHistogram(hm)
+		}
+	}
+
+	return nil
+}
+
+// addCumulative keeps the place of the series in the batch until the increment is known
+func (b *otlpBatch) addCumulative(p cumulativePoint) {
+	b.points = append(b.points, p)
+
+	key := p.key()
+	if _, ok := b.idx[key]; ok {
+		return
+	}
+
+	m := p.metric
+	if m.MType == "counter" {
+		var d int64
+		m.Delta = &d
+	}
+	b.idx[key] = len(b.metrics)
+	b.metrics = append(b.metrics, m)
+}
+
+func (b *otlpBatch) addGauge(m models.Metric, t uint64) {
+	key := m.MType + ":" + m.Key()
+	if i, ok := b.idx[key]; ok {
+		if t >= b.times[key] {
+			b.metrics[i] = m
+			b.times[key] = t
+		}
+		return
+	}
+	b.idx[key] = len(b.metrics)
+	b.times[key] = t
+	b.metrics = append(b.metrics, m)
+}
+
+func (b *otlpBatch) addCounter(m models.Metric) {
+	key := m.MType + ":" + m.Key()
+	if i, ok := b.idx[key]; ok {
+		*b.metrics[i].Delta += *m.Delta
+		return
+	}
+	b.idx[key] = len(b.metrics)
+	b.metrics = append(b.metrics, m)
+}
+
+func (b *otlpBatch) addHistogram(m models.Metric) {
+	key := m.MType + ":" + m.Key()
+	if i, ok := b.idx[key]; ok {
+		if b.metrics[i].Histogram == nil {
+			b.metrics[i].Histogram = m.Histogram
+			return
+		}
+		b.metrics[i].Histogram.Merge(m.Histogram)
+		return
+	}
+	b.idx[key] = len(b.metrics)
+	b.metrics = append(b.metrics, m)
+}
+
+// histogramFromOTLP converts explicit bucket counts, the last one counts values above the last bound
+func histogramFromOTLP(dp *otlp.HistogramDataPoint) (*models.Histogram, error) {
+	bounds, counts := dp.GetExplicitBounds(), dp.GetBucketCounts()
+	if len(counts) != 0 && len(counts) != len(bounds)+1 {
+		return nil, fmt.Errorf("%w: %d bucket counts for %d bounds", models.ErrInvalidHistogram, len(counts), len(bounds))
+	}
+
+	h := &models.Histogram{
+		Buckets: make([]models.Bucket, len(bounds)),
+		Sum:     dp.GetSum(),
+		Count:   dp.GetCount(),
+	}
+	for i := range bounds {
+		h.Buckets[i].UpperBound = bounds[i]
+		if len(counts) > 0 {
+			h.Buckets[i].Count = counts[i]
+		}
+	}
+
+	if err := h.Validate(); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// numberValue returns the value of the data point, false if there is no recorded value
+func numberValue(dp *otlp.NumberDataPoint) (float64, bool) {
+	if dp.GetFlags()&uint32(otlp.DataPointFlags_DATA_POINT_FLAGS_NO_RECORDED_VALUE_MASK) != 0 {
+		return 0, false
+	}
+
+	switch v := dp.GetValue().(type) {
+	case *otlp.NumberDataPoint_AsDouble:
+		if math.IsNaN(v.AsDouble) || math.IsInf(v.AsDouble, 0) {
+			return 0, false
+		}
+		return v.AsDouble, true
+	case *otlp.NumberDataPoint_AsInt:
+		return float64(v.AsInt), true
+	default:
+		return 0, false
+	}
+}
+
+func gaugeMetric(name string, attrs []*otlp.KeyValue, resLabels models.Labels, v float64) models.Metric {
+	return models.Metric{ID: name, MType: "gauge", Value: &v, Labels: attributesLabels(attrs, resLabels)}
+}
+
+// resourceLabels returns labels of the resource attributes listed in otlpResourceLabels
+func resourceLabels(res *otlp.Resource) models.Labels {
+	var labels models.Labels
+
+	for _, kv := range res.GetAttributes() {
+		name, ok := otlpResourceLabels[kv.GetKey()]
+		if !ok {
+			continue
+		}
+		if v, ok := attributeValue(kv.GetValue()); ok {
+			if labels == nil {
+				labels = make(models.Labels)
+			}
+			labels[name] = v
+		}
+	}
+
+	return labels
+}
+
+// attributesLabels returns labels of the data point attributes merged with the resource labels
+func attributesLabels(attrs []*otlp.KeyValue, resLabels models.Labels) models.Labels {
+	if len(attrs) == 0 && len(resLabels) == 0 {
+		return nil
+	}
+
+	labels := make(models.Labels, len(attrs)+len(resLabels))
+	for k, v := range resLabels {
+		labels[k] = v
+	}
+	for _, kv := range attrs {
+		if v, ok := attributeValue(kv.GetValue()); ok {
+			labels[kv.GetKey()] = v
+		}
+	}
+
+	return labels
+}
+
+func attributeValue(v *otlp.AnyValue) (string, bool) {
+	switch a := v.GetValue().(type) {
+	case *otlp.AnyValue_StringValue:
+		return a.StringValue, true
+	case *otlp.AnyValue_BoolValue:
+		return strconv.FormatBool(a.BoolValue), true
+	case *otlp.AnyValue_IntValue:
+		return strconv.FormatInt(a.IntValue, 10), true
+	case *otlp.AnyValue_DoubleValue:
+		return strconv.FormatFloat(a.DoubleValue, 'g', -1, 64), true
+	default:
+		return "", false
+	}
+}
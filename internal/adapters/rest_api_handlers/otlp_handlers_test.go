@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+	"github.com/Chystik/runtime-metrics/protobuf/otlp"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_metricsHandlers_ExportMetrics(t *testing.T) {
+	t.Parallel()
+	handlers, mks := getMetricsHandlersMocks()
+
+	labels := models.Labels{"job": "checkout", "method": "GET"}
+
+	mks.metricsService.EXPECT().Get(mock.Anything, mock.Anything).Return(models.Metric{}, models.ErrNotFoundMetric).Twice()
+	mks.metricsService.EXPECT().UpdateList(mock.Anything, []models.Metric{
+		{ID: "memory.usage", MType: "gauge", Value: createValue(300), Labels: labels},
+		{ID: "http.requests", MType: "counter", Delta: createDelta(10), Labels: labels},
+		{ID: "queue.size", MType: "gauge", Value: createValue(4), Labels: labels},
+		{ID: "bytes.sent", MType: "counter", Delta: createDelta(3), Labels: labels},
+		{ID: "http.duration", MType: "histogram", Labels: labels, Histogram: &models.Histogram{
+			Buckets: []models.Bucket{{UpperBound: 0.1, Count: 1}, {UpperBound: 1, Count: 2}}, Sum: 5.5, Count: 4,
+		}},
+	}).Return(nil).Once()
+
+	res := postOTLP(t, handlers, request(
+		gauge("memory.usage", 2, 200),
+		gauge("memory.usage", 3, 300),
+		gauge("memory.usage", 1, 100),
+		sum("http.requests", otlp.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, true, 10),
+		sum("queue.size", otlp.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, false, 4),
+		sum("queue.changes", otlp.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA, false, -1),
+		sum("bytes.sent", otlp.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA, true, 1),
+		sum("bytes.sent", otlp.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA, true, 2),
+		histogram("http.duration", otlp.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, 5.5, 1, 2, 1),
+	))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, otlpProtobufContentType, res.Header.Get("Content-Type"))
+
+	// cumulative values are converted to increments
+	mks.metricsService.EXPECT().UpdateList(mock.Anything, []models.Metric{
+		{ID: "http.requests", MType: "counter", Delta: createDelta(5), Labels: labels},
+		{ID: "http.duration", MType: "histogram", Labels: labels, Histogram: &models.Histogram{
+			Buckets: []models.Bucket{{UpperBound: 0.1, Count: 1}, {UpperBound: 1, Count: 0}}, Sum: 0.5, Count: 2,
+		}},
+	}).Return(nil).Once()
+
+	res = postOTLP(t, handlers, request(
+		sum("http.requests", otlp.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, true, 15),
+		histogram("http.duration", otlp.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, 6, 2, 2, 2),
+	))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	mks.metricsService.AssertExpectations(t)
+}
+
+func Test_metricsHandlers_ExportMetrics_JSON(t *testing.T) {
+	t.Parallel()
+	handlers, mks := getMetricsHandlersMocks()
+
+	body := `{"resourceMetrics":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"checkout"}}]},
+		"scopeMetrics":[{"scope":{"name":"test"},"metrics":[
+			{"name":"cpu.usage","unit":"1","gauge":{"dataPoints":[{"asDouble":0.5,"timeUnixNano":"1700000000000000000",
+				"attributes":[{"key":"cpu","value":{"intValue":"0"}}]}]}},
+			{"name":"jobs.done","sum":{"aggregationTemporality":"AGGREGATION_TEMPORALITY_DELTA","isMonotonic":true,
+				"dataPoints":[{"asInt":"7"}]}}
+		]}]}]}`
+
+	mks.metricsService.EXPECT().UpdateList(mock.Anything, []models.Metric{
+		{ID: "cpu.usage", MType: "gauge", Value: createValue(0.5), Labels: models.Labels{"job": "checkout", "cpu": "0"}},
+		{ID: "jobs.done", MType: "counter", Delta: createDelta(7), Labels: models.Labels{"job": "checkout"}},
+	}).Return(nil).Once()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/metrics", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	handlers.ExportMetrics(rec, req)
+
+	res := rec.Result()
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, otlpJSONContentType, res.Header.Get("Content-Type"))
+	assert.Equal(t, "{}", rec.Body.String())
+	mks.metricsService.AssertExpectations(t)
+}
+
+func Test_metricsHandlers_ExportMetrics_Errors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("unsupported content type", func(t *testing.T) {
+		handlers, _ := getMetricsHandlersMocks()
+		req := httptest.NewRequest(http.MethodPost, "/v1/metrics", strings.NewReader("{}"))
+		req.Header.Set("Content-Type", "text/plain")
+		rec := httptest.NewRecorder()
+
+		handlers.ExportMetrics(rec, req)
+		assert.Equal(t, http.StatusUnsupportedMediaType, rec.Result().StatusCode)
+	})
+
+	t.Run("bad histogram", func(t *testing.T) {
+		handlers, _ := getMetricsHandlersMocks()
+		h := histogram("http.duration", otlp.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA, 1, 1, 2)
+		h.GetHistogram().DataPoints[0].ExplicitBounds = []float64{1, 0.1}
+
+		res := postOTLP(t, handlers, request(h))
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("metricsService.UpdateList returns error", func(t *testing.T) {
+		handlers, mks := getMetricsHandlersMocks()
+		mks.metricsService.EXPECT().UpdateList(mock.Anything, mock.Anything).Return(errors.New("some error")).Once()
+
+		res := postOTLP(t, handlers, request(gauge("memory.usage", 1, 100)))
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	})
+
+	t.Run("metricsService.Get returns error", func(t *testing.T) {
+		handlers, mks := getMetricsHandlersMocks()
+		mks.metricsService.EXPECT().Get(mock.Anything, mock.Anything).Return(models.Metric{}, errors.New("some error")).Once()
+
+		res := postOTLP(t, handlers, request(sum("http.requests", otlp.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, true, 10)))
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		mks.metricsService.AssertExpectations(t)
+	})
+}
+
+func Test_metricsHandlers_ExportMetrics_AddsDeltaSums(t *testing.T) {
+	t.Parallel()
+
+	handlers, ms := getStoredMetricsHandlers()
+
+	for i := 0; i < 2; i++ {
+		res := postOTLP(t, handlers, request(sum("http.requests", otlp.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA, true, 5)))
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}
+
+	assert.Equal(t, int64(10), storedDelta(t, ms, "http.requests"))
+}
+
+func postOTLP(t *testing.T, handlers *metricsHandlers, r *otlp.ExportMetricsServiceRequest) *http.Response {
+	b, err := proto.Marshal(r)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/metrics", bytes.NewReader(b))
+	req.Header.Set("Content-Type", otlpProtobufContentType)
+	rec := httptest.NewRecorder()
+
+	handlers.ExportMetrics(rec, req)
+
+	res := rec.Result()
+	res.Body.Close()
+
+	return res
+}
+
+func request(metrics ...*otlp.Metric) *otlp.ExportMetricsServiceRequest {
+	return &otlp.ExportMetricsServiceRequest{
+		ResourceMetrics: []*otlp.ResourceMetrics{{
+			Resource: &otlp.Resource{Attributes: []*otlp.KeyValue{
+				{Key: "service.name", Value: &otlp.AnyValue{Value: &otlp.AnyValue_StringValue{StringValue: "checkout"}}},
+				{Key: "telemetry.sdk.language", Value: &otlp.AnyValue{Value: &otlp.AnyValue_StringValue{StringValue: "go"}}},
+			}},
+			ScopeMetrics: []*otlp.ScopeMetrics{{Metrics: metrics}},
+		}},
+	}
+}
+
+func attrs() []*otlp.KeyValue {
+	return []*otlp.KeyValue{{Key: "method", Value: &otlp.AnyValue{Value: &otlp.AnyValue_StringValue{StringValue: "GET"}}}}
+}
+
+func gauge(name string, t uint64, v float64) *otlp.Metric {
+	return &otlp.Metric{Name: name, Data: &otlp.Metric_Gauge{Gauge: &otlp.Gauge{
+		DataPoints: []*otlp.NumberDataPoint{{Attributes: attrs(), TimeUnixNano: t, Value: &otlp.NumberDataPoint_AsDouble{AsDouble: v}}},
+	}}}
+}
+
+func sum(name string, temporality otlp.AggregationTemporality, monotonic bool, v int64) *otlp.Metric {
+	return &otlp.Metric{Name: name, Data: &otlp.Metric_Sum{Sum: &otlp.Sum{
+		AggregationTemporality: temporality,
+		IsMonotonic:            monotonic,
+		DataPoints:             []*otlp.NumberDataPoint{{Attributes: attrs(), Value: &otlp.NumberDataPoint_AsInt{AsInt: v}}},
+	}}}
+}
+
+// histogram returns a histogram with bounds 0.1 and 1, the last count is for values above 1
+func histogram(name string, temporality otlp.AggregationTemporality, sum float64, counts ...uint64) *otlp.Metric {
+	var total uint64
+	for _, c := range counts {
+		total += c
+	}
+	return &otlp.Metric{Name: name, Data: &otlp.Metric_Histogram{Histogram: &otlp.Histogram{
+		AggregationTemporality: temporality,
+		DataPoints: []*otlp.HistogramDataPoint{{
+			Attributes:     attrs(),
+			Count:          total,
+			Sum:            sum,
+			BucketCounts:   counts,
+			ExplicitBounds: []float64{0.1, 1},
+		}},
+	}}}
+}
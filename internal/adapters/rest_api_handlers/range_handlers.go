@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+)
+
+type rangeResponse struct {
+	ID          string         `json:"id"`
+	MType       string         `json:"type"`
+	Labels      models.Labels  `json:"labels,omitempty"`
+	Aggregation string         `json:"aggregation"`
+	Points      []models.Point `json:"points"`
+}
+
+// QueryRange returns the metric values between start and end aggregated by step, e.g.:
+//
+//	GET /query_range?id=HeapAlloc&type=gauge&labels=host=a&start=1700000000&end=1700003600&step=60s&aggregation=max
+//
+// start and end accept unix seconds or RFC3339 time, step accepts a duration or seconds.
+// Aggregation is one of avg (default), min, max, last, sum. Labels are optional: k1=v1,k2=v2.
+func (mh *metricsHandlers) QueryRange(w http.ResponseWriter, r *http.Request) {
+	var (
+		q   models.RangeQuery
+		buf bytes.Buffer
+		err error
+	)
+
+	params := r.URL.Query()
+
+	q.ID = params.Get("id")
+	q.MType = params.Get("type")
+	q.Aggregation = params.Get("aggregation")
+	if q.Aggregation == "" {
+		q.Aggregation = models.AggregationAvg
+	}
+
+	q.Labels, err = models.ParseLabels(params.Get("labels"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.Start, err = parseTime(params.Get("start"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.End, err = parseTime(params.Get("end"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.Step, err = parseStep(params.Get("step"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	points, err := mh.metricsService.QueryRange(r.Context(), q)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	err = json.NewEncoder(&buf).Encode(rangeResponse{
+		ID:          q.ID,
+		MType:       q.MType,
+		Labels:      q.Labels,
+		Aggregation: q.Aggregation,
+		Points:      points,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(buf.Bytes())
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// parseTime parses unix time in seconds with optional fraction or RFC3339 time
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, errors.New("time parameter is empty")
+	}
+
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse %q to a valid timestamp", s)
+	}
+
+	return t, nil
+}
+
+// parseStep parses duration string like 15s or a number of seconds
+func parseStep(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, errors.New("step parameter is empty")
+	}
+
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(f * float64(time.Second)), nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse %q to a valid duration", s)
+	}
+
+	return d, nil
+}
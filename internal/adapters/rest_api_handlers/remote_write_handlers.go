@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+	"github.com/Chystik/runtime-metrics/protobuf/prompb"
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/proto"
+)
+
+const metricNameLabel = "__name__"
+
+// remoteWriteState keeps what Prometheus doesn't send with every request:
+// metric family types from metadata and the last cumulative values of counters
+type remoteWriteState struct {
+	mu       sync.Mutex
+	types    map[string]prompb.MetricMetadata_MetricType
+	counters *cumulativeState
+}
+
+func newRemoteWriteState() *remoteWriteState {
+	return &remoteWriteState{
+		types:    make(map[string]prompb.MetricMetadata_MetricType),
+		counters: newCumulativeState(),
+	}
+}
+
+// RemoteWrite receives samples with the Prometheus remote write protocol 1.0:
+// snappy compressed protobuf WriteRequest sent to POST /api/v1/write.
+//
+// The __name__ label becomes the metric ID and the rest labels are kept.
+// Counters, buckets, counts and sums of histograms and summaries are stored as
+// counters, the type is taken from metadata or from the _total suffix. Prometheus
+// counters are cumulative, so the increment since the last received value is
+// stored. Increments are rounded from cumulative values, so totals of float
+// counters stay within 1 of the source. The first sample of a counter that is
+// already stored is used only as a baseline. Other series are stored as gauges
+// with the latest value, stale markers are skipped.
+func (mh *metricsHandlers) RemoteWrite(w http.ResponseWriter, r *http.Request) {
+	compressed, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	b, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req prompb.WriteRequest
+	if err = proto.Unmarshal(b, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	st := mh.remoteWrite
+	st.mu.Lock()
+	for _, md := range req.GetMetadata() {
+		st.types[md.GetMetricFamilyName()] = md.GetType()
+	}
+	st.mu.Unlock()
+
+	var (
+		metrics []models.Metric
+		points  []cumulativePoint
+		pos     []int // position of the point series in metrics
+	)
+
+	for _, ts := range req.GetTimeseries() {
+		m, err := seriesMetric(ts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var samples []*prompb.Sample
+		for _, s := range ts.GetSamples() {
+			if !math.IsNaN(s.GetValue()) && !math.IsInf(s.GetValue(), 0) {
+				samples = append(samples, s)
+			}
+		}
+		if len(samples) == 0 {
+			continue
+		}
+
+		if st.isCounter(m.ID) {
+			var delta int64
+			m.MType = "counter"
+			for _, s := range samples {
+				points = append(points, cumulativePoint{metric: m, counter: s.GetValue()})
+				pos = append(pos, len(metrics))
+			}
+			m.Delta = &delta
+		} else {
+			v := samples[len(samples)-1].GetValue()
+			m.MType = "gauge"
+			m.Value = &v
+		}
+		metrics = append(metrics, m)
+	}
+
+	increments, rollback, err := st.counters.increments(r.Context(), mh.metricsService, points)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	for i, m := range increments {
+		*metrics[pos[i]].Delta += *m.Delta
+	}
+	metrics = mergeSeries(metrics)
+
+	if len(metrics) > 0 {
+		err = mh.metricsService.UpdateList(r.Context(), metrics)
+		if err != nil {
+			// Prometheus retries on 5xx, the counter values are restored for the retry
+			rollback()
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// mergeSeries merges metrics of the same series, the same series can be split between
+// several time series of the request: counter increments are summed, the last gauge value is kept
+func mergeSeries(metrics []models.Metric) []models.Metric {
+	idx := make(map[string]int)
+	merged := metrics[:0]
+
+	for _, m := range metrics {
+		if i, ok := idx[m.MType+":"+m.Key()]; ok {
+			if m.MType == "counter" {
+				*merged[i].Delta += *m.Delta
+			} else {
+				merged[i] = m
+			}
+			continue
+		}
+		idx[m.MType+":"+m.Key()] = len(merged)
+		merged = append(merged, m)
+	}
+
+	return merged
+}
+
+// isCounter reports whether the series is cumulative
+func (st *remoteWriteState) isCounter(name string) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if t, ok := st.types[name]; ok {
+		return t == prompb.MetricMetadata_COUNTER
+	}
+
+	for _, suffix := range []string{"_bucket", "_count", "_sum"} {
+		family, ok := strings.CutSuffix(name, suffix)
+		if !ok {
+			continue
+		}
+		if t := st.types[family]; t == prompb.MetricMetadata_HISTOGRAM || t == prompb.MetricMetadata_SUMMARY {
+			return true
+		}
+	}
+
+	return strings.HasSuffix(name, "_total")
+}
+
+// counterIncrease returns the increase of the cumulative counter, a decrease means the counter was reset
+func counterIncrease(prev, cur float64) int64 {
+	if cur < prev {
+		return int64(math.Round(cur))
+	}
+	return int64(math.Round(cur)) - int64(math.Round(prev))
+}
+
+// seriesMetric returns the metric with the ID and labels of the time series
+func seriesMetric(ts *prompb.TimeSeries) (models.Metric, error) {
+	var m models.Metric
+
+	for _, l := range ts.GetLabels() {
+		if l.GetName() == metricNameLabel {
+			m.ID = l.GetValue()
+			continue
+		}
+		if m.Labels == nil {
+			m.Labels = make(models.Labels)
+		}
+		m.Labels[l.GetName()] = l.GetValue()
+	}
+
+	if m.ID == "" {
+		return m, fmt.Errorf("time series without %s label", metricNameLabel)
+	}
+
+	return m, nil
+}
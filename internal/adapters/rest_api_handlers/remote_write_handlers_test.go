@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+	"github.com/Chystik/runtime-metrics/protobuf/prompb"
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_metricsHandlers_RemoteWrite(t *testing.T) {
+	t.Parallel()
+	handlers, mks := getMetricsHandlersMocks()
+
+	// process_cpu_seconds_total is stored, the rest counters are new
+	mks.metricsService.EXPECT().Get(mock.Anything, models.Metric{
+		ID: "http_requests_total", MType: "counter", Labels: models.Labels{"job": "api"},
+	}).Return(models.Metric{}, models.ErrNotFoundMetric).Once()
+	mks.metricsService.EXPECT().Get(mock.Anything, models.Metric{
+		ID: "latency_seconds_bucket", MType: "counter", Labels: models.Labels{"le": "0.5"},
+	}).Return(models.Metric{}, models.ErrNotFoundMetric).Once()
+	mks.metricsService.EXPECT().Get(mock.Anything, models.Metric{
+		ID: "process_cpu_seconds_total", MType: "counter",
+	}).Return(models.Metric{}, nil).Once()
+
+	mks.metricsService.EXPECT().UpdateList(mock.Anything, []models.Metric{
+		{ID: "http_requests_total", MType: "counter", Delta: createDelta(12), Labels: models.Labels{"job": "api"}},
+		{ID: "process_cpu_seconds_total", MType: "counter", Delta: createDelta(2)},
+		{ID: "memory_bytes", MType: "gauge", Value: createValue(2048)},
+		{ID: "latency_seconds_bucket", MType: "counter", Delta: createDelta(3), Labels: models.Labels{"le": "0.5"}},
+	}).Return(nil).Once()
+
+	res := postRemoteWrite(t, handlers, &prompb.WriteRequest{
+		Metadata: []*prompb.MetricMetadata{
+			{Type: prompb.MetricMetadata_HISTOGRAM, MetricFamilyName: "latency_seconds"},
+		},
+		Timeseries: []*prompb.TimeSeries{
+			series("http_requests_total", []string{"job", "api"}, 10, 12),
+			series("process_cpu_seconds_total", nil, 3.2, 4.6, 5.1),
+			series("memory_bytes", nil, 1024, math.NaN(), 2048),
+			series("latency_seconds_bucket", []string{"le", "0.5"}, 1, 3),
+			series("stale_total", nil, math.NaN()),
+		},
+	})
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+	// the next request stores the increase since the last values, a decrease is a reset
+	mks.metricsService.EXPECT().UpdateList(mock.Anything, []models.Metric{
+		{ID: "http_requests_total", MType: "counter", Delta: createDelta(3), Labels: models.Labels{"job": "api"}},
+		{ID: "process_cpu_seconds_total", MType: "counter", Delta: createDelta(1)},
+	}).Return(nil).Once()
+
+	res = postRemoteWrite(t, handlers, &prompb.WriteRequest{
+		Timeseries: []*prompb.TimeSeries{
+			series("http_requests_total", []string{"job", "api"}, 15),
+			series("process_cpu_seconds_total", nil, 0.7),
+		},
+	})
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+	mks.metricsService.AssertExpectations(t)
+}
+
+func Test_metricsHandlers_RemoteWrite_ServiceReturnsError(t *testing.T) {
+	t.Parallel()
+	handlers, mks := getMetricsHandlersMocks()
+
+	mks.metricsService.EXPECT().Get(mock.Anything, mock.Anything).Return(models.Metric{}, models.ErrNotFoundMetric).Twice()
+	mks.metricsService.EXPECT().UpdateList(mock.Anything, mock.Anything).Return(errors.New("some error")).Once()
+
+	req := &prompb.WriteRequest{Timeseries: []*prompb.TimeSeries{series("http_requests_total", nil, 10)}}
+
+	res := postRemoteWrite(t, handlers, req)
+	assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+
+	// the retry stores the same increase
+	mks.metricsService.EXPECT().UpdateList(mock.Anything, []models.Metric{
+		{ID: "http_requests_total", MType: "counter", Delta: createDelta(10)},
+	}).Return(nil).Once()
+
+	res = postRemoteWrite(t, handlers, req)
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	mks.metricsService.AssertExpectations(t)
+}
+
+func Test_metricsHandlers_RemoteWrite_GetReturnsError(t *testing.T) {
+	t.Parallel()
+	handlers, mks := getMetricsHandlersMocks()
+
+	// the counter may be stored, so all its value can't be stored as the increase
+	mks.metricsService.EXPECT().Get(mock.Anything, mock.Anything).Return(models.Metric{}, errors.New("some error")).Once()
+
+	res := postRemoteWrite(t, handlers, &prompb.WriteRequest{Timeseries: []*prompb.TimeSeries{series("http_requests_total", nil, 10)}})
+	assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	mks.metricsService.AssertExpectations(t)
+}
+
+func Test_metricsHandlers_RemoteWrite_BadRequest(t *testing.T) {
+	t.Parallel()
+	handlers, _ := getMetricsHandlersMocks()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/write", bytes.NewReader([]byte("not snappy")))
+	rec := httptest.NewRecorder()
+	handlers.RemoteWrite(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Result().StatusCode)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/write", bytes.NewReader(snappy.Encode(nil, []byte("not protobuf"))))
+	rec = httptest.NewRecorder()
+	handlers.RemoteWrite(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Result().StatusCode)
+
+	res := postRemoteWrite(t, handlers, &prompb.WriteRequest{
+		Timeseries: []*prompb.TimeSeries{{Samples: []*prompb.Sample{{Value: 1}}}},
+	})
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func Test_metricsHandlers_RemoteWrite_AddsCounters(t *testing.T) {
+	t.Parallel()
+
+	handlers, ms := getStoredMetricsHandlers()
+
+	res := postRemoteWrite(t, handlers, &prompb.WriteRequest{Timeseries: []*prompb.TimeSeries{series("http_requests_total", nil, 10)}})
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	res = postRemoteWrite(t, handlers, &prompb.WriteRequest{Timeseries: []*prompb.TimeSeries{series("http_requests_total", nil, 25)}})
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+	assert.Equal(t, int64(25), storedDelta(t, ms, "http_requests_total"))
+}
+
+func postRemoteWrite(t *testing.T, handlers *metricsHandlers, wr *prompb.WriteRequest) *http.Response {
+	b, err := proto.Marshal(wr)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/write", bytes.NewReader(snappy.Encode(nil, b)))
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	rec := httptest.NewRecorder()
+
+	handlers.RemoteWrite(rec, req)
+
+	res := rec.Result()
+	res.Body.Close()
+
+	return res
+}
+
+// series returns the time series with samples one second apart, labels are name-value pairs
+func series(name string, labels []string, values ...float64) *prompb.TimeSeries {
+	ts := &prompb.TimeSeries{Labels: []*prompb.Label{{Name: metricNameLabel, Value: name}}}
+	for i := 0; i+1 < len(labels); i += 2 {
+		ts.Labels = append(ts.Labels, &prompb.Label{Name: labels[i], Value: labels[i+1]})
+	}
+	for i, v := range values {
+		ts.Samples = append(ts.Samples, &prompb.Sample{Value: v, Timestamp: 1700000000000 + int64(i)*1000})
+	}
+	return ts
+}
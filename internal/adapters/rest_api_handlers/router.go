@@ -56,7 +56,12 @@ func NewRouter(
 		router.Get("/ping", dh.PingDB)
 	}
 	router.Get("/", mh.AllMetrics)
+	router.Get("/query_range", mh.QueryRange)
+	router.Get("/metrics", mh.Exposition)
 	router.Post("/updates/", mh.UpdateMetricsJSON)
+	router.Post("/write", mh.WriteLineProtocol)
+	router.Post("/api/v1/write", mh.RemoteWrite)
+	router.Post("/v1/metrics", mh.ExportMetrics)
 
 	return nil
 }
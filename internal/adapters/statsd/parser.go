@@ -0,0 +1,90 @@
+package statsd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+)
+
+var (
+	ErrInvalidLine = errors.New("invalid statsd line")
+)
+
+// sample is a single value of a statsd line
+type sample struct {
+	name     string
+	mType    string
+	value    float64
+	relative bool // gauge value with explicit sign changes the current value
+	rate     float64
+	labels   models.Labels
+}
+
+// parseLine parses a line in the form name:value|type[|@rate][|#tag:value,...],
+// supported types are c (counter), g (gauge) and ms, h, d (timers)
+func parseLine(line string) (sample, error) {
+	s := sample{rate: 1}
+
+	name, rest, ok := strings.Cut(line, ":")
+	if !ok || name == "" {
+		return s, fmt.Errorf("%w: %q", ErrInvalidLine, line)
+	}
+	s.name = name
+
+	parts := strings.Split(rest, "|")
+	if len(parts) < 2 {
+		return s, fmt.Errorf("%w: %q", ErrInvalidLine, line)
+	}
+
+	v, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return s, fmt.Errorf("%w: bad value in %q", ErrInvalidLine, line)
+	}
+	s.value = v
+
+	switch parts[1] {
+	case "c":
+		s.mType = "counter"
+	case "g":
+		s.mType = "gauge"
+		s.relative = strings.HasPrefix(parts[0], "+") || strings.HasPrefix(parts[0], "-")
+	case "ms", "h", "d":
+		s.mType = "summary"
+	default:
+		return s, fmt.Errorf("%w: unsupported type %q", ErrInvalidLine, parts[1])
+	}
+
+	for _, p := range parts[2:] {
+		switch {
+		case strings.HasPrefix(p, "@"):
+			s.rate, err = strconv.ParseFloat(p[1:], 64)
+			if err != nil || s.rate <= 0 || s.rate > 1 {
+				return s, fmt.Errorf("%w: bad sample rate in %q", ErrInvalidLine, line)
+			}
+		case strings.HasPrefix(p, "#"):
+			s.labels = parseTags(p[1:])
+		}
+	}
+
+	return s, nil
+}
+
+// parseTags parses DogStatsD tags: k1:v1,k2:v2, a tag without value gets an empty one
+func parseTags(s string) models.Labels {
+	if s == "" {
+		return nil
+	}
+
+	labels := make(models.Labels)
+	for _, t := range strings.Split(s, ",") {
+		k, v, _ := strings.Cut(t, ":")
+		if k != "" {
+			labels[k] = v
+		}
+	}
+
+	return labels
+}
@@ -0,0 +1,82 @@
+package statsd
+
+import (
+	"testing"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseLine(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		line    string
+		want    sample
+		wantErr bool
+	}{
+		{
+			name: "counter",
+			line: "requests:1|c",
+			want: sample{name: "requests", mType: "counter", value: 1, rate: 1},
+		},
+		{
+			name: "counter with sample rate and tags",
+			line: "requests:2|c|@0.5|#host:a,dc:eu",
+			want: sample{name: "requests", mType: "counter", value: 2, rate: 0.5, labels: models.Labels{"host": "a", "dc": "eu"}},
+		},
+		{
+			name: "gauge",
+			line: "temperature:3.2|g",
+			want: sample{name: "temperature", mType: "gauge", value: 3.2, rate: 1},
+		},
+		{
+			name: "relative gauge",
+			line: "temperature:-1|g",
+			want: sample{name: "temperature", mType: "gauge", value: -1, relative: true, rate: 1},
+		},
+		{
+			name: "timer",
+			line: "latency:320|ms",
+			want: sample{name: "latency", mType: "summary", value: 320, rate: 1},
+		},
+		{
+			name:    "no value",
+			line:    "requests",
+			wantErr: true,
+		},
+		{
+			name:    "no type",
+			line:    "requests:1",
+			wantErr: true,
+		},
+		{
+			name:    "bad value",
+			line:    "requests:one|c",
+			wantErr: true,
+		},
+		{
+			name:    "set is not supported",
+			line:    "users:42|s",
+			wantErr: true,
+		},
+		{
+			name:    "bad sample rate",
+			line:    "requests:1|c|@2",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLine(tt.line)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidLine)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package statsd
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+	"github.com/Chystik/runtime-metrics/internal/service"
+)
+
+// maxPacketSize is the maximum UDP payload size
+const maxPacketSize = 65535
+
+var (
+	ErrServerClosed = errors.New("statsd: server closed")
+)
+
+// Server listens for StatsD lines over UDP, aggregates them and
+// sends them to the metrics service every flush interval.
+type Server struct {
+	addr     string
+	ms       service.MetricsService
+	logger   service.AppLogger
+	interval time.Duration
+
+	mu   sync.Mutex
+	conn net.PacketConn
+	buf  *buffer
+	// gauges keep the last gauge values over flushes as bases of relative changes
+	gauges map[string]float64
+	// shutdown is set by Shutdown, so a connection opened after it is closed at once
+	shutdown bool
+	closed   chan struct{}
+}
+
+// buffer aggregates samples between flushes
+type buffer struct {
+	counters  map[string]models.Metric
+	gauges    map[string]models.Metric
+	summaries map[string]models.Metric
+}
+
+func NewServer(addr string, ms service.MetricsService, logger service.AppLogger, flushInterval time.Duration) *Server {
+	return &Server{
+		addr:     addr,
+		ms:       ms,
+		logger:   logger,
+		interval: flushInterval,
+		buf:      newBuffer(),
+		gauges:   make(map[string]float64),
+		closed:   make(chan struct{}),
+	}
+}
+
+// ListenAndServe reads packets until Shutdown, it always returns a non-nil error
+func (s *Server) ListenAndServe() error {
+	conn, err := net.ListenPacket("udp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if s.shutdown {
+		s.mu.Unlock()
+		conn.Close()
+		return ErrServerClosed
+	}
+	s.conn = conn
+	s.mu.Unlock()
+
+	go s.flushLoop()
+
+	b := make([]byte, maxPacketSize)
+	for {
+		n, _, err := conn.ReadFrom(b)
+		if err != nil {
+			select {
+			case <-s.closed:
+				return ErrServerClosed
+			default:
+				return err
+			}
+		}
+		s.handlePacket(context.Background(), b[:n])
+	}
+}
+
+// Shutdown stops reading packets and flushes buffered metrics
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	conn := s.conn
+	if !s.shutdown {
+		s.shutdown = true
+		close(s.closed)
+	}
+	s.mu.Unlock()
+
+	if conn != nil {
+		if err := conn.Close(); err != nil {
+			return err
+		}
+	}
+
+	return s.flush(ctx)
+}
+
+func (s *Server) flushLoop() {
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			if err := s.flush(context.Background()); err != nil {
+				s.logger.Error(err.Error())
+			}
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+// handlePacket adds all lines of the packet to the buffer, bad lines are logged and skipped
+func (s *Server) handlePacket(ctx context.Context, p []byte) {
+	var samples []sample
+
+	for _, line := range strings.Split(string(p), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		smp, err := parseLine(line)
+		if err != nil {
+			s.logger.Error(err.Error())
+			continue
+		}
+		samples = append(samples, smp)
+	}
+
+	bases := s.storedGauges(ctx, samples)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, v := range bases {
+		if _, ok := s.gauges[key]; !ok {
+			s.gauges[key] = v
+		}
+	}
+	for _, smp := range samples {
+		s.add(smp)
+	}
+}
+
+// storedGauges reads stored values of relatively changed gauges that have no base yet,
+// the service is called without holding the lock
+func (s *Server) storedGauges(ctx context.Context, samples []sample) map[string]float64 {
+	var missing []models.Metric
+
+	s.mu.Lock()
+	for _, smp := range samples {
+		if smp.mType != "gauge" || !smp.relative {
+			continue
+		}
+		m := models.Metric{ID: smp.name, MType: smp.mType, Labels: smp.labels}
+		if _, ok := s.gauges[m.Key()]; !ok {
+			missing = append(missing, m)
+		}
+	}
+	s.mu.Unlock()
+
+	bases := make(map[string]float64, len(missing))
+	for _, m := range missing {
+		key := m.Key()
+		if _, ok := bases[key]; ok {
+			continue
+		}
+
+		stored, err := s.ms.Get(ctx, m)
+		if err != nil || stored.Value == nil {
+			bases[key] = 0
+			continue
+		}
+		bases[key] = *stored.Value
+	}
+
+	return bases
+}
+
+// add aggregates the sample into the buffer, the caller must hold the lock
+func (s *Server) add(smp sample) {
+	m := models.Metric{ID: smp.name, MType: smp.mType, Labels: smp.labels}
+	key := m.Key()
+
+	switch smp.mType {
+	case "counter":
+		delta := int64(math.Round(smp.value / smp.rate))
+		if c, ok := s.buf.counters[key]; ok {
+			*c.Delta += delta
+			return
+		}
+		m.Delta = &delta
+		s.buf.counters[key] = m
+	case "gauge":
+		v := smp.value
+		if smp.relative {
+			v += s.gauges[key]
+		}
+		s.gauges[key] = v
+		m.Value = &v
+		s.buf.gauges[key] = m
+	case "summary":
+		sm, ok := s.buf.summaries[key]
+		if !ok {
+			m.Summary = models.NewSummary()
+			sm = m
+			s.buf.summaries[key] = sm
+		}
+		// a sampled timer stands for 1/rate observations
+		n := int(math.Max(1, math.Round(1/smp.rate)))
+		for i := 0; i < n; i++ {
+			sm.Summary.Observe(smp.value)
+		}
+	}
+}
+
+// flush sends buffered metrics to the service: counters one by one to keep
+// increments additive in every repository, gauges and summaries in a batch
+func (s *Server) flush(ctx context.Context) error {
+	s.mu.Lock()
+	b := s.buf
+	s.buf = newBuffer()
+	s.mu.Unlock()
+
+	var errs []error
+
+	for _, m := range b.counters {
+		if err := s.ms.UpdateCounter(ctx, m); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	list := make([]models.Metric, 0, len(b.gauges)+len(b.summaries))
+	for _, m := range b.gauges {
+		list = append(list, m)
+	}
+	for _, m := range b.summaries {
+		list = append(list, m)
+	}
+	if len(list) > 0 {
+		if err := s.ms.UpdateList(ctx, list); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func newBuffer() *buffer {
+	return &buffer{
+		counters:  make(map[string]models.Metric),
+		gauges:    make(map[string]models.Metric),
+		summaries: make(map[string]models.Metric),
+	}
+}
@@ -0,0 +1,152 @@
+package statsd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+	"github.com/Chystik/runtime-metrics/internal/service/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_handlePacketAndFlush(t *testing.T) {
+	t.Parallel()
+	ms := &mocks.MetricsService{}
+	logger := &mocks.Logger{}
+	s := NewServer(":0", ms, logger, time.Minute)
+
+	stored := models.Metric{ID: "temperature", MType: "gauge", Value: createValue(20)}
+
+	ms.EXPECT().Get(mock.Anything, mock.Anything).Return(stored, nil).Once()
+	logger.EXPECT().Error(mock.Anything).Return().Once()
+	ms.EXPECT().UpdateCounter(mock.Anything, mock.MatchedBy(func(m models.Metric) bool {
+		return m.ID == "requests" && *m.Delta == 5
+	})).Return(nil).Once()
+	ms.EXPECT().UpdateList(mock.Anything, mock.MatchedBy(func(list []models.Metric) bool {
+		if len(list) != 2 {
+			return false
+		}
+		g, sm := list[0], list[1]
+		return g.ID == "temperature" && *g.Value == 22 &&
+			sm.ID == "latency" && sm.Summary.Sketch.Count == 4 && sm.Labels["host"] == "a"
+	})).Return(nil).Once()
+
+	s.handlePacket(context.Background(), []byte("requests:1|c\nrequests:2|c|@0.5\n\ntemperature:+2|g\nbad line"))
+	s.handlePacket(context.Background(), []byte("latency:10|ms|#host:a\nlatency:20|ms|@0.5|#host:a\nlatency:30|ms|#host:a"))
+
+	err := s.flush(context.Background())
+	assert.NoError(t, err)
+	ms.AssertExpectations(t)
+	logger.AssertExpectations(t)
+
+	// the buffer is empty after flush
+	err = s.flush(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestServer_flush_WhenServiceReturnsError(t *testing.T) {
+	t.Parallel()
+	ms := &mocks.MetricsService{}
+	s := NewServer(":0", ms, &mocks.Logger{}, time.Minute)
+
+	ms.EXPECT().UpdateCounter(mock.Anything, mock.Anything).Return(errors.New("some error"))
+
+	s.handlePacket(context.Background(), []byte("requests:1|c"))
+
+	assert.Error(t, s.flush(context.Background()))
+}
+
+func TestServer_ListenAndServe(t *testing.T) {
+	t.Parallel()
+	ms := &mocks.MetricsService{}
+	s := NewServer("127.0.0.1:0", ms, &mocks.Logger{}, time.Minute)
+
+	done := make(chan error)
+	go func() {
+		done <- s.ListenAndServe()
+	}()
+
+	var addr net.Addr
+	require.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.conn == nil {
+			return false
+		}
+		addr = s.conn.LocalAddr()
+		return true
+	}, time.Second, 10*time.Millisecond)
+
+	conn, err := net.Dial("udp", addr.String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("requests:1|c"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(s.buf.counters) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	ms.EXPECT().UpdateCounter(mock.Anything, mock.Anything).Return(nil).Once()
+
+	assert.NoError(t, s.Shutdown(context.Background()))
+	assert.ErrorIs(t, <-done, ErrServerClosed)
+	ms.AssertExpectations(t)
+}
+
+func TestServer_handlePacket_KeepsGaugeBaseOverFlushes(t *testing.T) {
+	t.Parallel()
+	ms := &mocks.MetricsService{}
+	s := NewServer(":0", ms, &mocks.Logger{}, time.Minute)
+
+	stored := models.Metric{ID: "temperature", MType: "gauge", Value: createValue(20)}
+	gaugeIs := func(v float64) interface{} {
+		return mock.MatchedBy(func(list []models.Metric) bool {
+			return len(list) == 1 && *list[0].Value == v
+		})
+	}
+
+	ms.EXPECT().Get(mock.Anything, mock.Anything).Return(stored, nil).Once()
+	ms.EXPECT().UpdateList(mock.Anything, gaugeIs(22)).Return(nil).Once()
+	ms.EXPECT().UpdateList(mock.Anything, gaugeIs(21)).Return(nil).Once()
+
+	s.handlePacket(context.Background(), []byte("temperature:+2|g"))
+	require.NoError(t, s.flush(context.Background()))
+
+	s.handlePacket(context.Background(), []byte("temperature:-1|g"))
+	require.NoError(t, s.flush(context.Background()))
+
+	ms.AssertExpectations(t)
+}
+
+func TestServer_ListenAndServe_AfterShutdown(t *testing.T) {
+	t.Parallel()
+	s := NewServer("127.0.0.1:0", &mocks.MetricsService{}, &mocks.Logger{}, time.Minute)
+
+	require.NoError(t, s.Shutdown(context.Background()))
+
+	done := make(chan error)
+	go func() {
+		done <- s.ListenAndServe()
+	}()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, ErrServerClosed)
+	case <-time.After(time.Second):
+		t.Fatal("server is serving after shutdown")
+	}
+}
+
+func createValue(x float64) *float64 {
+	return &x
+}
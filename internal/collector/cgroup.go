@@ -0,0 +1,202 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+)
+
+const CgroupName = "cgroup"
+
+// cgroupCPUStat maps cpu.stat keys to cumulative counters
+var cgroupCPUStat = map[string]string{
+	"usage_usec":     "CgroupCPUUsageUsec",
+	"user_usec":      "CgroupCPUUserUsec",
+	"system_usec":    "CgroupCPUSystemUsec",
+	"nr_periods":     "CgroupCPUPeriods",
+	"nr_throttled":   "CgroupCPUThrottledPeriods",
+	"throttled_usec": "CgroupCPUThrottledUsec",
+}
+
+// cgroupIOStat maps io.stat keys to cumulative counters
+var cgroupIOStat = map[string]string{
+	"rbytes": "CgroupIOReadBytes",
+	"wbytes": "CgroupIOWriteBytes",
+	"rios":   "CgroupIOReads",
+	"wios":   "CgroupIOWrites",
+}
+
+// cgroup reads resource usage of the cgroup v2 the agent runs in
+type cgroup struct {
+	interval time.Duration
+	fsys     fs.FS
+	counters counters
+}
+
+// NewCgroup returns a collector of the cgroup v2 mounted at path
+func NewCgroup(interval time.Duration, path string) *cgroup {
+	return &cgroup{
+		interval: interval,
+		fsys:     os.DirFS(path),
+		counters: make(counters),
+	}
+}
+
+func (c *cgroup) Name() string {
+	return CgroupName
+}
+
+func (c *cgroup) Interval() time.Duration {
+	return c.interval
+}
+
+// Collect returns memory and pids usage and limits as gauges, CPU, throttling and IO
+// counters increments since the previous collect. Files missing outside of a cgroup v2
+// hierarchy or when a controller is not enabled are skipped, so are unlimited limits.
+func (c *cgroup) Collect(ctx context.Context) ([]models.Metric, error) {
+	var (
+		metrics []models.Metric
+		errs    []error
+	)
+
+	for _, f := range []struct {
+		file string
+		id   string
+	}{
+		{"memory.current", "CgroupMemoryUsage"},
+		{"memory.max", "CgroupMemoryLimit"},
+		{"pids.current", "CgroupPids"},
+		{"pids.max", "CgroupPidsLimit"},
+	} {
+		v, ok, err := c.readValue(f.file)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if ok {
+			metrics = append(metrics, gauge(f.id, float64(v)))
+		}
+	}
+
+	metrics, err := c.readCPUStat(metrics)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	metrics, err = c.readIOStat(metrics)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return metrics, errors.Join(errs...)
+}
+
+// readValue reads a single value file, false if the file is missing or the value is max
+func (c *cgroup) readValue(name string) (uint64, bool, error) {
+	b, err := fs.ReadFile(c.fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	s := string(bytes.TrimSpace(b))
+	if s == "max" {
+		return 0, false, nil
+	}
+
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return v, true, nil
+}
+
+// readCPUStat appends counters of cpu.stat lines in a form key value
+func (c *cgroup) readCPUStat(metrics []models.Metric) ([]models.Metric, error) {
+	lines, err := c.readLines("cpu.stat")
+	if err != nil {
+		return metrics, err
+	}
+
+	for _, line := range lines {
+		k, v, _ := strings.Cut(line, " ")
+		id, ok := cgroupCPUStat[k]
+		if !ok {
+			continue
+		}
+
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return metrics, fmt.Errorf("cpu.stat: %w", err)
+		}
+		metrics = c.counters.add(metrics, id, nil, n)
+	}
+
+	return metrics, nil
+}
+
+// readIOStat appends counters of io.stat lines in a form major:minor key=value... labeled by device
+func (c *cgroup) readIOStat(metrics []models.Metric) ([]models.Metric, error) {
+	lines, err := c.readLines("io.stat")
+	if err != nil {
+		return metrics, err
+	}
+
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		labels := models.Labels{"device": fields[0]}
+
+		for _, f := range fields[1:] {
+			k, v, _ := strings.Cut(f, "=")
+			id, ok := cgroupIOStat[k]
+			if !ok {
+				continue
+			}
+
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return metrics, fmt.Errorf("io.stat: %w", err)
+			}
+			metrics = c.counters.add(metrics, id, labels, n)
+		}
+	}
+
+	return metrics, nil
+}
+
+// readLines returns lines of the file, nothing if the file is missing
+func (c *cgroup) readLines(name string) ([]string, error) {
+	f, err := c.fsys.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		lines = append(lines, s.Text())
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,83 @@
+package collector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeCgroupFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+}
+
+func Test_cgroup_Collect(t *testing.T) {
+	dir := t.TempDir()
+	writeCgroupFiles(t, dir, map[string]string{
+		"memory.current": "1048576\n",
+		"memory.max":     "max\n",
+		"pids.current":   "12\n",
+		"pids.max":       "100\n",
+		"cpu.stat":       "usage_usec 1000\nuser_usec 600\nsystem_usec 400\nnr_periods 10\nnr_throttled 1\nthrottled_usec 50\n",
+		"io.stat":        "8:0 rbytes=100 wbytes=200 rios=1 wios=2 dbytes=0 dios=0\n",
+	})
+
+	c := NewCgroup(time.Second, dir)
+	assert.Equal(t, CgroupName, c.Name())
+
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CgroupMemoryUsage", "CgroupPids", "CgroupPidsLimit"}, metricIDs(metrics))
+
+	writeCgroupFiles(t, dir, map[string]string{
+		"cpu.stat": "usage_usec 3000\nuser_usec 1600\nsystem_usec 1400\nnr_periods 20\nnr_throttled 4\nthrottled_usec 250\n",
+		"io.stat":  "8:0 rbytes=150 wbytes=200 rios=2 wios=2 dbytes=0 dios=0\n",
+	})
+
+	metrics, err = c.Collect(context.Background())
+	require.NoError(t, err)
+
+	byID := make(map[string]models.Metric)
+	for _, m := range metrics {
+		byID[m.ID] = m
+	}
+	assert.Equal(t, 1048576.0, *byID["CgroupMemoryUsage"].Value)
+	assert.NotContains(t, byID, "CgroupMemoryLimit")
+	assert.Equal(t, 100.0, *byID["CgroupPidsLimit"].Value)
+	assert.Equal(t, int64(2000), *byID["CgroupCPUUsageUsec"].Delta)
+	assert.Equal(t, int64(3), *byID["CgroupCPUThrottledPeriods"].Delta)
+	assert.Equal(t, int64(200), *byID["CgroupCPUThrottledUsec"].Delta)
+	assert.Equal(t, int64(50), *byID["CgroupIOReadBytes"].Delta)
+	assert.Equal(t, models.Labels{"device": "8:0"}, byID["CgroupIOReadBytes"].Labels)
+}
+
+func Test_cgroup_Collect_WhenFilesAreMissing(t *testing.T) {
+	c := NewCgroup(time.Second, t.TempDir())
+
+	metrics, err := c.Collect(context.Background())
+
+	assert.NoError(t, err)
+	assert.Empty(t, metrics)
+}
+
+func Test_cgroup_Collect_WhenValueIsInvalid(t *testing.T) {
+	dir := t.TempDir()
+	writeCgroupFiles(t, dir, map[string]string{
+		"memory.current": "abc\n",
+		"pids.current":   "3\n",
+	})
+
+	metrics, err := NewCgroup(time.Second, dir).Collect(context.Background())
+
+	assert.ErrorContains(t, err, "memory.current")
+	assert.Equal(t, []string{"CgroupPids"}, metricIDs(metrics))
+}
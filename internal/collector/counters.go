@@ -0,0 +1,52 @@
+package collector
+
+import (
+	"math"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+)
+
+// counters keep cumulative values of the previous collect to report them as increments
+type counters map[string]uint64
+
+// add appends the increment of the cumulative value v since the previous collect, nothing
+// is appended for the first value. A decreased value means a reset and is reported as is.
+func (c counters) add(metrics []models.Metric, id string, labels models.Labels, v uint64) []models.Metric {
+	key := id + "{" + labels.String() + "}"
+
+	prev, ok := c[key]
+	c[key] = v
+	if !ok {
+		return metrics
+	}
+	if v < prev {
+		prev = 0
+	}
+
+	delta := int64(v - prev)
+	return append(metrics, models.Metric{ID: id, MType: "counter", Delta: &delta, Labels: labels})
+}
+
+func labeledGauge(id string, v float64, labels models.Labels) models.Metric {
+	return models.Metric{ID: id, MType: "gauge", Value: &v, Labels: labels}
+}
+
+// floatCounters keep cumulative float values of the previous collect to report
+// increments of their integer parts, so fractions are not lost over collects
+type floatCounters map[string]float64
+
+func (c floatCounters) add(metrics []models.Metric, id string, labels models.Labels, v float64) []models.Metric {
+	key := id + "{" + labels.String() + "}"
+
+	prev, ok := c[key]
+	c[key] = v
+	if !ok {
+		return metrics
+	}
+	if v < prev {
+		prev = 0
+	}
+
+	delta := int64(math.Floor(v) - math.Floor(prev))
+	return append(metrics, models.Metric{ID: id, MType: "counter", Delta: &delta, Labels: labels})
+}
@@ -0,0 +1,131 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+const (
+	DiskIOName     = "diskio"
+	FilesystemName = "filesystem"
+)
+
+// diskIO reads block device IO counters
+type diskIO struct {
+	interval   time.Duration
+	ioCounters func(ctx context.Context, names ...string) (map[string]disk.IOCountersStat, error)
+	counters   counters
+}
+
+func NewDiskIO(interval time.Duration) *diskIO {
+	return &diskIO{
+		interval:   interval,
+		ioCounters: disk.IOCountersWithContext,
+		counters:   make(counters),
+	}
+}
+
+func (d *diskIO) Name() string {
+	return DiskIOName
+}
+
+func (d *diskIO) Interval() time.Duration {
+	return d.interval
+}
+
+// Collect returns IO counters increments since the previous collect labeled by device,
+// times are in milliseconds
+func (d *diskIO) Collect(ctx context.Context) ([]models.Metric, error) {
+	stats, err := d.ioCounters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]string, 0, len(stats))
+	for name := range stats {
+		devices = append(devices, name)
+	}
+	sort.Strings(devices)
+
+	var metrics []models.Metric
+	for _, name := range devices {
+		s := stats[name]
+		labels := models.Labels{"device": name}
+
+		metrics = d.counters.add(metrics, "DiskReadCount", labels, s.ReadCount)
+		metrics = d.counters.add(metrics, "DiskWriteCount", labels, s.WriteCount)
+		metrics = d.counters.add(metrics, "DiskReadBytes", labels, s.ReadBytes)
+		metrics = d.counters.add(metrics, "DiskWriteBytes", labels, s.WriteBytes)
+		metrics = d.counters.add(metrics, "DiskReadTime", labels, s.ReadTime)
+		metrics = d.counters.add(metrics, "DiskWriteTime", labels, s.WriteTime)
+		metrics = d.counters.add(metrics, "DiskIOTime", labels, s.IoTime)
+		metrics = append(metrics, labeledGauge("DiskIOInProgress", float64(s.IopsInProgress), labels))
+	}
+
+	return metrics, nil
+}
+
+// filesystem reads usage of mounted filesystems
+type filesystem struct {
+	interval   time.Duration
+	partitions func(ctx context.Context, all bool) ([]disk.PartitionStat, error)
+	usage      func(ctx context.Context, path string) (*disk.UsageStat, error)
+}
+
+func NewFilesystem(interval time.Duration) *filesystem {
+	return &filesystem{
+		interval:   interval,
+		partitions: disk.PartitionsWithContext,
+		usage:      disk.UsageWithContext,
+	}
+}
+
+func (f *filesystem) Name() string {
+	return FilesystemName
+}
+
+func (f *filesystem) Interval() time.Duration {
+	return f.interval
+}
+
+// Collect returns usage gauges of physical filesystems labeled by mount point, device
+// and filesystem type. Mount points failed to read are reported in the error.
+func (f *filesystem) Collect(ctx context.Context) ([]models.Metric, error) {
+	parts, err := f.partitions(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		metrics []models.Metric
+		errs    []error
+	)
+
+	for _, p := range parts {
+		u, err := f.usage(ctx, p.Mountpoint)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("mount %s: %w", p.Mountpoint, err))
+			continue
+		}
+
+		labels := models.Labels{"mount": p.Mountpoint, "device": p.Device, "fstype": p.Fstype}
+
+		metrics = append(metrics,
+			labeledGauge("FilesystemTotal", float64(u.Total), labels),
+			labeledGauge("FilesystemFree", float64(u.Free), labels),
+			labeledGauge("FilesystemUsed", float64(u.Used), labels),
+			labeledGauge("FilesystemUsedPercent", u.UsedPercent, labels),
+			labeledGauge("FilesystemInodesTotal", float64(u.InodesTotal), labels),
+			labeledGauge("FilesystemInodesFree", float64(u.InodesFree), labels),
+		)
+	}
+
+	return metrics, errors.Join(errs...)
+}
@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_diskIO_Collect(t *testing.T) {
+	samples := []map[string]disk.IOCountersStat{
+		{"sda": {Name: "sda", ReadBytes: 100, WriteBytes: 10}},
+		{"sda": {Name: "sda", ReadBytes: 150, WriteBytes: 10, IopsInProgress: 2}},
+	}
+
+	c := NewDiskIO(time.Second)
+	assert.Equal(t, DiskIOName, c.Name())
+
+	var calls int
+	c.ioCounters = func(context.Context, ...string) (map[string]disk.IOCountersStat, error) {
+		calls++
+		return samples[calls-1], nil
+	}
+
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"DiskIOInProgress"}, metricIDs(metrics))
+
+	metrics, err = c.Collect(context.Background())
+	require.NoError(t, err)
+
+	byID := make(map[string]models.Metric)
+	for _, m := range metrics {
+		byID[m.ID] = m
+		assert.Equal(t, models.Labels{"device": "sda"}, m.Labels)
+	}
+	assert.Equal(t, int64(50), *byID["DiskReadBytes"].Delta)
+	assert.Equal(t, int64(0), *byID["DiskWriteBytes"].Delta)
+	assert.Equal(t, 2.0, *byID["DiskIOInProgress"].Value)
+}
+
+func Test_filesystem_Collect(t *testing.T) {
+	c := NewFilesystem(time.Second)
+	assert.Equal(t, FilesystemName, c.Name())
+
+	c.partitions = func(context.Context, bool) ([]disk.PartitionStat, error) {
+		return []disk.PartitionStat{
+			{Device: "/dev/sda1", Mountpoint: "/", Fstype: "ext4"},
+			{Device: "/dev/sdb1", Mountpoint: "/data", Fstype: "xfs"},
+		}, nil
+	}
+	c.usage = func(_ context.Context, path string) (*disk.UsageStat, error) {
+		if path == "/data" {
+			return nil, errors.New("permission denied")
+		}
+		return &disk.UsageStat{Total: 100, Free: 40, Used: 60, UsedPercent: 60}, nil
+	}
+
+	metrics, err := c.Collect(context.Background())
+
+	assert.ErrorContains(t, err, "mount /data")
+	require.Len(t, metrics, 6)
+	assert.Equal(t, "FilesystemTotal", metrics[0].ID)
+	assert.Equal(t, 100.0, *metrics[0].Value)
+	assert.Equal(t, models.Labels{"mount": "/", "device": "/dev/sda1", "fstype": "ext4"}, metrics[0].Labels)
+}
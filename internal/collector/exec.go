@@ -0,0 +1,273 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/config"
+	"github.com/Chystik/runtime-metrics/internal/models"
+)
+
+const (
+	ExecName = "exec"
+
+	// execWaitDelay limits waiting for the output after the command is killed,
+	// children of the shell may keep stdout open
+	execWaitDelay = time.Second
+)
+
+var ErrInvalidExecOutput = errors.New("invalid command output")
+
+// execCommands runs external commands and parses their output into metrics
+type execCommands struct {
+	interval time.Duration
+	commands []config.ExecCommand
+}
+
+func NewExec(interval time.Duration, commands []config.ExecCommand) (*execCommands, error) {
+	names := make(map[string]struct{}, len(commands))
+	for _, c := range commands {
+		if c.Name == "" || c.Command == "" {
+			return nil, fmt.Errorf("exec command must have a name and a command")
+		}
+		if _, ok := names[c.Name]; ok {
+			return nil, fmt.Errorf("duplicate exec command %s", c.Name)
+		}
+		names[c.Name] = struct{}{}
+
+		switch c.Format {
+		case config.ExecFormatAuto, config.ExecFormatJSON, config.ExecFormatLines:
+		default:
+			return nil, fmt.Errorf("exec command %s: unknown format %q", c.Name, c.Format)
+		}
+
+		// the collect context expires after the interval, so it caps the timeout
+		if c.Timeout.Duration > interval {
+			return nil, fmt.Errorf("exec command %s: timeout %s exceeds collector interval %s", c.Name, c.Timeout, interval)
+		}
+	}
+
+	return &execCommands{
+		interval: interval,
+		commands: commands,
+	}, nil
+}
+
+func (e *execCommands) Name() string {
+	return ExecName
+}
+
+func (e *execCommands) Interval() time.Duration {
+	return e.interval
+}
+
+// Collect runs commands concurrently and returns their metrics with the exit code and
+// the duration of each command. Failed runs are counted by reason: timeout, error when
+// the command could not run and parse when its output is invalid. A non-zero exit code
+// is not a failure, its output is parsed as well like Nagios plugins do.
+func (e *execCommands) Collect(ctx context.Context) ([]models.Metric, error) {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		metrics []models.Metric
+		errs    []error
+	)
+
+	for _, c := range e.commands {
+		wg.Add(1)
+		go func(c config.ExecCommand) {
+			defer wg.Done()
+
+			m, err := e.run(ctx, c)
+
+			mu.Lock()
+			metrics = append(metrics, m...)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("command %s: %w", c.Name, err))
+			}
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+
+	return metrics, errors.Join(errs...)
+}
+
+func (e *execCommands) run(ctx context.Context, c config.ExecCommand) ([]models.Metric, error) {
+	timeout := e.interval
+	if c.Timeout.Duration > 0 {
+		timeout = c.Timeout.Duration
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	labels := models.Labels{"command": c.Name}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", c.Command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = execWaitDelay
+
+	start := time.Now()
+	err := cmd.Run()
+	duration := time.Since(start)
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		return []models.Metric{execFailure(c.Name, "timeout")}, ctx.Err()
+	case errors.As(err, &exitErr):
+		// the output of the failed check is still meaningful
+	case err != nil:
+		return []models.Metric{execFailure(c.Name, "error")}, err
+	}
+
+	metrics := []models.Metric{
+		labeledGauge("ExecExitCode", float64(cmd.ProcessState.ExitCode()), labels),
+		labeledGauge("ExecDuration", duration.Seconds(), labels),
+	}
+
+	parsed, err := parseExecOutput(stdout.Bytes(), c.Format)
+	if err != nil {
+		if s := strings.TrimSpace(stderr.String()); s != "" {
+			err = fmt.Errorf("%w, stderr: %s", err, s)
+		}
+		return append(metrics, execFailure(c.Name, "parse")), err
+	}
+
+	return append(metrics, parsed...), nil
+}
+
+func execFailure(command, reason string) models.Metric {
+	delta := int64(1)
+	return models.Metric{
+		ID:     "ExecFailures",
+		MType:  "counter",
+		Delta:  &delta,
+		Labels: models.Labels{"command": command, "reason": reason},
+	}
+}
+
+// parseExecOutput parses a JSON array of metrics or name value lines,
+// the format is detected by the first character when it is not set
+func parseExecOutput(out []byte, format string) ([]models.Metric, error) {
+	out = bytes.TrimSpace(out)
+
+	if format == config.ExecFormatJSON || (format == config.ExecFormatAuto && bytes.HasPrefix(out, []byte("["))) {
+		return parseExecJSON(out)
+	}
+
+	return parseExecLines(string(out))
+}
+
+func parseExecJSON(out []byte) ([]models.Metric, error) {
+	var metrics []models.Metric
+	if err := json.Unmarshal(out, &metrics); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExecOutput, err)
+	}
+
+	for _, m := range metrics {
+		if m.ID == "" {
+			return nil, fmt.Errorf("%w: metric without id", ErrInvalidExecOutput)
+		}
+
+		var ok bool
+		switch m.MType {
+		case "gauge":
+			ok = m.Value != nil
+		case "counter":
+			ok = m.Delta != nil
+		case "histogram":
+			ok = m.Histogram.Validate() == nil
+		case "summary":
+			ok = m.Summary.Validate() == nil
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: bad %s metric %s", ErrInvalidExecOutput, m.MType, m.ID)
+		}
+	}
+
+	return metrics, nil
+}
+
+// parseExecLines parses name value lines as gauges, empty lines and comments are skipped.
+// The first line may be a Nagios plugin status with performance data after a pipe,
+// e.g. DISK OK - free space: 20% | 'used space'=80%;90;95;0;100
+func parseExecLines(out string) ([]models.Metric, error) {
+	var metrics []models.Metric
+
+	for i, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 2 {
+			if v, err := strconv.ParseFloat(fields[1], 64); err == nil {
+				metrics = append(metrics, gauge(fields[0], v))
+				continue
+			}
+		}
+
+		if i > 0 {
+			return nil, fmt.Errorf("%w: line %d, expect name value", ErrInvalidExecOutput, i+1)
+		}
+
+		if _, perfdata, ok := strings.Cut(line, "|"); ok {
+			m, err := parsePerfdata(perfdata)
+			if err != nil {
+				return nil, err
+			}
+			metrics = append(metrics, m...)
+		}
+	}
+
+	return metrics, nil
+}
+
+// parsePerfdata parses Nagios performance data 'label'=value[UOM];warn;crit;min;max as gauges,
+// only the value is kept and the unit of measurement is dropped
+func parsePerfdata(s string) ([]models.Metric, error) {
+	var metrics []models.Metric
+
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		var label string
+		if strings.HasPrefix(s, "'") {
+			end := strings.Index(s[1:], "'=")
+			if end < 0 {
+				return nil, fmt.Errorf("%w: bad perfdata label in %q", ErrInvalidExecOutput, s)
+			}
+			label, s = s[1:end+1], s[end+3:]
+		} else {
+			var ok bool
+			label, s, ok = strings.Cut(s, "=")
+			if !ok || label == "" {
+				return nil, fmt.Errorf("%w: bad perfdata %q", ErrInvalidExecOutput, s)
+			}
+		}
+
+		item, rest, _ := strings.Cut(s, " ")
+		s = rest
+
+		value, _, _ := strings.Cut(item, ";")
+		value = strings.TrimRight(value, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ%")
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad perfdata value of %s", ErrInvalidExecOutput, label)
+		}
+
+		metrics = append(metrics, gauge(label, v))
+	}
+
+	return metrics, nil
+}
@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/config"
+	"github.com/Chystik/runtime-metrics/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_execCommands_Collect(t *testing.T) {
+	c, err := NewExec(time.Second, []config.ExecCommand{
+		{Name: "json", Command: `echo '[{"id":"Queue","type":"gauge","value":3,"labels":{"q":"a"}},{"id":"Jobs","type":"counter","delta":2}]'`},
+		{Name: "lines", Command: "printf 'temp 21.5\\n# comment\\nfans 2\\n'", Format: config.ExecFormatLines},
+		{Name: "nagios", Command: "echo \"DISK WARNING - free space: 15% | 'used space'=85%;80;90;0;100 inodes=10\"; exit 1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ExecName, c.Name())
+
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+
+	byKey := make(map[string]models.Metric)
+	for _, m := range metrics {
+		byKey[m.Key()] = m
+	}
+
+	assert.Equal(t, 3.0, *byKey[`Queue{q="a"}`].Value)
+	assert.Equal(t, int64(2), *byKey["Jobs"].Delta)
+	assert.Equal(t, 21.5, *byKey["temp"].Value)
+	assert.Equal(t, 2.0, *byKey["fans"].Value)
+	assert.Equal(t, 85.0, *byKey["used space"].Value)
+	assert.Equal(t, 10.0, *byKey["inodes"].Value)
+	assert.Equal(t, 1.0, *byKey[`ExecExitCode{command="nagios"}`].Value)
+	assert.Equal(t, 0.0, *byKey[`ExecExitCode{command="json"}`].Value)
+	assert.Contains(t, byKey, `ExecDuration{command="lines"}`)
+}
+
+func Test_execCommands_Collect_Failures(t *testing.T) {
+	c, err := NewExec(time.Second, []config.ExecCommand{
+		{Name: "slow", Command: "sleep 5", Timeout: config.Duration{Duration: 50 * time.Millisecond}},
+		{Name: "garbage", Command: "echo 'not a metric line'; echo 'x y z'", Format: config.ExecFormatLines},
+		{Name: "badjson", Command: `echo '[{"id":"a","type":"gauge"}]'`},
+	})
+	require.NoError(t, err)
+
+	metrics, err := c.Collect(context.Background())
+
+	assert.ErrorContains(t, err, "command slow")
+	assert.ErrorIs(t, err, ErrInvalidExecOutput)
+
+	failures := make(map[string]string)
+	for _, m := range metrics {
+		if m.ID == "ExecFailures" {
+			assert.Equal(t, int64(1), *m.Delta)
+			failures[m.Labels["command"]] = m.Labels["reason"]
+		}
+	}
+	assert.Equal(t, map[string]string{"slow": "timeout", "garbage": "parse", "badjson": "parse"}, failures)
+}
+
+func TestNewExec_WhenConfigIsInvalid(t *testing.T) {
+	for _, commands := range [][]config.ExecCommand{
+		{{Name: "a"}},
+		{{Name: "a", Command: "true"}, {Name: "a", Command: "false"}},
+		{{Name: "a", Command: "true", Format: "xml"}},
+		{{Name: "a", Command: "true", Timeout: config.Duration{Duration: 2 * time.Second}}},
+	} {
+		_, err := NewExec(time.Second, commands)
+		assert.Error(t, err)
+	}
+}
+
+func Test_parsePerfdata(t *testing.T) {
+	metrics, err := parsePerfdata(" time=0.05s;1;2 'free mem'=512MB size=1e3B")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"time", "free mem", "size"}, metricIDs(metrics))
+	assert.Equal(t, 0.05, *metrics[0].Value)
+	assert.Equal(t, 512.0, *metrics[1].Value)
+	assert.Equal(t, 1000.0, *metrics[2].Value)
+
+	_, err = parsePerfdata("'broken=1")
+	assert.ErrorIs(t, err, ErrInvalidExecOutput)
+}
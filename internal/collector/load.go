@@ -0,0 +1,46 @@
+package collector
+
+import (
+	"context"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+
+	"github.com/shirou/gopsutil/v3/load"
+)
+
+const LoadName = "load"
+
+// loadAvg reads system load averages
+type loadAvg struct {
+	interval time.Duration
+	avg      func(ctx context.Context) (*load.AvgStat, error)
+}
+
+func NewLoad(interval time.Duration) *loadAvg {
+	return &loadAvg{
+		interval: interval,
+		avg:      load.AvgWithContext,
+	}
+}
+
+func (l *loadAvg) Name() string {
+	return LoadName
+}
+
+func (l *loadAvg) Interval() time.Duration {
+	return l.interval
+}
+
+func (l *loadAvg) Collect(ctx context.Context) ([]models.Metric, error) {
+	avg, err := l.avg(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return []models.Metric{
+		gauge("Load1", avg.Load1),
+		gauge("Load5", avg.Load5),
+		gauge("Load15", avg.Load15),
+	}, nil
+}
@@ -0,0 +1,26 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_loadAvg_Collect(t *testing.T) {
+	c := NewLoad(time.Second)
+	assert.Equal(t, LoadName, c.Name())
+
+	c.avg = func(context.Context) (*load.AvgStat, error) {
+		return &load.AvgStat{Load1: 1, Load5: 0.5, Load15: 0.25}, nil
+	}
+
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Load1", "Load5", "Load15"}, metricIDs(metrics))
+	assert.Equal(t, 0.25, *metrics[2].Value)
+}
@@ -0,0 +1,278 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/config"
+	"github.com/Chystik/runtime-metrics/internal/models"
+)
+
+const (
+	LogTailName = "logtail"
+
+	// maxLogLine limits a line without a newline kept between collects
+	maxLogLine = 1 << 20
+)
+
+// logTail reads lines appended to log files and matches them against rules
+type logTail struct {
+	interval time.Duration
+	files    []*tailedFile
+}
+
+type tailedFile struct {
+	path  string
+	rules []logRule
+	f     *os.File
+	info  os.FileInfo
+	// partial is the last line read without a newline
+	partial []byte
+	started bool
+}
+
+type logRule struct {
+	metric string
+	re     *regexp.Regexp
+	gauge  bool
+	value  int
+}
+
+func NewLogTail(interval time.Duration, files []config.LogFile) (*logTail, error) {
+	lt := &logTail{interval: interval}
+
+	for _, lf := range files {
+		if lf.Path == "" {
+			return nil, errors.New("log file must have a path")
+		}
+
+		tf := &tailedFile{path: lf.Path}
+		for _, r := range lf.Rules {
+			rule, err := newLogRule(r)
+			if err != nil {
+				return nil, fmt.Errorf("log file %s: %w", lf.Path, err)
+			}
+			tf.rules = append(tf.rules, rule)
+		}
+		lt.files = append(lt.files, tf)
+	}
+
+	return lt, nil
+}
+
+func newLogRule(r config.LogRule) (logRule, error) {
+	if r.Metric == "" {
+		return logRule{}, errors.New("log rule must have a metric")
+	}
+
+	re, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return logRule{}, fmt.Errorf("rule %s: %w", r.Metric, err)
+	}
+	rule := logRule{metric: r.Metric, re: re, value: -1}
+
+	switch r.Type {
+	case "", config.LogRuleCounter:
+	case config.LogRuleGauge:
+		rule.gauge = true
+		rule.value = 1
+		if r.Value != "" {
+			rule.value = re.SubexpIndex(r.Value)
+		}
+		if rule.value < 1 || rule.value > re.NumSubexp() {
+			return logRule{}, fmt.Errorf("rule %s: gauge pattern has no value group", r.Metric)
+		}
+	default:
+		return logRule{}, fmt.Errorf("rule %s: unknown type %q", r.Metric, r.Type)
+	}
+
+	return rule, nil
+}
+
+func (lt *logTail) Name() string {
+	return LogTailName
+}
+
+func (lt *logTail) Interval() time.Duration {
+	return lt.interval
+}
+
+// Collect returns counters of matched lines and gauges of the last captured values since
+// the previous collect labeled by the file path. Lines written before the first collect
+// are skipped unless the file did not exist then. A rotated file is read to the end before the new one is read from the start,
+// a truncated file is read from the start.
+func (lt *logTail) Collect(ctx context.Context) ([]models.Metric, error) {
+	var (
+		metrics []models.Metric
+		errs    []error
+	)
+
+	for _, tf := range lt.files {
+		lines, err := tf.readLines()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("log file %s: %w", tf.path, err))
+		}
+		metrics = append(metrics, tf.match(lines)...)
+	}
+
+	return metrics, errors.Join(errs...)
+}
+
+// readLines returns complete lines appended since the previous call
+func (tf *tailedFile) readLines() ([][]byte, error) {
+	started := tf.started
+	tf.started = true
+
+	info, err := os.Stat(tf.path)
+	if err != nil {
+		if tf.f != nil && errors.Is(err, os.ErrNotExist) {
+			// the file is rotated and the new one is not created yet
+			return tf.drain()
+		}
+		return nil, err
+	}
+
+	if tf.f == nil {
+		// start at the end, the history is not counted unless the file is created after the start
+		if !started {
+			return nil, tf.open(info, io.SeekEnd)
+		}
+		if err := tf.open(info, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return tf.drain()
+	}
+
+	if !os.SameFile(tf.info, info) {
+		lines, err := tf.drain()
+		tf.f.Close()
+		tf.f, tf.partial = nil, nil
+		if err != nil {
+			return lines, err
+		}
+		if err := tf.open(info, io.SeekStart); err != nil {
+			return lines, err
+		}
+		more, err := tf.drain()
+		return append(lines, more...), err
+	}
+
+	offset, err := tf.f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < offset {
+		// truncated in place
+		if _, err := tf.f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		tf.partial = nil
+	}
+	tf.info = info
+
+	return tf.drain()
+}
+
+func (tf *tailedFile) open(info os.FileInfo, whence int) error {
+	f, err := os.Open(tf.path)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, whence); err != nil {
+		f.Close()
+		return err
+	}
+	tf.f, tf.info = f, info
+	return nil
+}
+
+// drain reads the open file to the end
+func (tf *tailedFile) drain() ([][]byte, error) {
+	data, err := io.ReadAll(tf.f)
+	if len(data) == 0 {
+		return nil, err
+	}
+
+	data = append(tf.partial, data...)
+	i := bytes.LastIndexByte(data, '\n')
+	if i < 0 {
+		tf.partial = data
+		if len(tf.partial) > maxLogLine {
+			tf.partial = nil
+		}
+		return nil, err
+	}
+
+	tf.partial = append([]byte(nil), data[i+1:]...)
+	return bytes.Split(data[:i], []byte("\n")), err
+}
+
+func (tf *tailedFile) match(lines [][]byte) []models.Metric {
+	type series struct {
+		metric models.Metric
+		count  int64
+		value  float64
+	}
+	var order []string
+	found := make(map[string]*series)
+
+	for _, line := range lines {
+		for _, r := range tf.rules {
+			sub := r.re.FindSubmatch(line)
+			if sub == nil {
+				continue
+			}
+
+			var value float64
+			if r.gauge {
+				v, err := strconv.ParseFloat(string(sub[r.value]), 64)
+				if err != nil {
+					continue
+				}
+				value = v
+			}
+
+			labels := models.Labels{"file": tf.path}
+			for i, name := range r.re.SubexpNames() {
+				if name != "" && i != r.value && sub[i] != nil {
+					labels[name] = string(sub[i])
+				}
+			}
+
+			m := models.Metric{ID: r.metric, MType: "counter", Labels: labels}
+			if r.gauge {
+				m.MType = "gauge"
+			}
+
+			s, ok := found[m.Key()]
+			if !ok {
+				s = &series{metric: m}
+				found[m.Key()] = s
+				order = append(order, m.Key())
+			}
+			s.count++
+			s.value = value
+		}
+	}
+
+	metrics := make([]models.Metric, 0, len(order))
+	for _, k := range order {
+		s := found[k]
+		m := s.metric
+		if m.MType == "gauge" {
+			m.Value = &s.value
+		} else {
+			m.Delta = &s.count
+		}
+		metrics = append(metrics, m)
+	}
+
+	return metrics
+}
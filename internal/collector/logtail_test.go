@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/config"
+	"github.com/Chystik/runtime-metrics/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func appendLog(t *testing.T, path, s string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString(s)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+func collectLog(t *testing.T, c *logTail) map[string]models.Metric {
+	t.Helper()
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+
+	byKey := make(map[string]models.Metric)
+	for _, m := range metrics {
+		byKey[m.Key()] = m
+	}
+	return byKey
+}
+
+func Test_logTail_Collect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendLog(t, path, "ERROR old line\n")
+
+	c, err := NewLogTail(time.Second, []config.LogFile{{
+		Path: path,
+		Rules: []config.LogRule{
+			{Metric: "LogErrors", Pattern: `ERROR`},
+			{Metric: "RequestDuration", Pattern: `status=(?P<status>\d+) took=(?P<ms>[\d.]+)ms`, Type: config.LogRuleGauge, Value: "ms"},
+		},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, LogTailName, c.Name())
+
+	// the history is skipped
+	assert.Empty(t, collectLog(t, c))
+
+	appendLog(t, path, "ERROR one\nINFO status=200 took=12.5ms\nERROR two\nINFO status=200 took=7ms\nERROR par")
+	got := collectLog(t, c)
+
+	file := `file="` + path + `"`
+	assert.Equal(t, int64(2), *got["LogErrors{"+file+"}"].Delta)
+	assert.Equal(t, 7.0, *got["RequestDuration{"+file+`,status="200"}`].Value)
+
+	// the partial line is completed
+	appendLog(t, path, "tial\n")
+	got = collectLog(t, c)
+	assert.Equal(t, int64(1), *got["LogErrors{"+file+"}"].Delta)
+}
+
+func Test_logTail_Collect_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendLog(t, path, "")
+
+	c, err := NewLogTail(time.Second, []config.LogFile{{
+		Path:  path,
+		Rules: []config.LogRule{{Metric: "LogErrors", Pattern: `ERROR`}},
+	}})
+	require.NoError(t, err)
+	key := `LogErrors{file="` + path + `"}`
+
+	assert.Empty(t, collectLog(t, c))
+
+	// lines written before the rotation are read from the old file
+	appendLog(t, path, "ERROR 1\n")
+	require.NoError(t, os.Rename(path, path+".1"))
+	appendLog(t, path+".1", "ERROR 2\n")
+	appendLog(t, path, "ERROR 3\n")
+	assert.Equal(t, int64(3), *collectLog(t, c)[key].Delta)
+
+	// truncated in place, the file is shorter than the read offset
+	require.NoError(t, os.Truncate(path, 0))
+	appendLog(t, path, "ERROR\n")
+	assert.Equal(t, int64(1), *collectLog(t, c)[key].Delta)
+}
+
+func Test_logTail_Collect_WhenFileIsCreatedLater(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	c, err := NewLogTail(time.Second, []config.LogFile{{
+		Path:  path,
+		Rules: []config.LogRule{{Metric: "LogErrors", Pattern: `ERROR`}},
+	}})
+	require.NoError(t, err)
+
+	_, err = c.Collect(context.Background())
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	appendLog(t, path, "ERROR 1\n")
+	assert.Equal(t, int64(1), *collectLog(t, c)[`LogErrors{file="`+path+`"}`].Delta)
+}
+
+func TestNewLogTail_WhenRuleIsInvalid(t *testing.T) {
+	for _, r := range []config.LogRule{
+		{Pattern: "x"},
+		{Metric: "m", Pattern: "("},
+		{Metric: "m", Pattern: "x", Type: "gauge"},
+		{Metric: "m", Pattern: "(?P<v>x)", Type: "gauge", Value: "w"},
+		{Metric: "m", Pattern: "x", Type: "histogram"},
+	} {
+		_, err := NewLogTail(time.Second, []config.LogFile{{Path: "a.log", Rules: []config.LogRule{r}}})
+		assert.Error(t, err, r)
+	}
+}
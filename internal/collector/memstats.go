@@ -0,0 +1,96 @@
+package collector
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/config"
+	"github.com/Chystik/runtime-metrics/internal/models"
+)
+
+const MemStatsName = "memstats"
+
+// memStats reads runtime.MemStats fields listed in collectable metrics,
+// GC pauses histogram, poll count and a random value
+type memStats struct {
+	interval     time.Duration
+	fields       config.CollectableMetrics
+	pauseBuckets []float64
+	stats        runtime.MemStats
+	lastNumGC    uint32
+}
+
+func NewMemStats(interval time.Duration, fields config.CollectableMetrics, pauseBuckets []float64) *memStats {
+	return &memStats{
+		interval:     interval,
+		fields:       fields,
+		pauseBuckets: pauseBuckets,
+	}
+}
+
+func (ms *memStats) Name() string {
+	return MemStatsName
+}
+
+func (ms *memStats) Interval() time.Duration {
+	return ms.interval
+}
+
+func (ms *memStats) Collect(ctx context.Context) ([]models.Metric, error) {
+	runtime.ReadMemStats(&ms.stats)
+
+	metrics := make([]models.Metric, 0, len(ms.fields)+3)
+
+	r := reflect.ValueOf(ms.stats)
+	for _, name := range ms.fields {
+		f := r.FieldByName(name)
+		if !f.IsValid() {
+			continue
+		}
+
+		var v float64
+		switch val := f.Interface().(type) {
+		case float64:
+			v = val
+		case uint64:
+			v = float64(val)
+		case uint32:
+			v = float64(val)
+		default:
+			continue
+		}
+		metrics = append(metrics, models.Metric{ID: name, MType: "gauge", Value: &v})
+	}
+
+	pauses := models.NewHistogram(ms.pauseBuckets)
+	ms.observePauses(pauses)
+
+	pollCount := int64(1)
+	randomValue := float64(rand.Intn(1000))
+
+	metrics = append(metrics,
+		models.Metric{ID: "PauseNs", MType: "histogram", Histogram: pauses},
+		models.Metric{ID: "PollCount", MType: "counter", Delta: &pollCount},
+		models.Metric{ID: "RandomValue", MType: "gauge", Value: &randomValue},
+	)
+
+	return metrics, nil
+}
+
+// observePauses adds GC pauses happened since the previous collect to the histogram
+func (ms *memStats) observePauses(h *models.Histogram) {
+	numGC := ms.stats.NumGC
+	n := numGC - ms.lastNumGC
+	// PauseNs is a circular buffer of recent pauses, older ones are lost
+	if n > uint32(len(ms.stats.PauseNs)) {
+		n = uint32(len(ms.stats.PauseNs))
+	}
+
+	for i := uint32(0); i < n; i++ {
+		h.Observe(float64(ms.stats.PauseNs[(numGC-i+255)%256]))
+	}
+	ms.lastNumGC = numGC
+}
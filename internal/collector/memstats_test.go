@@ -0,0 +1,55 @@
+package collector
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_memStats_Collect(t *testing.T) {
+	c := NewMemStats(time.Second, []string{"Alloc", "GCCPUFraction", "NumGC", "Unknown", "PauseNs"}, []float64{1e6, 1e9})
+	assert.Equal(t, MemStatsName, c.Name())
+	assert.Equal(t, time.Second, c.Interval())
+
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+
+	byID := make(map[string]models.Metric)
+	for _, m := range metrics {
+		byID[m.ID] = m
+	}
+
+	for _, id := range []string{"Alloc", "GCCPUFraction", "NumGC", "RandomValue"} {
+		assert.Equal(t, "gauge", byID[id].MType, id)
+	}
+	assert.NotContains(t, byID, "Unknown")
+	assert.Equal(t, int64(1), *byID["PollCount"].Delta)
+	assert.Equal(t, "histogram", byID["PauseNs"].MType)
+	assert.Len(t, byID["PauseNs"].Histogram.Buckets, 2)
+}
+
+func Test_memStats_Collect_ObservesNewGCPauses(t *testing.T) {
+	c := NewMemStats(time.Second, nil, []float64{1e6, 1e9})
+
+	_, err := c.Collect(context.Background())
+	require.NoError(t, err)
+
+	runtime.GC()
+	runtime.GC()
+
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+
+	for _, m := range metrics {
+		if m.ID == "PauseNs" {
+			assert.GreaterOrEqual(t, m.Histogram.Count, uint64(2))
+		}
+	}
+	assert.Equal(t, c.stats.NumGC, c.lastNumGC)
+}
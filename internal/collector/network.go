@@ -0,0 +1,59 @@
+package collector
+
+import (
+	"context"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+const NetworkName = "network"
+
+// network reads per-interface network counters
+type network struct {
+	interval   time.Duration
+	ioCounters func(ctx context.Context, pernic bool) ([]net.IOCountersStat, error)
+	counters   counters
+}
+
+func NewNetwork(interval time.Duration) *network {
+	return &network{
+		interval:   interval,
+		ioCounters: net.IOCountersWithContext,
+		counters:   make(counters),
+	}
+}
+
+func (n *network) Name() string {
+	return NetworkName
+}
+
+func (n *network) Interval() time.Duration {
+	return n.interval
+}
+
+// Collect returns counters increments since the previous collect labeled by interface
+func (n *network) Collect(ctx context.Context) ([]models.Metric, error) {
+	stats, err := n.ioCounters(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var metrics []models.Metric
+	for _, s := range stats {
+		labels := models.Labels{"interface": s.Name}
+
+		metrics = n.counters.add(metrics, "NetBytesSent", labels, s.BytesSent)
+		metrics = n.counters.add(metrics, "NetBytesRecv", labels, s.BytesRecv)
+		metrics = n.counters.add(metrics, "NetPacketsSent", labels, s.PacketsSent)
+		metrics = n.counters.add(metrics, "NetPacketsRecv", labels, s.PacketsRecv)
+		metrics = n.counters.add(metrics, "NetErrIn", labels, s.Errin)
+		metrics = n.counters.add(metrics, "NetErrOut", labels, s.Errout)
+		metrics = n.counters.add(metrics, "NetDropIn", labels, s.Dropin)
+		metrics = n.counters.add(metrics, "NetDropOut", labels, s.Dropout)
+	}
+
+	return metrics, nil
+}
@@ -0,0 +1,46 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_network_Collect(t *testing.T) {
+	samples := [][]net.IOCountersStat{
+		{{Name: "eth0", BytesSent: 10, BytesRecv: 20}},
+		{{Name: "eth0", BytesSent: 15, BytesRecv: 5}},
+	}
+
+	c := NewNetwork(time.Second)
+	assert.Equal(t, NetworkName, c.Name())
+
+	var calls int
+	c.ioCounters = func(context.Context, bool) ([]net.IOCountersStat, error) {
+		calls++
+		return samples[calls-1], nil
+	}
+
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, metrics)
+
+	metrics, err = c.Collect(context.Background())
+	require.NoError(t, err)
+	require.Len(t, metrics, 8)
+
+	byID := make(map[string]models.Metric)
+	for _, m := range metrics {
+		byID[m.ID] = m
+	}
+	assert.Equal(t, int64(5), *byID["NetBytesSent"].Delta)
+	// the counter was reset
+	assert.Equal(t, int64(5), *byID["NetBytesRecv"].Delta)
+	assert.Equal(t, models.Labels{"interface": "eth0"}, byID["NetBytesSent"].Labels)
+}
@@ -0,0 +1,170 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+)
+
+const ProbeName = "probe"
+
+// probe checks HTTP URLs and TCP addresses
+type probe struct {
+	interval time.Duration
+	targets  []*url.URL
+	client   *http.Client
+	dialer   *net.Dialer
+}
+
+// NewProbe returns a collector of targets in a form http://host/path, https://host/path or tcp://host:port
+func NewProbe(interval time.Duration, targets []string) (*probe, error) {
+	p := &probe{
+		interval: interval,
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:             http.ProxyFromEnvironment,
+				DisableKeepAlives: true,
+			},
+		},
+		dialer: &net.Dialer{},
+	}
+
+	for _, t := range targets {
+		u, err := url.Parse(t)
+		if err != nil {
+			return nil, fmt.Errorf("bad probe target %q: %w", t, err)
+		}
+
+		switch u.Scheme {
+		case "http", "https":
+		case "tcp":
+			if _, _, err := net.SplitHostPort(u.Host); err != nil {
+				return nil, fmt.Errorf("bad probe target %q: %w", t, err)
+			}
+		default:
+			return nil, fmt.Errorf("bad probe target %q: expect http, https or tcp scheme", t)
+		}
+		if u.Host == "" {
+			return nil, fmt.Errorf("bad probe target %q: no host", t)
+		}
+
+		p.targets = append(p.targets, u)
+	}
+
+	return p, nil
+}
+
+func (p *probe) Name() string {
+	return ProbeName
+}
+
+func (p *probe) Interval() time.Duration {
+	return p.interval
+}
+
+// Collect probes targets concurrently and returns ProbeUp, a ProbeDuration histogram
+// in seconds, HTTP status codes and the earliest TLS certificate expiry as a unix
+// timestamp labeled by target. Failed probes are reported in the error as well.
+func (p *probe) Collect(ctx context.Context) ([]models.Metric, error) {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		metrics []models.Metric
+		errs    []error
+	)
+
+	for _, t := range p.targets {
+		wg.Add(1)
+		go func(t *url.URL) {
+			defer wg.Done()
+
+			labels := models.Labels{"target": t.String()}
+
+			var (
+				m   []models.Metric
+				err error
+			)
+			start := time.Now()
+			if t.Scheme == "tcp" {
+				err = p.probeTCP(ctx, t)
+			} else {
+				m, err = p.probeHTTP(ctx, t, labels)
+			}
+
+			duration := models.NewHistogram(models.DefaultBuckets)
+			duration.Observe(time.Since(start).Seconds())
+
+			var up float64
+			if err == nil {
+				up = 1
+			}
+			m = append(m,
+				labeledGauge("ProbeUp", up, labels),
+				models.Metric{ID: "ProbeDuration", MType: "histogram", Histogram: duration, Labels: labels},
+			)
+
+			mu.Lock()
+			metrics = append(metrics, m...)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("probe %s: %w", t, err))
+			}
+			mu.Unlock()
+		}(t)
+	}
+	wg.Wait()
+
+	return metrics, errors.Join(errs...)
+}
+
+func (p *probe) probeTCP(ctx context.Context, t *url.URL) error {
+	conn, err := p.dialer.DialContext(ctx, "tcp", t.Host)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// probeHTTP returns the status code and the certificate expiry, status codes from 400 are failures
+func (p *probe) probeHTTP(ctx context.Context, t *url.URL, labels models.Labels) ([]models.Metric, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := []models.Metric{labeledGauge("ProbeStatusCode", float64(resp.StatusCode), labels)}
+
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		expiry := resp.TLS.PeerCertificates[0].NotAfter
+		for _, c := range resp.TLS.PeerCertificates[1:] {
+			if c.NotAfter.Before(expiry) {
+				expiry = c.NotAfter
+			}
+		}
+		metrics = append(metrics, labeledGauge("ProbeTLSCertExpiry", float64(expiry.Unix()), labels))
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return metrics, fmt.Errorf("resp status code: %s", resp.Status)
+	}
+
+	return metrics, nil
+}
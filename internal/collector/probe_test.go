@@ -0,0 +1,78 @@
+package collector
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_probe_Collect(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tlsSrv := httptest.NewTLSServer(mux)
+	defer tlsSrv.Close()
+
+	// a closed port
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closed := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	targets := []string{
+		srv.URL + "/ok",
+		srv.URL + "/fail",
+		tlsSrv.URL + "/ok",
+		"tcp://" + strings.TrimPrefix(srv.URL, "http://"),
+		"tcp://" + closed,
+	}
+	c, err := NewProbe(time.Second, targets)
+	require.NoError(t, err)
+	c.client.Transport = tlsSrv.Client().Transport
+	assert.Equal(t, ProbeName, c.Name())
+
+	metrics, err := c.Collect(context.Background())
+	assert.ErrorContains(t, err, "503")
+	assert.ErrorContains(t, err, closed)
+
+	got := make(map[string]models.Metric)
+	for _, m := range metrics {
+		got[m.ID+" "+m.Labels["target"]] = m
+	}
+
+	assert.Equal(t, 1.0, *got["ProbeUp "+targets[0]].Value)
+	assert.Equal(t, 200.0, *got["ProbeStatusCode "+targets[0]].Value)
+	assert.Equal(t, 0.0, *got["ProbeUp "+targets[1]].Value)
+	assert.Equal(t, 503.0, *got["ProbeStatusCode "+targets[1]].Value)
+	assert.Equal(t, 1.0, *got["ProbeUp "+targets[2]].Value)
+	assert.Greater(t, *got["ProbeTLSCertExpiry "+targets[2]].Value, float64(time.Now().Unix()))
+	assert.Equal(t, 1.0, *got["ProbeUp "+targets[3]].Value)
+	assert.Equal(t, 0.0, *got["ProbeUp "+targets[4]].Value)
+
+	for _, target := range targets {
+		h := got["ProbeDuration "+target].Histogram
+		require.NotNil(t, h, target)
+		assert.Equal(t, uint64(1), h.Count)
+	}
+}
+
+func TestNewProbe_WhenTargetIsInvalid(t *testing.T) {
+	for _, target := range []string{"ftp://host", "tcp://host", "http://", "localhost:80"} {
+		_, err := NewProbe(time.Second, []string{target})
+		assert.Error(t, err, target)
+	}
+}
@@ -0,0 +1,256 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+const ProcessName = "process"
+
+var ErrInvalidProcessTarget = errors.New("invalid process target")
+
+// ProcessTarget selects watched processes by PID, PID file or executable name
+type ProcessTarget struct {
+	PID     int32
+	PIDFile string
+	Name    string
+}
+
+// ParseProcessTarget parses a target in a form pid:123, pidfile:/run/app.pid or name:app
+func ParseProcessTarget(s string) (ProcessTarget, error) {
+	kind, v, ok := strings.Cut(s, ":")
+	if !ok || v == "" {
+		return ProcessTarget{}, fmt.Errorf("%w: %q, expect pid:123, pidfile:/path or name:app", ErrInvalidProcessTarget, s)
+	}
+
+	switch kind {
+	case "pid":
+		pid, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || pid <= 0 {
+			return ProcessTarget{}, fmt.Errorf("%w: bad pid %q", ErrInvalidProcessTarget, v)
+		}
+		return ProcessTarget{PID: int32(pid)}, nil
+	case "pidfile":
+		return ProcessTarget{PIDFile: v}, nil
+	case "name":
+		return ProcessTarget{Name: v}, nil
+	}
+
+	return ProcessTarget{}, fmt.Errorf("%w: unknown kind %q", ErrInvalidProcessTarget, kind)
+}
+
+// processes reads resource usage of processes selected by targets
+type processes struct {
+	interval time.Duration
+	targets  []ProcessTarget
+	// previous samples by pid, processes that are gone are dropped
+	prev map[int32]processSample
+}
+
+type processSample struct {
+	at         time.Time
+	cpu        float64 // user and system seconds
+	readBytes  uint64
+	writeBytes uint64
+}
+
+func NewProcess(interval time.Duration, targets []ProcessTarget) *processes {
+	return &processes{
+		interval: interval,
+		targets:  targets,
+		prev:     make(map[int32]processSample),
+	}
+}
+
+func (p *processes) Name() string {
+	return ProcessName
+}
+
+func (p *processes) Interval() time.Duration {
+	return p.interval
+}
+
+// Collect returns metrics of every process matching targets labeled by process name and pid.
+// CPU percent and IO bytes are computed since the previous collect, so they are omitted
+// for a process seen the first time.
+func (p *processes) Collect(ctx context.Context) ([]models.Metric, error) {
+	if len(p.targets) == 0 {
+		return nil, nil
+	}
+
+	var (
+		metrics []models.Metric
+		errs    []error
+	)
+
+	pids, err := p.resolve(ctx)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	next := make(map[int32]processSample, len(pids))
+	for _, pid := range pids {
+		m, s, err := p.collect(ctx, pid)
+		metrics = append(metrics, m...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("pid %d: %w", pid, err))
+		}
+		if s != nil {
+			next[pid] = *s
+		}
+	}
+	p.prev = next
+
+	return metrics, errors.Join(errs...)
+}
+
+// resolve returns distinct pids of targets, targets that match no process are reported in the error
+func (p *processes) resolve(ctx context.Context) ([]int32, error) {
+	var (
+		pids []int32
+		errs []error
+		all  []*process.Process
+	)
+	seen := make(map[int32]struct{})
+	add := func(pid int32) {
+		if _, ok := seen[pid]; !ok {
+			seen[pid] = struct{}{}
+			pids = append(pids, pid)
+		}
+	}
+
+	for _, t := range p.targets {
+		switch {
+		case t.PIDFile != "":
+			pid, err := readPIDFile(t.PIDFile)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			add(pid)
+		case t.Name != "":
+			if all == nil {
+				var err error
+				if all, err = process.ProcessesWithContext(ctx); err != nil {
+					return pids, err
+				}
+			}
+
+			var found bool
+			for _, proc := range all {
+				if processNameMatches(ctx, proc, t.Name) {
+					found = true
+					add(proc.Pid)
+				}
+			}
+			if !found {
+				errs = append(errs, fmt.Errorf("no process with name %s", t.Name))
+			}
+		default:
+			add(t.PID)
+		}
+	}
+
+	return pids, errors.Join(errs...)
+}
+
+// collect returns metrics of the process and its current sample
+func (p *processes) collect(ctx context.Context, pid int32) ([]models.Metric, *processSample, error) {
+	proc, err := process.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	name, err := proc.NameWithContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	labels := models.Labels{"process": name, "pid": strconv.Itoa(int(pid))}
+
+	var (
+		metrics []models.Metric
+		errs    []error
+	)
+
+	if mi, err := proc.MemoryInfoWithContext(ctx); err == nil {
+		metrics = append(metrics, labeledGauge("ProcessRSS", float64(mi.RSS), labels))
+	} else {
+		errs = append(errs, fmt.Errorf("memory info: %w", err))
+	}
+
+	if n, err := proc.NumFDsWithContext(ctx); err == nil {
+		metrics = append(metrics, labeledGauge("ProcessOpenFDs", float64(n), labels))
+	} else {
+		errs = append(errs, fmt.Errorf("open fds: %w", err))
+	}
+
+	if n, err := proc.NumThreadsWithContext(ctx); err == nil {
+		metrics = append(metrics, labeledGauge("ProcessThreads", float64(n), labels))
+	} else {
+		errs = append(errs, fmt.Errorf("threads: %w", err))
+	}
+
+	s := processSample{at: time.Now()}
+	prev, seen := p.prev[pid]
+
+	if times, err := proc.TimesWithContext(ctx); err == nil {
+		s.cpu = times.User + times.System
+		if elapsed := s.at.Sub(prev.at).Seconds(); seen && elapsed > 0 && s.cpu >= prev.cpu {
+			metrics = append(metrics, labeledGauge("ProcessCPUPercent", 100*(s.cpu-prev.cpu)/elapsed, labels))
+		}
+	} else {
+		errs = append(errs, fmt.Errorf("cpu times: %w", err))
+	}
+
+	if io, err := proc.IOCountersWithContext(ctx); err == nil {
+		s.readBytes, s.writeBytes = io.ReadBytes, io.WriteBytes
+		if seen && s.readBytes >= prev.readBytes && s.writeBytes >= prev.writeBytes {
+			read := int64(s.readBytes - prev.readBytes)
+			written := int64(s.writeBytes - prev.writeBytes)
+			metrics = append(metrics,
+				models.Metric{ID: "ProcessReadBytes", MType: "counter", Delta: &read, Labels: labels},
+				models.Metric{ID: "ProcessWriteBytes", MType: "counter", Delta: &written, Labels: labels},
+			)
+		}
+	} else {
+		errs = append(errs, fmt.Errorf("io counters: %w", err))
+	}
+
+	return metrics, &s, errors.Join(errs...)
+}
+
+func readPIDFile(path string) (int32, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	pid, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 32)
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("bad pid in %s", path)
+	}
+
+	return int32(pid), nil
+}
+
+// processNameMatches compares the name with the process name and its executable base name,
+// the process name may be truncated by the kernel
+func processNameMatches(ctx context.Context, proc *process.Process, name string) bool {
+	if n, err := proc.NameWithContext(ctx); err == nil && n == name {
+		return true
+	}
+	if exe, err := proc.ExeWithContext(ctx); err == nil && filepath.Base(exe) == name {
+		return true
+	}
+	return false
+}
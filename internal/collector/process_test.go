@@ -0,0 +1,86 @@
+package collector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProcessTarget(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ProcessTarget
+		wantErr bool
+	}{
+		{in: "pid:42", want: ProcessTarget{PID: 42}},
+		{in: "pidfile:/run/app.pid", want: ProcessTarget{PIDFile: "/run/app.pid"}},
+		{in: "name:app", want: ProcessTarget{Name: "app"}},
+		{in: "pid:-1", wantErr: true},
+		{in: "pid:", wantErr: true},
+		{in: "app", wantErr: true},
+		{in: "exe:app", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseProcessTarget(tt.in)
+		if tt.wantErr {
+			assert.ErrorIs(t, err, ErrInvalidProcessTarget, tt.in)
+			continue
+		}
+		assert.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+}
+
+func Test_processes_Collect(t *testing.T) {
+	pid := os.Getpid()
+	pidFile := filepath.Join(t.TempDir(), "app.pid")
+	require.NoError(t, os.WriteFile(pidFile, []byte(strconv.Itoa(pid)+"\n"), 0o600))
+
+	exe, err := os.Executable()
+	require.NoError(t, err)
+
+	c := NewProcess(time.Second, []ProcessTarget{
+		{PID: int32(pid)},
+		{PIDFile: pidFile},
+		{Name: filepath.Base(exe)},
+	})
+	assert.Equal(t, ProcessName, c.Name())
+
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ProcessRSS", "ProcessOpenFDs", "ProcessThreads"}, metricIDs(metrics))
+
+	metrics, err = c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ProcessRSS", "ProcessOpenFDs", "ProcessThreads", "ProcessCPUPercent", "ProcessReadBytes", "ProcessWriteBytes"}, metricIDs(metrics))
+
+	byID := make(map[string]models.Metric)
+	for _, m := range metrics {
+		byID[m.ID] = m
+		assert.Equal(t, strconv.Itoa(pid), m.Labels["pid"])
+	}
+	assert.Greater(t, *byID["ProcessRSS"].Value, 0.0)
+	assert.GreaterOrEqual(t, *byID["ProcessThreads"].Value, 1.0)
+}
+
+func Test_processes_Collect_WhenTargetIsMissing(t *testing.T) {
+	c := NewProcess(time.Second, []ProcessTarget{
+		{PIDFile: filepath.Join(t.TempDir(), "missing.pid")},
+		{Name: "no-such-process-name"},
+		{PID: int32(os.Getpid())},
+	})
+
+	metrics, err := c.Collect(context.Background())
+
+	assert.ErrorContains(t, err, "missing.pid")
+	assert.ErrorContains(t, err, "no process with name no-such-process-name")
+	assert.NotEmpty(t, metrics)
+}
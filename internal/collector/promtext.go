@@ -0,0 +1,144 @@
+package collector
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+)
+
+var ErrInvalidExposition = errors.New("invalid exposition format")
+
+// promSample is a sample of the Prometheus text exposition format
+type promSample struct {
+	name   string
+	labels models.Labels
+	value  float64
+}
+
+// parsePromText parses the Prometheus text exposition format 0.0.4 and returns
+// family types by name and samples in the order of appearance, timestamps are ignored
+func parsePromText(r io.Reader) (map[string]string, []promSample, error) {
+	types := make(map[string]string)
+	var samples []promSample
+
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			fields := strings.Fields(line)
+			if len(fields) >= 4 && fields[1] == "TYPE" {
+				types[fields[2]] = fields[3]
+			}
+			continue
+		}
+
+		sample, err := parsePromSample(line)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		samples = append(samples, sample)
+	}
+	if err := s.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return types, samples, nil
+}
+
+func parsePromSample(line string) (promSample, error) {
+	var sample promSample
+
+	i := strings.IndexAny(line, "{ \t")
+	if i <= 0 {
+		return sample, fmt.Errorf("%w: no value in %q", ErrInvalidExposition, line)
+	}
+	sample.name, line = line[:i], line[i:]
+
+	if line[0] == '{' {
+		labels, rest, err := parsePromLabels(line[1:])
+		if err != nil {
+			return sample, err
+		}
+		sample.labels, line = labels, rest
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 || len(fields) > 2 {
+		return sample, fmt.Errorf("%w: bad value in %q", ErrInvalidExposition, line)
+	}
+
+	v, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return sample, fmt.Errorf("%w: bad value %q", ErrInvalidExposition, fields[0])
+	}
+	sample.value = v
+
+	return sample, nil
+}
+
+// parsePromLabels parses labels after the opening brace and returns the rest after the closing one
+func parsePromLabels(s string) (models.Labels, string, error) {
+	labels := make(models.Labels)
+
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if strings.HasPrefix(s, "}") {
+			return labels, s[1:], nil
+		}
+
+		name, rest, ok := strings.Cut(s, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, "", fmt.Errorf("%w: bad labels", ErrInvalidExposition)
+		}
+
+		rest = strings.TrimLeft(rest, " \t")
+		if !strings.HasPrefix(rest, `"`) {
+			return nil, "", fmt.Errorf("%w: label %s value is not quoted", ErrInvalidExposition, name)
+		}
+
+		var (
+			value   strings.Builder
+			escaped bool
+			end     = -1
+		)
+		for i := 1; i < len(rest); i++ {
+			c := rest[i]
+			switch {
+			case escaped:
+				if c == 'n' {
+					c = '\n'
+				}
+				value.WriteByte(c)
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				end = i
+			default:
+				value.WriteByte(c)
+			}
+			if end >= 0 {
+				break
+			}
+		}
+		if end < 0 {
+			return nil, "", fmt.Errorf("%w: label %s value is not terminated", ErrInvalidExposition, name)
+		}
+
+		labels[name] = value.String()
+		s = strings.TrimLeft(rest[end+1:], " \t")
+		s = strings.TrimPrefix(s, ",")
+	}
+}
@@ -0,0 +1,46 @@
+package collector
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parsePromText(t *testing.T) {
+	text := `# HELP http_requests_total Requests.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",path="/a \"b\"\\c"} 10 1700000000000
+http_requests_total{method="POST",} 2
+
+# TYPE temperature gauge
+temperature -1.5e1
+up +Inf
+`
+	types, samples, err := parsePromText(strings.NewReader(text))
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"http_requests_total": "counter", "temperature": "gauge"}, types)
+	require.Len(t, samples, 4)
+	assert.Equal(t, promSample{name: "http_requests_total", labels: models.Labels{"method": "GET", "path": `/a "b"\c`}, value: 10}, samples[0])
+	assert.Equal(t, models.Labels{"method": "POST"}, samples[1].labels)
+	assert.Equal(t, -15.0, samples[2].value)
+	assert.True(t, math.IsInf(samples[3].value, 1))
+}
+
+func Test_parsePromText_WhenLineIsInvalid(t *testing.T) {
+	for _, text := range []string{
+		"metric",
+		"metric abc",
+		`metric{a="b} 1`,
+		"metric{a=b} 1",
+		"metric 1 2 3",
+	} {
+		_, _, err := parsePromText(strings.NewReader(text))
+		assert.ErrorIs(t, err, ErrInvalidExposition, text)
+	}
+}
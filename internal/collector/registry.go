@@ -0,0 +1,92 @@
+// Package collector contains agent metric sources and the registry that configures them.
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/config"
+	"github.com/Chystik/runtime-metrics/internal/service"
+)
+
+var (
+	ErrDuplicateCollector = errors.New("collector is already registered")
+	ErrUnknownCollector   = errors.New("unknown collector")
+)
+
+// defaultCollectors are enabled unless they are disabled in the config, other
+// collectors are enabled only when they are listed in it
+var defaultCollectors = map[string]bool{
+	MemStatsName: true,
+	SystemName:   true,
+}
+
+// Factory creates a collector polled every interval
+type Factory func(interval time.Duration) (service.Collector, error)
+
+// Registry creates the collectors enabled in the agent config
+type Registry struct {
+	cfg             config.Collectors
+	defaultInterval time.Duration
+	names           map[string]struct{}
+	collectors      []service.Collector
+}
+
+func NewRegistry(cfg config.Collectors, defaultInterval time.Duration) *Registry {
+	return &Registry{
+		cfg:             cfg,
+		defaultInterval: defaultInterval,
+		names:           make(map[string]struct{}),
+	}
+}
+
+// Register creates the collector with the configured interval, disabled collectors are not created
+func (r *Registry) Register(name string, newCollector Factory) error {
+	if _, ok := r.names[name]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateCollector, name)
+	}
+	r.names[name] = struct{}{}
+
+	if !r.cfg.Enabled(name, defaultCollectors[name]) {
+		return nil
+	}
+
+	c, err := newCollector(r.cfg.Interval(name, r.defaultInterval))
+	if err != nil {
+		return fmt.Errorf("collector %s: %w", name, err)
+	}
+	r.collectors = append(r.collectors, c)
+
+	return nil
+}
+
+// Validate checks that all configured collectors are registered
+func (r *Registry) Validate() error {
+	var unknown []string
+	for name := range r.cfg {
+		if _, ok := r.names[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("%w: %v", ErrUnknownCollector, unknown)
+	}
+	return nil
+}
+
+// Collectors returns enabled collectors in the order of registration
+func (r *Registry) Collectors() []service.Collector {
+	return r.collectors
+}
+
+// Names returns names of enabled collectors in the order of registration
+func (r *Registry) Names() []string {
+	names := make([]string, len(r.collectors))
+	for i, c := range r.collectors {
+		names[i] = c.Name()
+	}
+	return names
+}
@@ -0,0 +1,80 @@
+package collector
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/config"
+	"github.com/Chystik/runtime-metrics/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_Register(t *testing.T) {
+	var cfg config.Collectors
+	require.NoError(t, cfg.Set("memstats:5s,system:off,listed:on"))
+
+	r := NewRegistry(cfg, time.Second)
+
+	assert.NoError(t, r.Register(MemStatsName, func(interval time.Duration) (service.Collector, error) {
+		return NewMemStats(interval, nil, nil), nil
+	}))
+	assert.NoError(t, r.Register(SystemName, func(interval time.Duration) (service.Collector, error) {
+		t.Fatal("disabled collector is created")
+		return nil, nil
+	}))
+	assert.NoError(t, r.Register("other", func(interval time.Duration) (service.Collector, error) {
+		t.Fatal("collector that is not listed is created")
+		return nil, nil
+	}))
+	assert.NoError(t, r.Register("listed", func(interval time.Duration) (service.Collector, error) {
+		return NewSystem(interval), nil
+	}))
+	assert.NoError(t, r.Validate())
+
+	require.Len(t, r.Collectors(), 2)
+	assert.Equal(t, 5*time.Second, r.Collectors()[0].Interval())
+	assert.Equal(t, time.Second, r.Collectors()[1].Interval())
+	assert.Equal(t, []string{MemStatsName, SystemName}, r.Names())
+}
+
+func TestRegistry_Register_Defaults(t *testing.T) {
+	r := NewRegistry(nil, time.Second)
+
+	assert.NoError(t, r.Register(MemStatsName, func(interval time.Duration) (service.Collector, error) {
+		return NewMemStats(interval, nil, nil), nil
+	}))
+	assert.NoError(t, r.Register(SystemName, func(interval time.Duration) (service.Collector, error) {
+		return NewSystem(interval), nil
+	}))
+	assert.NoError(t, r.Register(RuntimeName, func(interval time.Duration) (service.Collector, error) {
+		t.Fatal("opt-in collector is created")
+		return nil, nil
+	}))
+
+	assert.Equal(t, []string{MemStatsName, SystemName}, r.Names())
+}
+
+func TestRegistry_Register_Errors(t *testing.T) {
+	r := NewRegistry(config.Collectors{"bad": {}}, time.Second)
+
+	newMemStats := func(interval time.Duration) (service.Collector, error) {
+		return NewMemStats(interval, nil, nil), nil
+	}
+
+	assert.NoError(t, r.Register(MemStatsName, newMemStats))
+	assert.ErrorIs(t, r.Register(MemStatsName, newMemStats), ErrDuplicateCollector)
+
+	errFactory := errors.New("bad config")
+	assert.ErrorIs(t, r.Register("bad", func(time.Duration) (service.Collector, error) {
+		return nil, errFactory
+	}), errFactory)
+}
+
+func TestRegistry_Validate_WhenCollectorIsUnknown(t *testing.T) {
+	r := NewRegistry(config.Collectors{"memstat": {Disabled: true}}, time.Second)
+
+	assert.ErrorIs(t, r.Validate(), ErrUnknownCollector)
+}
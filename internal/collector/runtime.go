@@ -0,0 +1,187 @@
+package collector
+
+import (
+	"context"
+	"math"
+	"runtime/metrics"
+	"strings"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/config"
+	"github.com/Chystik/runtime-metrics/internal/models"
+)
+
+const RuntimeName = "runtime"
+
+// runtimeMetrics reads runtime/metrics samples selected by collectable metrics
+// patterns, it does not stop the world unlike runtime.ReadMemStats
+type runtimeMetrics struct {
+	interval time.Duration
+	samples  []metrics.Sample
+	descs    []metrics.Description
+	ids      []string
+	// cumulative values of the previous collect, reported as increments
+	counters   counters
+	histograms map[string]*models.Histogram
+}
+
+// NewRuntime returns a collector of runtime/metrics samples matching patterns of
+// collectable metrics starting with a slash, all supported samples are collected
+// when there are no such patterns
+func NewRuntime(interval time.Duration, selected config.CollectableMetrics) *runtimeMetrics {
+	var patterns []string
+	for _, s := range selected {
+		if strings.HasPrefix(s, "/") {
+			patterns = append(patterns, s)
+		}
+	}
+
+	rm := &runtimeMetrics{
+		interval:   interval,
+		counters:   make(counters),
+		histograms: make(map[string]*models.Histogram),
+	}
+
+	for _, d := range metrics.All() {
+		if d.Kind == metrics.KindBad || !matchAny(patterns, d.Name) {
+			continue
+		}
+		rm.samples = append(rm.samples, metrics.Sample{Name: d.Name})
+		rm.descs = append(rm.descs, d)
+		rm.ids = append(rm.ids, runtimeMetricID(d.Name))
+	}
+
+	return rm
+}
+
+func (rm *runtimeMetrics) Name() string {
+	return RuntimeName
+}
+
+func (rm *runtimeMetrics) Interval() time.Duration {
+	return rm.interval
+}
+
+// Collect returns cumulative integer samples as counter increments, cumulative
+// histograms as new observations and other samples as gauges. Cumulative samples
+// are not reported on the first collect, as their values are not increments.
+func (rm *runtimeMetrics) Collect(ctx context.Context) ([]models.Metric, error) {
+	metrics.Read(rm.samples)
+
+	res := make([]models.Metric, 0, len(rm.samples))
+
+	for i, s := range rm.samples {
+		id := rm.ids[i]
+
+		switch s.Value.Kind() {
+		case metrics.KindUint64:
+			v := s.Value.Uint64()
+			if !rm.descs[i].Cumulative {
+				res = append(res, gauge(id, float64(v)))
+				continue
+			}
+
+			res = rm.counters.add(res, id, nil, v)
+		case metrics.KindFloat64:
+			res = append(res, gauge(id, s.Value.Float64()))
+		case metrics.KindFloat64Histogram:
+			h := runtimeHistogram(s.Value.Float64Histogram())
+			if !rm.descs[i].Cumulative {
+				res = append(res, models.Metric{ID: id, MType: "histogram", Histogram: h})
+				continue
+			}
+
+			prev, ok := rm.histograms[id]
+			rm.histograms[id] = h
+			if !ok {
+				continue
+			}
+			res = append(res, models.Metric{ID: id, MType: "histogram", Histogram: h.Diff(prev)})
+		}
+	}
+
+	return res, nil
+}
+
+// runtimeHistogram converts the runtime histogram into the model one. The infinite upper
+// bound is dropped, its observations are counted only in the total count.
+// The sum is unknown and estimated from the bucket bounds.
+func runtimeHistogram(rh *metrics.Float64Histogram) *models.Histogram {
+	h := &models.Histogram{Buckets: make([]models.Bucket, 0, len(rh.Counts))}
+
+	for i, n := range rh.Counts {
+		lo, hi := rh.Buckets[i], rh.Buckets[i+1]
+
+		if !math.IsInf(hi, 1) {
+			h.Buckets = append(h.Buckets, models.Bucket{UpperBound: hi, Count: n})
+		}
+		h.Count += n
+
+		switch {
+		case math.IsInf(lo, -1):
+			h.Sum += hi * float64(n)
+		case math.IsInf(hi, 1):
+			h.Sum += lo * float64(n)
+		default:
+			h.Sum += (lo + hi) / 2 * float64(n)
+		}
+	}
+
+	return h
+}
+
+// runtimeMetricID maps a runtime/metrics name like /sched/goroutines:goroutines
+// to go_sched_goroutines_goroutines
+func runtimeMetricID(name string) string {
+	var b strings.Builder
+	b.Grow(len(name) + 2)
+	b.WriteString("go")
+
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+
+	return b.String()
+}
+
+// matchAny reports whether the name matches one of patterns or patterns are empty
+func matchAny(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if matchPattern(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPattern matches the name against the pattern where * matches any characters including slashes
+func matchPattern(pattern, name string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == name
+	}
+
+	if !strings.HasPrefix(name, parts[0]) {
+		return false
+	}
+	name = name[len(parts[0]):]
+
+	last := parts[len(parts)-1]
+	for _, p := range parts[1 : len(parts)-1] {
+		i := strings.Index(name, p)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(p):]
+	}
+
+	return len(name) >= len(last) && strings.HasSuffix(name, last)
+}
@@ -0,0 +1,108 @@
+package collector
+
+import (
+	"context"
+	"math"
+	"runtime"
+	"runtime/metrics"
+	"testing"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_runtimeMetrics_Collect(t *testing.T) {
+	c := NewRuntime(time.Second, []string{"Alloc", "/sched/goroutines:goroutines", "/gc/cycles/*", "/sched/latencies:*"})
+	assert.Equal(t, RuntimeName, c.Name())
+
+	_, err := c.Collect(context.Background())
+	require.NoError(t, err)
+
+	runtime.GC()
+
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+
+	byID := make(map[string]models.Metric)
+	for _, m := range metrics {
+		byID[m.ID] = m
+	}
+
+	assert.Equal(t, "gauge", byID["go_sched_goroutines_goroutines"].MType)
+	assert.Equal(t, "histogram", byID["go_sched_latencies_seconds"].MType)
+	require.Equal(t, "counter", byID["go_gc_cycles_total_gc_cycles"].MType)
+	assert.GreaterOrEqual(t, *byID["go_gc_cycles_total_gc_cycles"].Delta, int64(1))
+	for id := range byID {
+		assert.Regexp(t, `^go_(sched_goroutines|sched_latencies|gc_cycles)_`, id)
+	}
+}
+
+func Test_runtimeMetrics_Collect_SkipsFirstCumulativeValues(t *testing.T) {
+	c := NewRuntime(time.Second, []string{"/gc/cycles/total:gc-cycles", "/sched/latencies:seconds", "/sched/goroutines:goroutines"})
+
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "go_sched_goroutines_goroutines", metrics[0].ID)
+
+	metrics, err = c.Collect(context.Background())
+	require.NoError(t, err)
+
+	assert.Len(t, metrics, 3)
+}
+
+func Test_runtimeMetrics_Collect_AllSupportedSamples(t *testing.T) {
+	c := NewRuntime(time.Second, []string{"Alloc"})
+
+	_, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+
+	assert.Len(t, metrics, len(c.samples))
+	for _, m := range metrics {
+		if m.Histogram != nil {
+			assert.NoError(t, m.Histogram.Validate(), m.ID)
+		}
+	}
+}
+
+func Test_runtimeHistogram(t *testing.T) {
+	h := runtimeHistogram(&metrics.Float64Histogram{
+		Counts:  []uint64{1, 2, 3},
+		Buckets: []float64{math.Inf(-1), 1, 2, math.Inf(1)},
+	})
+
+	assert.Equal(t, &models.Histogram{
+		Buckets: []models.Bucket{{UpperBound: 1, Count: 1}, {UpperBound: 2, Count: 2}},
+		Sum:     1 + 2*1.5 + 3*2,
+		Count:   6,
+	}, h)
+}
+
+func Test_runtimeMetricID(t *testing.T) {
+	assert.Equal(t, "go_gc_heap_allocs_by_size_bytes", runtimeMetricID("/gc/heap/allocs-by-size:bytes"))
+}
+
+func Test_matchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"/sched/goroutines:goroutines", "/sched/goroutines:goroutines", true},
+		{"/sched/*", "/sched/latencies:seconds", true},
+		{"/gc/*", "/gc/heap/allocs:bytes", true},
+		{"/gc/*:bytes", "/gc/heap/allocs:objects", false},
+		{"/*/heap/*:bytes", "/gc/heap/allocs:bytes", true},
+		{"/sched/*", "/gc/cycles/total:gc-cycles", false},
+		{"/a*a", "/a", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, matchPattern(tt.pattern, tt.name), tt.pattern)
+	}
+}
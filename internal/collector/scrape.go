@@ -0,0 +1,291 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+)
+
+const (
+	ScrapeName = "scrape"
+
+	scrapeAccept  = "text/plain;version=0.0.4;q=0.9,application/json;q=0.5,*/*;q=0.1"
+	maxScrapeBody = 16 << 20
+)
+
+// scrape reads metrics of HTTP targets exposing the Prometheus text format or expvar JSON
+type scrape struct {
+	interval   time.Duration
+	targets    []string
+	client     *http.Client
+	counters   floatCounters
+	histograms map[string]*models.Histogram
+}
+
+// NewScrape returns a collector of the target URLs, each target metric gets the instance
+// label with the target host
+func NewScrape(interval time.Duration, targets []string) (*scrape, error) {
+	for _, t := range targets {
+		u, err := url.Parse(t)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("bad scrape target %q", t)
+		}
+	}
+
+	return &scrape{
+		interval:   interval,
+		targets:    targets,
+		client:     &http.Client{},
+		counters:   make(floatCounters),
+		histograms: make(map[string]*models.Histogram),
+	}, nil
+}
+
+func (s *scrape) Name() string {
+	return ScrapeName
+}
+
+func (s *scrape) Interval() time.Duration {
+	return s.interval
+}
+
+// Collect scrapes targets one by one, failed targets are reported in the error
+func (s *scrape) Collect(ctx context.Context) ([]models.Metric, error) {
+	var (
+		metrics []models.Metric
+		errs    []error
+	)
+
+	for _, t := range s.targets {
+		m, err := s.scrapeTarget(ctx, t)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("target %s: %w", t, err))
+			continue
+		}
+		metrics = append(metrics, m...)
+	}
+
+	return metrics, errors.Join(errs...)
+}
+
+func (s *scrape) scrapeTarget(ctx context.Context, target string) ([]models.Metric, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", scrapeAccept)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("resp status code: %s", resp.Status)
+	}
+
+	body := io.LimitReader(resp.Body, maxScrapeBody)
+	instance := models.Labels{"instance": req.URL.Host}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		return expvarMetrics(body, instance)
+	}
+
+	types, samples, err := parsePromText(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.promMetrics(types, samples, instance), nil
+}
+
+// expvarMetrics returns numbers and booleans of the expvar JSON as gauges, nested
+// object keys are joined with an underscore, arrays and strings are skipped
+func expvarMetrics(r io.Reader, instance models.Labels) ([]models.Metric, error) {
+	d := json.NewDecoder(r)
+	d.UseNumber()
+
+	var vars map[string]any
+	if err := d.Decode(&vars); err != nil {
+		return nil, err
+	}
+
+	var metrics []models.Metric
+	var walk func(prefix string, v any)
+	walk = func(prefix string, v any) {
+		switch val := v.(type) {
+		case json.Number:
+			if f, err := val.Float64(); err == nil {
+				metrics = append(metrics, labeledGauge(prefix, f, instance))
+			}
+		case bool:
+			var f float64
+			if val {
+				f = 1
+			}
+			metrics = append(metrics, labeledGauge(prefix, f, instance))
+		case map[string]any:
+			keys := make([]string, 0, len(val))
+			for k := range val {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				walk(prefix+"_"+k, val[k])
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		walk(k, vars[k])
+	}
+
+	return metrics, nil
+}
+
+// promMetrics converts samples into metrics. Gauges and untyped samples become gauges,
+// counters and cumulative histograms are reported as increments since the previous
+// scrape, summary quantiles and sums become gauges and counts become counters.
+func (s *scrape) promMetrics(types map[string]string, samples []promSample, instance models.Labels) []models.Metric {
+	var metrics []models.Metric
+
+	type histogramSeries struct {
+		id      string
+		labels  models.Labels
+		buckets map[float64]float64
+		sum     float64
+		count   float64
+	}
+	var histograms []*histogramSeries
+	byKey := make(map[string]*histogramSeries)
+
+	for _, sample := range samples {
+		labels := mergeLabels(instance, sample.labels)
+		family, suffix := promFamily(types, sample.name)
+
+		switch types[family] {
+		case "counter":
+			metrics = s.counters.add(metrics, sample.name, labels, sample.value)
+		case "histogram":
+			le := labels["le"]
+			delete(labels, "le")
+
+			key := family + "{" + labels.String() + "}"
+			h, ok := byKey[key]
+			if !ok {
+				h = &histogramSeries{id: family, labels: labels, buckets: make(map[float64]float64)}
+				byKey[key] = h
+				histograms = append(histograms, h)
+			}
+
+			switch suffix {
+			case "_bucket":
+				if bound, err := strconv.ParseFloat(le, 64); err == nil {
+					h.buckets[bound] = sample.value
+				}
+			case "_sum":
+				h.sum = sample.value
+			case "_count":
+				h.count = sample.value
+			}
+		case "summary":
+			if suffix == "_count" {
+				metrics = s.counters.add(metrics, sample.name, labels, sample.value)
+				continue
+			}
+			metrics = append(metrics, labeledGauge(sample.name, sample.value, labels))
+		default:
+			metrics = append(metrics, labeledGauge(sample.name, sample.value, labels))
+		}
+	}
+
+	for _, hs := range histograms {
+		bounds := make([]float64, 0, len(hs.buckets))
+		for b := range hs.buckets {
+			if !math.IsInf(b, 1) {
+				bounds = append(bounds, b)
+			}
+		}
+		sort.Float64s(bounds)
+
+		// exposed buckets are cumulative, the model ones are not
+		h := &models.Histogram{Buckets: make([]models.Bucket, len(bounds)), Sum: hs.sum, Count: uint64(hs.count)}
+		var prev float64
+		for i, b := range bounds {
+			n := hs.buckets[b]
+			h.Buckets[i] = models.Bucket{UpperBound: b, Count: uint64(math.Max(n-prev, 0))}
+			prev = n
+		}
+		if h.Validate() != nil {
+			continue
+		}
+
+		key := hs.id + "{" + hs.labels.String() + "}"
+		last, ok := s.histograms[key]
+		s.histograms[key] = h
+		if !ok {
+			continue
+		}
+		metrics = append(metrics, models.Metric{ID: hs.id, MType: "histogram", Histogram: h.Diff(last), Labels: hs.labels})
+	}
+
+	return metrics
+}
+
+// promFamily returns the family name of the sample and its suffix for histogram,
+// summary and counter samples
+func promFamily(types map[string]string, name string) (string, string) {
+	if _, ok := types[name]; ok {
+		return name, ""
+	}
+
+	for _, suffix := range []string{"_bucket", "_sum", "_count", "_total"} {
+		family, ok := strings.CutSuffix(name, suffix)
+		if !ok {
+			continue
+		}
+		switch types[family] {
+		case "histogram", "summary":
+			if suffix != "_total" {
+				return family, suffix
+			}
+		case "counter":
+			if suffix == "_total" {
+				return family, suffix
+			}
+		}
+	}
+
+	return name, ""
+}
+
+// mergeLabels returns a copy of target labels overridden by the sample ones
+func mergeLabels(target, sample models.Labels) models.Labels {
+	labels := make(models.Labels, len(target)+len(sample))
+	for k, v := range target {
+		labels[k] = v
+	}
+	for k, v := range sample {
+		labels[k] = v
+	}
+	return labels
+}
@@ -0,0 +1,112 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_scrape_Collect_Prometheus(t *testing.T) {
+	var scrapes int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scrapes++
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprintf(w, `# TYPE requests_total counter
+requests_total{code="200"} %d.5
+# TYPE goroutines gauge
+goroutines 7
+# TYPE latency histogram
+latency_bucket{le="0.1"} %d
+latency_bucket{le="1"} %d
+latency_bucket{le="+Inf"} %d
+latency_sum %d
+latency_count %d
+# TYPE rpc summary
+rpc{quantile="0.5"} 0.2
+rpc_sum 3
+rpc_count %d
+`, 10*scrapes, scrapes, 2*scrapes, 3*scrapes, scrapes, 3*scrapes, 5*scrapes)
+	}))
+	defer srv.Close()
+
+	c, err := NewScrape(time.Second, []string{srv.URL + "/metrics"})
+	require.NoError(t, err)
+	assert.Equal(t, ScrapeName, c.Name())
+
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"goroutines", "rpc", "rpc_sum"}, metricIDs(metrics))
+
+	metrics, err = c.Collect(context.Background())
+	require.NoError(t, err)
+
+	byID := make(map[string]models.Metric)
+	for _, m := range metrics {
+		byID[m.ID] = m
+		assert.Equal(t, strings.TrimPrefix(srv.URL, "http://"), m.Labels["instance"])
+	}
+
+	assert.Equal(t, int64(10), *byID["requests_total"].Delta)
+	assert.Equal(t, "200", byID["requests_total"].Labels["code"])
+	assert.Equal(t, 7.0, *byID["goroutines"].Value)
+	assert.Equal(t, "0.5", byID["rpc"].Labels["quantile"])
+	assert.Equal(t, int64(5), *byID["rpc_count"].Delta)
+
+	h := byID["latency"].Histogram
+	require.NotNil(t, h)
+	assert.Equal(t, []models.Bucket{{UpperBound: 0.1, Count: 1}, {UpperBound: 1, Count: 1}}, h.Buckets)
+	assert.Equal(t, uint64(3), h.Count)
+	assert.Equal(t, 1.0, h.Sum)
+}
+
+func Test_scrape_Collect_Expvar(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprint(w, `{"cmdline": ["app"], "memstats": {"Alloc": 1024, "PauseNs": [1, 2]}, "requests": 5, "ready": true, "version": "1.0"}`)
+	}))
+	defer srv.Close()
+
+	c, err := NewScrape(time.Second, []string{srv.URL + "/debug/vars"})
+	require.NoError(t, err)
+
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"memstats_Alloc", "ready", "requests"}, metricIDs(metrics))
+	assert.Equal(t, 1024.0, *metrics[0].Value)
+	assert.Equal(t, 1.0, *metrics[1].Value)
+}
+
+func Test_scrape_Collect_WhenTargetFails(t *testing.T) {
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer bad.Close()
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "up 1")
+	}))
+	defer good.Close()
+
+	c, err := NewScrape(time.Second, []string{bad.URL, good.URL})
+	require.NoError(t, err)
+
+	metrics, err := c.Collect(context.Background())
+
+	assert.ErrorContains(t, err, bad.URL)
+	assert.Equal(t, []string{"up"}, metricIDs(metrics))
+}
+
+func TestNewScrape_WhenTargetIsInvalid(t *testing.T) {
+	_, err := NewScrape(time.Second, []string{"localhost:9090/metrics"})
+
+	assert.Error(t, err)
+}
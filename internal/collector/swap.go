@@ -0,0 +1,53 @@
+package collector
+
+import (
+	"context"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+const SwapName = "swap"
+
+// swap reads swap usage and paging counters
+type swap struct {
+	interval   time.Duration
+	swapMemory func(ctx context.Context) (*mem.SwapMemoryStat, error)
+	counters   counters
+}
+
+func NewSwap(interval time.Duration) *swap {
+	return &swap{
+		interval:   interval,
+		swapMemory: mem.SwapMemoryWithContext,
+		counters:   make(counters),
+	}
+}
+
+func (s *swap) Name() string {
+	return SwapName
+}
+
+func (s *swap) Interval() time.Duration {
+	return s.interval
+}
+
+// Collect returns swap usage gauges and swapped in and out bytes since the previous collect
+func (s *swap) Collect(ctx context.Context) ([]models.Metric, error) {
+	sm, err := s.swapMemory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := []models.Metric{
+		gauge("SwapTotal", float64(sm.Total)),
+		gauge("SwapFree", float64(sm.Free)),
+		gauge("SwapUsed", float64(sm.Used)),
+	}
+	metrics = s.counters.add(metrics, "SwapIn", nil, sm.Sin)
+	metrics = s.counters.add(metrics, "SwapOut", nil, sm.Sout)
+
+	return metrics, nil
+}
@@ -0,0 +1,38 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_swap_Collect(t *testing.T) {
+	samples := []*mem.SwapMemoryStat{
+		{Total: 100, Free: 80, Used: 20, Sin: 10, Sout: 20},
+		{Total: 100, Free: 70, Used: 30, Sin: 15, Sout: 20},
+	}
+
+	c := NewSwap(time.Second)
+	assert.Equal(t, SwapName, c.Name())
+
+	var calls int
+	c.swapMemory = func(context.Context) (*mem.SwapMemoryStat, error) {
+		calls++
+		return samples[calls-1], nil
+	}
+
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"SwapTotal", "SwapFree", "SwapUsed"}, metricIDs(metrics))
+
+	metrics, err = c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"SwapTotal", "SwapFree", "SwapUsed", "SwapIn", "SwapOut"}, metricIDs(metrics))
+	assert.Equal(t, 30.0, *metrics[2].Value)
+	assert.Equal(t, int64(5), *metrics[3].Delta)
+	assert.Equal(t, int64(0), *metrics[4].Delta)
+}
@@ -0,0 +1,133 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+const SystemName = "system"
+
+// system reads host memory and CPU utilization metrics with gopsutil
+type system struct {
+	interval time.Duration
+	cpuTimes func(ctx context.Context, percpu bool) ([]cpu.TimesStat, error)
+	// previous samples, utilization is computed between two collects
+	prevCores []cpu.TimesStat
+	prevTotal *cpu.TimesStat
+}
+
+func NewSystem(interval time.Duration) *system {
+	return &system{
+		interval: interval,
+		cpuTimes: cpu.TimesWithContext,
+	}
+}
+
+func (s *system) Name() string {
+	return SystemName
+}
+
+func (s *system) Interval() time.Duration {
+	return s.interval
+}
+
+// Collect returns memory gauges and CPU utilization in percents since the previous collect,
+// CPU gauges are omitted on the first collect
+func (s *system) Collect(ctx context.Context) ([]models.Metric, error) {
+	vm, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	totalMemory := float64(vm.Total)
+	freeMemory := float64(vm.Free)
+
+	metrics := []models.Metric{
+		{ID: "TotalMemory", MType: "gauge", Value: &totalMemory},
+		{ID: "FreeMemory", MType: "gauge", Value: &freeMemory},
+	}
+
+	cores, err := s.cpuTimes(ctx, true)
+	if err != nil {
+		return metrics, err
+	}
+	total, err := s.cpuTimes(ctx, false)
+	if err != nil {
+		return metrics, err
+	}
+	if len(total) == 0 {
+		return metrics, fmt.Errorf("no total cpu times")
+	}
+
+	// cores may go online or offline between collects, the sample is a new baseline then
+	if s.prevTotal != nil && len(s.prevCores) == len(cores) {
+		for i := range cores {
+			util, ok := cpuUtilization(s.prevCores[i], cores[i])
+			if ok {
+				metrics = append(metrics, gauge(fmt.Sprintf("CPUutilization%d", i+1), util.busy))
+			}
+		}
+
+		if util, ok := cpuUtilization(*s.prevTotal, total[0]); ok {
+			metrics = append(metrics,
+				gauge("CPUutilizationTotal", util.busy),
+				gauge("CPUutilizationUser", util.user),
+				gauge("CPUutilizationSystem", util.system),
+				gauge("CPUutilizationIowait", util.iowait),
+			)
+		}
+	}
+
+	s.prevCores = cores
+	s.prevTotal = &total[0]
+
+	return metrics, nil
+}
+
+type utilization struct {
+	busy, user, system, iowait float64
+}
+
+// cpuUtilization returns shares of time in percents between two samples,
+// false if no time elapsed or the counters were reset
+func cpuUtilization(prev, cur cpu.TimesStat) (utilization, bool) {
+	elapsed := cpuTotal(cur) - cpuTotal(prev)
+	if elapsed <= 0 {
+		return utilization{}, false
+	}
+
+	idle := (cur.Idle + cur.Iowait) - (prev.Idle + prev.Iowait)
+
+	return utilization{
+		busy:   percent(elapsed-idle, elapsed),
+		user:   percent((cur.User+cur.Nice)-(prev.User+prev.Nice), elapsed),
+		system: percent(cur.System-prev.System, elapsed),
+		iowait: percent(cur.Iowait-prev.Iowait, elapsed),
+	}, true
+}
+
+// cpuTotal sums all CPU times, guest time is already accounted in user time
+func cpuTotal(t cpu.TimesStat) float64 {
+	return t.User + t.System + t.Idle + t.Nice + t.Iowait + t.Irq + t.Softirq + t.Steal
+}
+
+func percent(part, whole float64) float64 {
+	p := 100 * part / whole
+	switch {
+	case p < 0:
+		return 0
+	case p > 100:
+		return 100
+	}
+	return p
+}
+
+func gauge(id string, v float64) models.Metric {
+	return models.Metric{ID: id, MType: "gauge", Value: &v}
+}
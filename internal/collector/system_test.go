@@ -0,0 +1,98 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_system_Collect(t *testing.T) {
+	c := NewSystem(time.Second)
+	assert.Equal(t, SystemName, c.Name())
+
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"TotalMemory", "FreeMemory"}, metricIDs(metrics))
+
+	metrics, err = c.Collect(context.Background())
+	require.NoError(t, err)
+	for _, m := range metrics {
+		assert.Equal(t, "gauge", m.MType)
+	}
+}
+
+func Test_system_Collect_CPUUtilization(t *testing.T) {
+	samples := [][]cpu.TimesStat{
+		{{User: 10, System: 10, Idle: 80}, {User: 0, System: 0, Idle: 100}},
+		{{User: 30, System: 20, Idle: 140, Iowait: 10}, {User: 50, System: 0, Idle: 150}},
+	}
+
+	c := NewSystem(time.Second)
+	var calls int
+	c.cpuTimes = func(_ context.Context, percpu bool) ([]cpu.TimesStat, error) {
+		cores := samples[calls/2]
+		calls++
+		if percpu {
+			return cores, nil
+		}
+		total := cpu.TimesStat{CPU: "cpu-total"}
+		for _, t := range cores {
+			total.User += t.User
+			total.System += t.System
+			total.Idle += t.Idle
+			total.Iowait += t.Iowait
+		}
+		return []cpu.TimesStat{total}, nil
+	}
+
+	_, err := c.Collect(context.Background())
+	require.NoError(t, err)
+
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+
+	got := make(map[string]float64)
+	for _, m := range metrics {
+		got[m.ID] = *m.Value
+	}
+
+	assert.InDelta(t, 30.0, got["CPUutilization1"], 1e-9)
+	assert.InDelta(t, 50.0, got["CPUutilization2"], 1e-9)
+	assert.InDelta(t, 40.0, got["CPUutilizationTotal"], 1e-9)
+	assert.InDelta(t, 35.0, got["CPUutilizationUser"], 1e-9)
+	assert.InDelta(t, 5.0, got["CPUutilizationSystem"], 1e-9)
+	assert.InDelta(t, 5.0, got["CPUutilizationIowait"], 1e-9)
+}
+
+func Test_system_Collect_WhenCPUTimesFail(t *testing.T) {
+	c := NewSystem(time.Second)
+	c.cpuTimes = func(context.Context, bool) ([]cpu.TimesStat, error) {
+		return nil, errors.New("some error")
+	}
+
+	metrics, err := c.Collect(context.Background())
+
+	assert.Error(t, err)
+	assert.Equal(t, []string{"TotalMemory", "FreeMemory"}, metricIDs(metrics))
+}
+
+func Test_cpuUtilization_WhenCountersReset(t *testing.T) {
+	_, ok := cpuUtilization(cpu.TimesStat{Idle: 100}, cpu.TimesStat{Idle: 10})
+
+	assert.False(t, ok)
+}
+
+func metricIDs(metrics []models.Metric) []string {
+	ids := make([]string, len(metrics))
+	for i, m := range metrics {
+		ids[i] = m.ID
+	}
+	return ids
+}
@@ -2,38 +2,57 @@ package inmemory
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/Chystik/runtime-metrics/config"
 	"github.com/Chystik/runtime-metrics/internal/models"
 )
 
+// samplesLimit bounds the memory used by samples of a metric, older ones are dropped
+// even within the retention
+const samplesLimit = 10000
+
 var (
-	ErrNotFoundMetric = errors.New("not found in repository")
+	ErrNotFoundMetric = models.ErrNotFoundMetric
 )
 
 type memStorage struct {
-	data map[string]models.Metric
-	Mu   sync.RWMutex
+	data    map[string]models.Metric
+	samples map[string][]models.Sample
+	batches map[string]time.Time
+	// batchRetention is how long IDs of stored batches are remembered
+	batchRetention time.Duration
+	// samplesRetention is how long samples are kept, zero keeps them
+	samplesRetention time.Duration
+	Mu               sync.RWMutex
 }
 
 func NewMetricsRepo(cfg *config.ServerConfig) *memStorage {
-	return &memStorage{data: make(map[string]models.Metric)}
+	return &memStorage{
+		data:             make(map[string]models.Metric),
+		samples:          make(map[string][]models.Sample),
+		batches:          make(map[string]time.Time),
+		batchRetention:   batchRetention(cfg),
+		samplesRetention: cfg.SamplesRetention.Duration,
+	}
 }
 
 func (ms *memStorage) UpdateGauge(ctx context.Context, metric models.Metric) error {
 	ms.Mu.Lock()
 	defer ms.Mu.Unlock()
 
-	m, ok := ms.data[metric.ID]
+	key := metric.Key()
+	m, ok := ms.data[key]
 	if !ok {
-		ms.data[metric.ID] = metric
+		ms.data[key] = metric
 	} else {
 		m.Value = metric.Value
-		ms.data[metric.ID] = m
+		ms.data[key] = m
 	}
+	ms.addSample(ms.data[key])
 
 	return nil
 }
@@ -42,13 +61,35 @@ func (ms *memStorage) UpdateCounter(ctx context.Context, metric models.Metric) e
 	ms.Mu.Lock()
 	defer ms.Mu.Unlock()
 
-	m, ok := ms.data[metric.ID]
+	key := metric.Key()
+	m, ok := ms.data[key]
 	if !ok {
-		ms.data[metric.ID] = metric
+		ms.data[key] = metric
 	} else {
 		*m.Delta = *metric.Delta + *m.Delta
-		ms.data[metric.ID] = m
+		ms.data[key] = m
 	}
+	ms.addSample(ms.data[key])
+
+	return nil
+}
+
+// UpdateHistogram replaces the stored histogram, merging is done by the service
+func (ms *memStorage) UpdateHistogram(ctx context.Context, metric models.Metric) error {
+	ms.Mu.Lock()
+	defer ms.Mu.Unlock()
+
+	ms.data[metric.Key()] = metric
+
+	return nil
+}
+
+// UpdateSummary replaces the stored summary, merging is done by the service
+func (ms *memStorage) UpdateSummary(ctx context.Context, metric models.Metric) error {
+	ms.Mu.Lock()
+	defer ms.Mu.Unlock()
+
+	ms.data[metric.Key()] = metric
 
 	return nil
 }
@@ -57,9 +98,9 @@ func (ms *memStorage) Get(ctx context.Context, metric models.Metric) (models.Met
 	ms.Mu.RLock()
 	defer ms.Mu.RUnlock()
 
-	m, ok := ms.data[metric.ID]
+	m, ok := ms.data[metric.Key()]
 	if !ok {
-		return models.Metric{ID: metric.ID, MType: "", Delta: nil, Value: nil}, fmt.Errorf("metric with ID %s %w", metric.ID, ErrNotFoundMetric)
+		return models.Metric{ID: metric.ID, MType: "", Delta: nil, Value: nil}, fmt.Errorf("metric %s %w", metric.Key(), ErrNotFoundMetric)
 	}
 
 	return m, nil
@@ -81,9 +122,116 @@ func (ms *memStorage) GetAll(ctx context.Context) ([]models.Metric, error) {
 func (ms *memStorage) UpdateList(ctx context.Context, metrics []models.Metric) error {
 	for _, m := range metrics {
 		ms.Mu.Lock()
-		ms.data[m.ID] = m
+		ms.put(m)
 		ms.Mu.Unlock()
 	}
 
 	return nil
 }
+
+func (ms *memStorage) GetRange(ctx context.Context, metric models.Metric, from, to time.Time) ([]models.Sample, error) {
+	ms.Mu.RLock()
+	defer ms.Mu.RUnlock()
+
+	s, ok := ms.samples[metric.Key()]
+	if !ok {
+		return nil, fmt.Errorf("metric %s %w", metric.Key(), ErrNotFoundMetric)
+	}
+
+	if ms.samplesRetention > 0 {
+		// samples of metrics that are not updated are pruned only on the next update
+		if oldest := time.Now().Add(-ms.samplesRetention); from.Before(oldest) {
+			from = oldest
+		}
+	}
+
+	// samples are appended in time order
+	i := sort.Search(len(s), func(i int) bool { return !s[i].Timestamp.Before(from) })
+	j := sort.Search(len(s), func(j int) bool { return s[j].Timestamp.After(to) })
+	if i >= j {
+		return []models.Sample{}, nil
+	}
+
+	res := make([]models.Sample, j-i)
+	copy(res, s[i:j])
+
+	return res, nil
+}
+
+func batchRetention(cfg *config.ServerConfig) time.Duration {
+	if cfg.BatchRetention.Duration > 0 {
+		return cfg.BatchRetention.Duration
+	}
+	return config.DefaultBatchRetention
+}
+
+// UpdateBatch stores the metrics like UpdateList unless the batch ID was stored within the retention.
+// Batch IDs are not saved to the storage file, so they are forgotten on restart.
+func (ms *memStorage) UpdateBatch(ctx context.Context, batchID string, metrics []models.Metric) error {
+	ms.Mu.Lock()
+	defer ms.Mu.Unlock()
+
+	now := time.Now()
+	if ms.batches == nil {
+		ms.batches = make(map[string]time.Time)
+	}
+	for id, t := range ms.batches {
+		if now.Sub(t) > ms.batchRetention {
+			delete(ms.batches, id)
+		}
+	}
+
+	if _, ok := ms.batches[batchID]; ok {
+		return nil
+	}
+	ms.batches[batchID] = now
+
+	for _, m := range metrics {
+		ms.put(m)
+	}
+
+	return nil
+}
+
+// put stores the metric of a list, counter increments are added to the stored counter,
+// the caller must hold the lock
+func (ms *memStorage) put(m models.Metric) {
+	key := m.Key()
+	if stored, ok := ms.data[key]; ok && m.MType == "counter" && stored.MType == "counter" &&
+		m.Delta != nil && stored.Delta != nil {
+		d := *stored.Delta + *m.Delta
+		m.Delta = &d
+	}
+
+	ms.data[key] = m
+	ms.addSample(m)
+}
+
+// addSample saves a copy of the metric value to the metric history, the caller must hold the lock
+func (ms *memStorage) addSample(m models.Metric) {
+	if ms.samples == nil {
+		ms.samples = make(map[string][]models.Sample)
+	}
+
+	now := time.Now()
+	sample := models.Sample{Metric: models.Metric{ID: m.ID, MType: m.MType, Labels: m.Labels}, Timestamp: now}
+	if m.Delta != nil {
+		d := *m.Delta
+		sample.Delta = &d
+	}
+	if m.Value != nil {
+		v := *m.Value
+		sample.Value = &v
+	}
+
+	key := m.Key()
+	s := append(ms.samples[key], sample)
+	if ms.samplesRetention > 0 {
+		oldest := now.Add(-ms.samplesRetention)
+		s = s[sort.Search(len(s), func(i int) bool { return !s[i].Timestamp.Before(oldest) }):]
+	}
+	if len(s) > samplesLimit {
+		s = s[len(s)-samplesLimit:]
+	}
+	ms.samples[key] = s
+}
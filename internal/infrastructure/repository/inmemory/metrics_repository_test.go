@@ -4,6 +4,7 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/Chystik/runtime-metrics/config"
 	"github.com/Chystik/runtime-metrics/internal/models"
@@ -287,13 +288,130 @@ func Test_memStorage_UpdateList(t *testing.T) {
 				var val models.Metric
 				var ok bool
 
-				if val, ok = tt.ms.data[m.ID]; !ok {
-					t.Errorf("memStorage.UpdateList() cant find stored metric %v", m.ID)
-				}
-				if val != m {
-					t.Errorf("memStorage.UpdateList() stored metric = %v, want %v", val, m)
+				if val, ok = tt.ms.data[m.Key()]; !ok {
+					t.Errorf("memStorage.UpdateList() cant find stored metric %v", m.Key())
 				}
+				assert.Equal(t, m, val)
 			}
 		})
 	}
 }
+
+func Test_memStorage_UpdateBatch(t *testing.T) {
+	ms := NewMetricsRepo(&config.ServerConfig{})
+	ctx := context.Background()
+
+	assert.NoError(t, ms.UpdateBatch(ctx, "batch-1", []models.Metric{{ID: "a", MType: "gauge", Value: createValue(1)}}))
+	// the resent batch is ignored
+	assert.NoError(t, ms.UpdateBatch(ctx, "batch-1", []models.Metric{{ID: "a", MType: "gauge", Value: createValue(2)}}))
+	assert.Equal(t, 1.0, *ms.data["a"].Value)
+
+	// expired batch IDs are forgotten
+	ms.batches["batch-1"] = time.Now().Add(-2 * ms.batchRetention)
+	assert.NoError(t, ms.UpdateBatch(ctx, "batch-2", nil))
+	assert.NotContains(t, ms.batches, "batch-1")
+	assert.Contains(t, ms.batches, "batch-2")
+}
+
+func Test_memStorage_UpdateList_AddsCounterDeltas(t *testing.T) {
+	ms := NewMetricsRepo(&config.ServerConfig{})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, ms.UpdateList(ctx, []models.Metric{{ID: "PollCount", MType: "counter", Delta: createDelta(5)}}))
+	}
+	for _, id := range []string{"batch-1", "batch-2"} {
+		assert.NoError(t, ms.UpdateBatch(ctx, id, []models.Metric{{ID: "PollCount", MType: "counter", Delta: createDelta(5)}}))
+	}
+
+	assert.Equal(t, int64(25), *ms.data["PollCount"].Delta)
+}
+
+func Test_memStorage_GetRange(t *testing.T) {
+	t.Parallel()
+	ms := NewMetricsRepo(&config.ServerConfig{})
+	ctx := context.Background()
+
+	from := time.Now()
+	_ = ms.UpdateCounter(ctx, models.Metric{ID: "counter", MType: "counter", Delta: createDelta(1)})
+	_ = ms.UpdateCounter(ctx, models.Metric{ID: "counter", MType: "counter", Delta: createDelta(2)})
+	_ = ms.UpdateGauge(ctx, models.Metric{ID: "gauge", MType: "gauge", Value: createValue(3)})
+	to := time.Now()
+
+	got, err := ms.GetRange(ctx, models.Metric{ID: "counter"}, from, to)
+	assert.NoError(t, err)
+	if assert.Len(t, got, 2) {
+		assert.Equal(t, int64(1), *got[0].Delta)
+		assert.Equal(t, int64(3), *got[1].Delta)
+		assert.False(t, got[1].Timestamp.Before(got[0].Timestamp))
+	}
+
+	got, err = ms.GetRange(ctx, models.Metric{ID: "gauge"}, to.Add(time.Second), to.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+
+	_, err = ms.GetRange(ctx, models.Metric{ID: "unknown"}, from, to)
+	assert.ErrorIs(t, err, ErrNotFoundMetric)
+}
+
+func Test_memStorage_SamplesRetention(t *testing.T) {
+	t.Parallel()
+	ms := NewMetricsRepo(&config.ServerConfig{SamplesRetention: config.Duration{Duration: time.Hour}})
+	ctx := context.Background()
+
+	_ = ms.UpdateGauge(ctx, models.Metric{ID: "gauge", MType: "gauge", Value: createValue(1)})
+	_ = ms.UpdateGauge(ctx, models.Metric{ID: "other", MType: "gauge", Value: createValue(1)})
+	ms.samples["gauge"][0].Timestamp = time.Now().Add(-2 * time.Hour)
+	ms.samples["other"][0].Timestamp = time.Now().Add(-2 * time.Hour)
+
+	_ = ms.UpdateGauge(ctx, models.Metric{ID: "gauge", MType: "gauge", Value: createValue(2)})
+	if assert.Len(t, ms.samples["gauge"], 1) {
+		assert.Equal(t, 2.0, *ms.samples["gauge"][0].Value)
+	}
+
+	// the sample of the metric without updates is kept but not returned
+	got, err := ms.GetRange(ctx, models.Metric{ID: "other"}, time.Now().Add(-3*time.Hour), time.Now())
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func Test_memStorage_Labels(t *testing.T) {
+	t.Parallel()
+	ms := NewMetricsRepo(&config.ServerConfig{})
+	ctx := context.Background()
+
+	_ = ms.UpdateCounter(ctx, models.Metric{ID: "requests", MType: "counter", Delta: createDelta(1), Labels: models.Labels{"host": "a"}})
+	_ = ms.UpdateCounter(ctx, models.Metric{ID: "requests", MType: "counter", Delta: createDelta(2), Labels: models.Labels{"host": "b"}})
+	_ = ms.UpdateCounter(ctx, models.Metric{ID: "requests", MType: "counter", Delta: createDelta(3), Labels: models.Labels{"host": "a"}})
+
+	got, err := ms.Get(ctx, models.Metric{ID: "requests", Labels: models.Labels{"host": "a"}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), *got.Delta)
+
+	got, err = ms.Get(ctx, models.Metric{ID: "requests", Labels: models.Labels{"host": "b"}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), *got.Delta)
+
+	_, err = ms.Get(ctx, models.Metric{ID: "requests"})
+	assert.ErrorIs(t, err, ErrNotFoundMetric)
+
+	all, err := ms.GetAll(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, all, 2)
+}
+
+func Test_memStorage_UpdateHistogram(t *testing.T) {
+	t.Parallel()
+	ms := NewMetricsRepo(&config.ServerConfig{})
+	ctx := context.Background()
+
+	h := models.NewHistogram([]float64{1})
+	h.Observe(0.5)
+	m := models.Metric{ID: "latency", MType: "histogram", Histogram: h}
+
+	assert.NoError(t, ms.UpdateHistogram(ctx, m))
+
+	got, err := ms.Get(ctx, models.Metric{ID: "latency"})
+	assert.NoError(t, err)
+	assert.Equal(t, m, got)
+}
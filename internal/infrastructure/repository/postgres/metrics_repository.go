@@ -3,9 +3,11 @@ package postgresrepo
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"sort"
+	"sync"
+	"time"
 
+	"github.com/Chystik/runtime-metrics/config"
 	"github.com/Chystik/runtime-metrics/internal/models"
 	"github.com/Chystik/runtime-metrics/internal/service"
 
@@ -13,55 +15,121 @@ import (
 )
 
 var (
-	ErrNotFoundMetric = errors.New("not found in repository")
+	ErrNotFoundMetric = models.ErrNotFoundMetric
 )
 
+// samplesPruneInterval is how often samples older than the retention are deleted
+const samplesPruneInterval = time.Minute
+
 type pgRepo struct {
 	db *sqlx.DB
 	r  service.ConnectionRetrier
 	l  service.AppLogger
+	// batchRetention is how long IDs of stored batches are remembered
+	batchRetention time.Duration
+	// samplesRetention is how long samples are kept, zero keeps them
+	samplesRetention time.Duration
+	mu               sync.Mutex
+	samplesPrunedAt  time.Time
 }
 
-func NewMetricsRepo(db *sqlx.DB, r service.ConnectionRetrier, logger service.AppLogger) *pgRepo {
+func NewMetricsRepo(cfg *config.ServerConfig, db *sqlx.DB, r service.ConnectionRetrier, logger service.AppLogger) *pgRepo {
+	retention := cfg.BatchRetention.Duration
+	if retention <= 0 {
+		retention = config.DefaultBatchRetention
+	}
+
 	return &pgRepo{
-		db: db,
-		r:  r,
-		l:  logger,
+		db:               db,
+		r:                r,
+		l:                logger,
+		batchRetention:   retention,
+		samplesRetention: cfg.SamplesRetention.Duration,
 	}
 }
 
 func (pg *pgRepo) UpdateGauge(ctx context.Context, metric models.Metric) error {
 	query := `
-			INSERT INTO	praktikum.metrics (id, m_type, m_value)
-			VALUES ($1, $2, $3)
-			ON CONFLICT (id) DO 
-			UPDATE SET 
-				m_value = EXCLUDED.m_value`
+			WITH updated AS (
+				INSERT INTO	praktikum.metrics (id, m_type, m_value, labels)
+				VALUES ($1, $2, $3, $4)
+				ON CONFLICT (id, labels) DO 
+				UPDATE SET 
+					m_value = EXCLUDED.m_value
+				RETURNING id, m_type, m_delta, m_value, labels)
+			INSERT INTO praktikum.samples (id, m_type, m_delta, m_value, labels)
+			SELECT id, m_type, m_delta, m_value, labels FROM updated`
 
 	err := pg.r.DoWithRetry(func() error {
-		_, err := pg.db.ExecContext(ctx, query, metric.ID, metric.MType, metric.Value)
+		_, err := pg.db.ExecContext(ctx, query, metric.ID, metric.MType, metric.Value, metric.Labels)
 		return err
 	})
 	if err != nil {
 		pg.l.Error(err.Error())
 		return err
 	}
+	pg.pruneSamples(ctx)
 
 	return nil
 }
 
 func (pg *pgRepo) UpdateCounter(ctx context.Context, metric models.Metric) error {
 	query := `
-			INSERT INTO	praktikum.metrics (id, m_type, m_delta)
-			VALUES ($1, $2, $3)
-			ON CONFLICT (id) DO 
+			WITH updated AS (
+				INSERT INTO	praktikum.metrics (id, m_type, m_delta, labels)
+				VALUES ($1, $2, $3, $4)
+				ON CONFLICT (id, labels) DO 
+				UPDATE SET 
+					m_delta = praktikum.metrics.m_delta + EXCLUDED.m_delta
+				RETURNING id, m_type, m_delta, m_value, labels)
+			INSERT INTO praktikum.samples (id, m_type, m_delta, m_value, labels)
+			SELECT id, m_type, m_delta, m_value, labels FROM updated`
+
+	err := pg.r.DoWithRetry(func() error {
+		_, err := pg.db.ExecContext(ctx, query, metric.ID, metric.MType, metric.Delta, metric.Labels)
+		return err
+	})
+	if err != nil {
+		pg.l.Error(err.Error())
+		return err
+	}
+	pg.pruneSamples(ctx)
+
+	return nil
+}
+
+// UpdateHistogram replaces the stored histogram, merging is done by the service
+func (pg *pgRepo) UpdateHistogram(ctx context.Context, metric models.Metric) error {
+	query := `
+			INSERT INTO	praktikum.metrics (id, m_type, m_histogram, labels)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (id, labels) DO 
+			UPDATE SET 
+				m_histogram = EXCLUDED.m_histogram`
+
+	err := pg.r.DoWithRetry(func() error {
+		_, err := pg.db.ExecContext(ctx, query, metric.ID, metric.MType, metric.Histogram, metric.Labels)
+		return err
+	})
+	if err != nil {
+		pg.l.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// UpdateSummary replaces the stored summary, merging is done by the service
+func (pg *pgRepo) UpdateSummary(ctx context.Context, metric models.Metric) error {
+	query := `
+			INSERT INTO	praktikum.metrics (id, m_type, m_summary, labels)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (id, labels) DO 
 			UPDATE SET 
-				m_delta = $3 + (SELECT m_delta
-					FROM praktikum.metrics
-					WHERE id = $1)`
+				m_summary = EXCLUDED.m_summary`
 
 	err := pg.r.DoWithRetry(func() error {
-		_, err := pg.db.ExecContext(ctx, query, metric.ID, metric.MType, metric.Delta)
+		_, err := pg.db.ExecContext(ctx, query, metric.ID, metric.MType, metric.Summary, metric.Labels)
 		return err
 	})
 	if err != nil {
@@ -76,17 +144,19 @@ func (pg *pgRepo) Get(ctx context.Context, metric models.Metric) (models.Metric,
 	var m models.Metric
 
 	query := `
-			SELECT id, m_type, m_value, m_delta
+			SELECT id, m_type, m_value, m_delta, m_histogram, m_summary, labels
 			FROM praktikum.metrics
-			WHERE id = $1`
+			WHERE id = $1 AND labels = $2`
 
 	err := pg.r.DoWithRetry(func() error {
-		return pg.db.GetContext(ctx, &m, query, metric.ID)
+		return pg.db.GetContext(ctx, &m, query, metric.ID, metric.Labels)
 	})
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return m, ErrNotFoundMetric
 		}
+		pg.l.Error(err.Error())
+		return m, err
 	}
 
 	return m, nil
@@ -97,7 +167,7 @@ func (pg *pgRepo) GetAll(ctx context.Context) ([]models.Metric, error) {
 	var err error
 
 	query := `
-			SELECT id, m_type, m_value, m_delta
+			SELECT id, m_type, m_value, m_delta, m_histogram, m_summary, labels
 			FROM praktikum.metrics`
 
 	err = pg.r.DoWithRetry(func() error {
@@ -112,11 +182,43 @@ func (pg *pgRepo) GetAll(ctx context.Context) ([]models.Metric, error) {
 	return metrics, nil
 }
 
-func (pg *pgRepo) UpdateList(ctx context.Context, metrics []models.Metric) (err error) {
+func (pg *pgRepo) GetRange(ctx context.Context, metric models.Metric, from, to time.Time) ([]models.Sample, error) {
+	var samples []models.Sample
+	var err error
+
+	query := `
+			SELECT id, m_type, m_value, m_delta, labels, ts
+			FROM praktikum.samples
+			WHERE id = $1 AND labels = $2 AND ts BETWEEN $3 AND $4
+			ORDER BY ts`
+
+	err = pg.r.DoWithRetry(func() error {
+		err = pg.db.SelectContext(ctx, &samples, query, metric.ID, metric.Labels, from, to)
+		return err
+	})
+	if err != nil {
+		pg.l.Error(err.Error())
+		return nil, err
+	}
+
+	return samples, nil
+}
+
+func (pg *pgRepo) UpdateList(ctx context.Context, metrics []models.Metric) error {
+	return pg.updateList(ctx, "", metrics)
+}
+
+// UpdateBatch stores the metrics like UpdateList unless the batch ID was stored within the retention,
+// the ID is recorded in the same transaction as the metrics
+func (pg *pgRepo) UpdateBatch(ctx context.Context, batchID string, metrics []models.Metric) error {
+	return pg.updateList(ctx, batchID, metrics)
+}
+
+func (pg *pgRepo) updateList(ctx context.Context, batchID string, metrics []models.Metric) (err error) {
 	var tx *sql.Tx
 
 	sort.Slice(metrics, func(i, j int) bool { // prevent error on concurrent update: deadlock detected (SQLSTATE 40P01)
-		return metrics[i].ID < metrics[j].ID
+		return metrics[i].Key() < metrics[j].Key()
 	})
 
 	err = pg.r.DoWithRetry(func() error {
@@ -134,15 +236,30 @@ func (pg *pgRepo) UpdateList(ctx context.Context, metrics []models.Metric) (err
 		}
 	}()
 
+	if batchID != "" {
+		stored, err := pg.recordBatch(ctx, tx, batchID)
+		if err != nil {
+			pg.l.Error(err.Error())
+			return err
+		}
+		if !stored {
+			return nil
+		}
+	}
+
 	query := `
-			INSERT INTO	praktikum.metrics (id, m_type, m_value, m_delta)
-			VALUES ($1, $2, $3, $4)
-			ON CONFLICT (id) DO 
-			UPDATE SET 
-				m_value = EXCLUDED.m_value, 
-				m_delta = $4 + (SELECT m_delta
-				FROM praktikum.metrics
-				WHERE id = $1)`
+			WITH updated AS (
+				INSERT INTO	praktikum.metrics (id, m_type, m_value, m_delta, m_histogram, m_summary, labels)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+				ON CONFLICT (id, labels) DO 
+				UPDATE SET 
+					m_value = EXCLUDED.m_value, 
+					m_delta = praktikum.metrics.m_delta + EXCLUDED.m_delta,
+					m_histogram = EXCLUDED.m_histogram,
+					m_summary = EXCLUDED.m_summary
+				RETURNING id, m_type, m_delta, m_value, labels)
+			INSERT INTO praktikum.samples (id, m_type, m_delta, m_value, labels)
+			SELECT id, m_type, m_delta, m_value, labels FROM updated`
 
 	stmt, err := tx.PrepareContext(ctx, query)
 	if err != nil {
@@ -156,6 +273,9 @@ func (pg *pgRepo) UpdateList(ctx context.Context, metrics []models.Metric) (err
 			m.MType,
 			m.Value,
 			m.Delta,
+			m.Histogram,
+			m.Summary,
+			m.Labels,
 		)
 		if err != nil {
 			pg.l.Error(err.Error())
@@ -168,6 +288,52 @@ func (pg *pgRepo) UpdateList(ctx context.Context, metrics []models.Metric) (err
 		pg.l.Error(err.Error())
 		return err
 	}
+	pg.pruneSamples(ctx)
 
 	return nil
 }
+
+// pruneSamples deletes samples older than the retention at most once per samplesPruneInterval,
+// an error is logged as the update is already stored
+func (pg *pgRepo) pruneSamples(ctx context.Context) {
+	if pg.samplesRetention <= 0 {
+		return
+	}
+
+	now := time.Now()
+	pg.mu.Lock()
+	if now.Sub(pg.samplesPrunedAt) < samplesPruneInterval {
+		pg.mu.Unlock()
+		return
+	}
+	pg.samplesPrunedAt = now
+	pg.mu.Unlock()
+
+	err := pg.r.DoWithRetry(func() error {
+		_, err := pg.db.ExecContext(ctx, `DELETE FROM praktikum.samples WHERE ts < $1`, now.Add(-pg.samplesRetention))
+		return err
+	})
+	if err != nil {
+		pg.l.Error(err.Error())
+	}
+}
+
+// recordBatch drops expired batch IDs and records the new one, false if it is already recorded
+func (pg *pgRepo) recordBatch(ctx context.Context, tx *sql.Tx, batchID string) (bool, error) {
+	_, err := tx.ExecContext(ctx, `DELETE FROM praktikum.batches WHERE created_at < $1`, time.Now().Add(-pg.batchRetention))
+	if err != nil {
+		return false, err
+	}
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO praktikum.batches (id) VALUES ($1) ON CONFLICT (id) DO NOTHING`, batchID)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
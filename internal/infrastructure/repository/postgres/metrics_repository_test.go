@@ -11,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Chystik/runtime-metrics/config"
 	"github.com/Chystik/runtime-metrics/internal/models"
 	"github.com/Chystik/runtime-metrics/internal/service"
 	"github.com/Chystik/runtime-metrics/internal/service/mocks"
@@ -49,17 +50,21 @@ func Test_UpdateGauge(t *testing.T) {
 	conRetMock := newConRetryer()
 	logMock := &mocks.Logger{}
 
-	pgRepo := NewMetricsRepo(sqlxDB, conRetMock, logMock)
+	pgRepo := NewMetricsRepo(&config.ServerConfig{}, sqlxDB, conRetMock, logMock)
 
 	m := models.Metric{ID: "test", MType: "gauge", Value: new(float64)}
 	query := regexp.QuoteMeta(`
-			INSERT INTO	praktikum.metrics (id, m_type, m_value)
-			VALUES ($1, $2, $3)
-			ON CONFLICT (id) DO 
-			UPDATE SET 
-				m_value = EXCLUDED.m_value`)
-
-	mockSQL.ExpectExec(query).WithArgs(m.ID, m.MType, m.Value).WillReturnResult(sqlmock.NewResult(0, 0))
+			WITH updated AS (
+				INSERT INTO	praktikum.metrics (id, m_type, m_value, labels)
+				VALUES ($1, $2, $3, $4)
+				ON CONFLICT (id, labels) DO
+				UPDATE SET
+					m_value = EXCLUDED.m_value
+				RETURNING id, m_type, m_delta, m_value, labels)
+			INSERT INTO praktikum.samples (id, m_type, m_delta, m_value, labels)
+			SELECT id, m_type, m_delta, m_value, labels FROM updated`)
+
+	mockSQL.ExpectExec(query).WithArgs(m.ID, m.MType, m.Value, m.Labels).WillReturnResult(sqlmock.NewResult(0, 0))
 	err := conRetMock.DoWithRetry(func() error {
 		return nil
 	})
@@ -81,7 +86,7 @@ func Test_UpdateGauge_WhenRetryerReturnsError(t *testing.T) {
 	conRetMock := newConRetryer()
 	logMock := &mocks.Logger{}
 
-	pgRepo := NewMetricsRepo(sqlxDB, conRetMock, logMock)
+	pgRepo := NewMetricsRepo(&config.ServerConfig{}, sqlxDB, conRetMock, logMock)
 
 	err := conRetMock.DoWithRetry(func() error {
 		return errors.New("some err")
@@ -104,19 +109,21 @@ func Test_UpdateCounter(t *testing.T) {
 	conRetMock := newConRetryer()
 	logMock := &mocks.Logger{}
 
-	pgRepo := NewMetricsRepo(sqlxDB, conRetMock, logMock)
+	pgRepo := NewMetricsRepo(&config.ServerConfig{}, sqlxDB, conRetMock, logMock)
 
 	m := models.Metric{ID: "test", MType: "gauge", Delta: new(int64)}
 	query := regexp.QuoteMeta(`
-			INSERT INTO	praktikum.metrics (id, m_type, m_delta)
-			VALUES ($1, $2, $3)
-			ON CONFLICT (id) DO
-			UPDATE SET
-				m_delta = $3 + (SELECT m_delta
-					FROM praktikum.metrics
-					WHERE id = $1)`)
-
-	mockSQL.ExpectExec(query).WithArgs(m.ID, m.MType, m.Delta).WillReturnResult(sqlmock.NewResult(1, 1))
+			WITH updated AS (
+				INSERT INTO	praktikum.metrics (id, m_type, m_delta, labels)
+				VALUES ($1, $2, $3, $4)
+				ON CONFLICT (id, labels) DO
+				UPDATE SET
+					m_delta = praktikum.metrics.m_delta + EXCLUDED.m_delta
+				RETURNING id, m_type, m_delta, m_value, labels)
+			INSERT INTO praktikum.samples (id, m_type, m_delta, m_value, labels)
+			SELECT id, m_type, m_delta, m_value, labels FROM updated`)
+
+	mockSQL.ExpectExec(query).WithArgs(m.ID, m.MType, m.Delta, m.Labels).WillReturnResult(sqlmock.NewResult(1, 1))
 	err := conRetMock.DoWithRetry(func() error {
 		return nil
 	})
@@ -138,7 +145,7 @@ func Test_UpdateCounter_WhenRetryerReturnsError(t *testing.T) {
 	conRetMock := newConRetryer()
 	logMock := &mocks.Logger{}
 
-	pgRepo := NewMetricsRepo(sqlxDB, conRetMock, logMock)
+	pgRepo := NewMetricsRepo(&config.ServerConfig{}, sqlxDB, conRetMock, logMock)
 
 	err := conRetMock.DoWithRetry(func() error {
 		return errors.New("some err")
@@ -152,6 +159,69 @@ func Test_UpdateCounter_WhenRetryerReturnsError(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func Test_UpdateHistogram(t *testing.T) {
+	t.Parallel()
+
+	sqlxDB, mockSQL := newSqlxDB(t)
+	defer sqlxDB.Close()
+
+	conRetMock := newConRetryer()
+	logMock := &mocks.Logger{}
+
+	pgRepo := NewMetricsRepo(&config.ServerConfig{}, sqlxDB, conRetMock, logMock)
+
+	m := models.Metric{ID: "test", MType: "histogram", Histogram: models.NewHistogram([]float64{1, 2})}
+	m.Histogram.Observe(1.5)
+	query := regexp.QuoteMeta(`
+			INSERT INTO	praktikum.metrics (id, m_type, m_histogram, labels)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (id, labels) DO
+			UPDATE SET
+				m_histogram = EXCLUDED.m_histogram`)
+
+	mockSQL.ExpectExec(query).
+		WithArgs(m.ID, m.MType, `{"buckets":[{"le":1,"count":0},{"le":2,"count":1}],"sum":1.5,"count":1}`, m.Labels).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	logMock.EXPECT().Error(mock.Anything).Return()
+
+	err := pgRepo.UpdateHistogram(context.Background(), m)
+
+	assert.NoError(t, mockSQL.ExpectationsWereMet())
+	assert.NoError(t, err)
+}
+
+func Test_UpdateSummary(t *testing.T) {
+	t.Parallel()
+
+	sqlxDB, mockSQL := newSqlxDB(t)
+	defer sqlxDB.Close()
+
+	conRetMock := newConRetryer()
+	logMock := &mocks.Logger{}
+
+	pgRepo := NewMetricsRepo(&config.ServerConfig{}, sqlxDB, conRetMock, logMock)
+
+	m := models.Metric{ID: "test", MType: "summary", Summary: models.NewSummary()}
+	m.Summary.Observe(1)
+	m.Summary.FillQuantiles(models.DefaultQuantiles)
+	query := regexp.QuoteMeta(`
+			INSERT INTO	praktikum.metrics (id, m_type, m_summary, labels)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (id, labels) DO
+			UPDATE SET
+				m_summary = EXCLUDED.m_summary`)
+
+	mockSQL.ExpectExec(query).
+		WithArgs(m.ID, m.MType, `{"sketch":{"relative_accuracy":0.01,"positive":{"0":1},"count":1,"sum":1,"min":1,"max":1}}`, m.Labels).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	logMock.EXPECT().Error(mock.Anything).Return()
+
+	err := pgRepo.UpdateSummary(context.Background(), m)
+
+	assert.NoError(t, mockSQL.ExpectationsWereMet())
+	assert.NoError(t, err)
+}
+
 func Test_Get(t *testing.T) {
 	t.Parallel()
 
@@ -161,16 +231,16 @@ func Test_Get(t *testing.T) {
 	conRetMock := newConRetryer()
 	logMock := &mocks.Logger{}
 
-	pgRepo := NewMetricsRepo(sqlxDB, conRetMock, logMock)
+	pgRepo := NewMetricsRepo(&config.ServerConfig{}, sqlxDB, conRetMock, logMock)
 
 	m := generateMetric("test", "counter")
 	query := regexp.QuoteMeta(`
-			SELECT id, m_type, m_value, m_delta
+			SELECT id, m_type, m_value, m_delta, m_histogram, m_summary, labels
 			FROM praktikum.metrics
-			WHERE id = $1`)
+			WHERE id = $1 AND labels = $2`)
 
 	err := conRetMock.DoWithRetry(func() error {
-		var v, d string
+		var v, d interface{}
 
 		if m.Value != nil {
 			v = strconv.Itoa(int(*m.Value))
@@ -179,7 +249,7 @@ func Test_Get(t *testing.T) {
 			d = strconv.Itoa(int(*m.Delta))
 		}
 
-		mockSQL.ExpectQuery(query).WithArgs(m.ID).WillReturnRows(
+		mockSQL.ExpectQuery(query).WithArgs(m.ID, m.Labels).WillReturnRows(
 			sqlmock.NewRows([]string{
 				"id",
 				"m_type",
@@ -212,7 +282,7 @@ func Test_Get_WhenRetryerReturnsError(t *testing.T) {
 	conRetMock := &mocks.ConnectionRetrier{}
 	logMock := &mocks.Logger{}
 
-	pgRepo := NewMetricsRepo(sqlxDB, conRetMock, logMock)
+	pgRepo := NewMetricsRepo(&config.ServerConfig{}, sqlxDB, conRetMock, logMock)
 
 	conRetMock.EXPECT().DoWithRetry(mock.Anything).Return(sql.ErrNoRows)
 	logMock.EXPECT().Error(mock.Anything).Return()
@@ -231,10 +301,10 @@ func Test_GetAll(t *testing.T) {
 	conRetMock := newConRetryer()
 	logMock := &mocks.Logger{}
 
-	pgRepo := NewMetricsRepo(sqlxDB, conRetMock, logMock)
+	pgRepo := NewMetricsRepo(&config.ServerConfig{}, sqlxDB, conRetMock, logMock)
 
 	query := regexp.QuoteMeta(`
-			SELECT id, m_type, m_value, m_delta
+			SELECT id, m_type, m_value, m_delta, m_histogram, m_summary, labels
 			FROM praktikum.metrics`)
 
 	mockSQL.ExpectQuery(query).WithArgs().WillReturnRows(sqlmock.NewRows([]string{"id", "m_type"}).AddRow(1, 1))
@@ -259,7 +329,7 @@ func Test_GetAll_WhenRetryerReturnsError(t *testing.T) {
 	conRetMock := &mocks.ConnectionRetrier{}
 	logMock := &mocks.Logger{}
 
-	pgRepo := NewMetricsRepo(sqlxDB, conRetMock, logMock)
+	pgRepo := NewMetricsRepo(&config.ServerConfig{}, sqlxDB, conRetMock, logMock)
 
 	conRetMock.EXPECT().DoWithRetry(mock.Anything).Return(sql.ErrNoRows)
 	logMock.EXPECT().Error(mock.Anything).Return()
@@ -269,6 +339,61 @@ func Test_GetAll_WhenRetryerReturnsError(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func Test_GetRange(t *testing.T) {
+	t.Parallel()
+
+	sqlxDB, mockSQL := newSqlxDB(t)
+	defer sqlxDB.Close()
+
+	conRetMock := newConRetryer()
+	logMock := &mocks.Logger{}
+
+	pgRepo := NewMetricsRepo(&config.ServerConfig{}, sqlxDB, conRetMock, logMock)
+
+	m := generateMetric("gauge", "test")
+	m.Labels = models.Labels{"host": "a"}
+	to := time.Now()
+	from := to.Add(-time.Minute)
+	query := regexp.QuoteMeta(`
+			SELECT id, m_type, m_value, m_delta, labels, ts
+			FROM praktikum.samples
+			WHERE id = $1 AND labels = $2 AND ts BETWEEN $3 AND $4
+			ORDER BY ts`)
+
+	mockSQL.ExpectQuery(query).WithArgs(m.ID, m.Labels, from, to).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "m_type", "m_value", "m_delta", "labels", "ts"}).
+			AddRow(m.ID, m.MType, *m.Value, nil, `{"host":"a"}`, from).
+			AddRow(m.ID, m.MType, *m.Value, nil, `{"host":"a"}`, to))
+
+	samples, err := pgRepo.GetRange(context.Background(), m, from, to)
+
+	assert.NoError(t, mockSQL.ExpectationsWereMet())
+	assert.NoError(t, err)
+	assert.Len(t, samples, 2)
+	assert.Equal(t, from, samples[0].Timestamp)
+	assert.Equal(t, *m.Value, *samples[1].Value)
+	assert.Equal(t, m.Labels, samples[0].Labels)
+}
+
+func Test_GetRange_WhenRetryerReturnsError(t *testing.T) {
+	t.Parallel()
+
+	sqlxDB, _ := newSqlxDB(t)
+	defer sqlxDB.Close()
+
+	conRetMock := &mocks.ConnectionRetrier{}
+	logMock := &mocks.Logger{}
+
+	pgRepo := NewMetricsRepo(&config.ServerConfig{}, sqlxDB, conRetMock, logMock)
+
+	conRetMock.EXPECT().DoWithRetry(mock.Anything).Return(errors.New("some err"))
+	logMock.EXPECT().Error(mock.Anything).Return()
+
+	_, err := pgRepo.GetRange(context.Background(), models.Metric{}, time.Now(), time.Now())
+
+	assert.Error(t, err)
+}
+
 func Test_UpdateList(t *testing.T) {
 	t.Parallel()
 
@@ -278,18 +403,22 @@ func Test_UpdateList(t *testing.T) {
 	conRetMock := newConRetryer()
 	logMock := &mocks.Logger{}
 
-	pgRepo := NewMetricsRepo(sqlxDB, conRetMock, logMock)
+	pgRepo := NewMetricsRepo(&config.ServerConfig{}, sqlxDB, conRetMock, logMock)
 
 	metrics := generateMetrics(10)
 	query := regexp.QuoteMeta(`
-		INSERT INTO	praktikum.metrics (id, m_type, m_value, m_delta)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (id) DO
-		UPDATE SET
-			m_value = EXCLUDED.m_value,
-			m_delta = $4 + (SELECT m_delta
-			FROM praktikum.metrics
-			WHERE id = $1)`)
+		WITH updated AS (
+			INSERT INTO	praktikum.metrics (id, m_type, m_value, m_delta, m_histogram, m_summary, labels)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (id, labels) DO
+			UPDATE SET
+				m_value = EXCLUDED.m_value,
+				m_delta = praktikum.metrics.m_delta + EXCLUDED.m_delta,
+				m_histogram = EXCLUDED.m_histogram,
+				m_summary = EXCLUDED.m_summary
+			RETURNING id, m_type, m_delta, m_value, labels)
+		INSERT INTO praktikum.samples (id, m_type, m_delta, m_value, labels)
+		SELECT id, m_type, m_delta, m_value, labels FROM updated`)
 
 	err := conRetMock.DoWithRetry(func() error {
 		mockSQL.ExpectBegin()
@@ -304,6 +433,9 @@ func Test_UpdateList(t *testing.T) {
 			m.MType,
 			m.Value,
 			m.Delta,
+			m.Histogram,
+			m.Summary,
+			m.Labels,
 		).WillReturnResult(sqlmock.NewResult(1, 1))
 	}
 
@@ -316,6 +448,58 @@ func Test_UpdateList(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func Test_UpdateBatch(t *testing.T) {
+	t.Parallel()
+
+	sqlxDB, mockSQL := newSqlxDB(t)
+	defer sqlxDB.Close()
+
+	pgRepo := NewMetricsRepo(&config.ServerConfig{}, sqlxDB, newConRetryer(), &mocks.Logger{})
+
+	m := models.Metric{ID: "test", MType: "counter", Delta: new(int64)}
+
+	mockSQL.ExpectBegin()
+	mockSQL.ExpectExec(regexp.QuoteMeta(`DELETE FROM praktikum.batches WHERE created_at < $1`)).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mockSQL.ExpectExec(regexp.QuoteMeta(`INSERT INTO praktikum.batches (id) VALUES ($1) ON CONFLICT (id) DO NOTHING`)).
+		WithArgs("batch-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mockSQL.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO	praktikum.metrics`)).
+		ExpectExec().
+		WithArgs(m.ID, m.MType, m.Value, m.Delta, m.Histogram, m.Summary, m.Labels).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mockSQL.ExpectCommit()
+
+	err := pgRepo.UpdateBatch(context.Background(), "batch-1", []models.Metric{m})
+
+	assert.NoError(t, mockSQL.ExpectationsWereMet())
+	assert.NoError(t, err)
+}
+
+func Test_UpdateBatch_WhenBatchIsStored(t *testing.T) {
+	t.Parallel()
+
+	sqlxDB, mockSQL := newSqlxDB(t)
+	defer sqlxDB.Close()
+
+	pgRepo := NewMetricsRepo(&config.ServerConfig{}, sqlxDB, newConRetryer(), &mocks.Logger{})
+
+	mockSQL.ExpectBegin()
+	mockSQL.ExpectExec(regexp.QuoteMeta(`DELETE FROM praktikum.batches`)).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mockSQL.ExpectExec(regexp.QuoteMeta(`INSERT INTO praktikum.batches`)).
+		WithArgs("batch-1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mockSQL.ExpectRollback()
+
+	err := pgRepo.UpdateBatch(context.Background(), "batch-1", generateMetrics(3))
+
+	assert.NoError(t, mockSQL.ExpectationsWereMet())
+	assert.NoError(t, err)
+}
+
 func Test_UpdateList_WhenBeginReturnsError(t *testing.T) {
 	t.Parallel()
 
@@ -325,7 +509,7 @@ func Test_UpdateList_WhenBeginReturnsError(t *testing.T) {
 	conRetMock := newConRetryer()
 	logMock := &mocks.Logger{}
 
-	pgRepo := NewMetricsRepo(sqlxDB, conRetMock, logMock)
+	pgRepo := NewMetricsRepo(&config.ServerConfig{}, sqlxDB, conRetMock, logMock)
 	expErr := errors.New("tx begin error")
 
 	err := conRetMock.DoWithRetry(func() error {
@@ -351,19 +535,23 @@ func Test_UpdateList_WhenPrepareContextReturnsError(t *testing.T) {
 	conRetMock := newConRetryer()
 	logMock := &mocks.Logger{}
 
-	pgRepo := NewMetricsRepo(sqlxDB, conRetMock, logMock)
+	pgRepo := NewMetricsRepo(&config.ServerConfig{}, sqlxDB, conRetMock, logMock)
 	expErr := errors.New("prepare context error")
 
 	metrics := generateMetrics(10)
 	query := regexp.QuoteMeta(`
-		INSERT INTO	praktikum.metrics (id, m_type, m_value, m_delta)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (id) DO
-		UPDATE SET
-			m_value = EXCLUDED.m_value,
-			m_delta = $4 + (SELECT m_delta
-			FROM praktikum.metrics
-			WHERE id = $1)`)
+		WITH updated AS (
+			INSERT INTO	praktikum.metrics (id, m_type, m_value, m_delta, m_histogram, m_summary, labels)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (id, labels) DO
+			UPDATE SET
+				m_value = EXCLUDED.m_value,
+				m_delta = praktikum.metrics.m_delta + EXCLUDED.m_delta,
+				m_histogram = EXCLUDED.m_histogram,
+				m_summary = EXCLUDED.m_summary
+			RETURNING id, m_type, m_delta, m_value, labels)
+		INSERT INTO praktikum.samples (id, m_type, m_delta, m_value, labels)
+		SELECT id, m_type, m_delta, m_value, labels FROM updated`)
 
 	err := conRetMock.DoWithRetry(func() error {
 		mockSQL.ExpectBegin()
@@ -391,19 +579,23 @@ func Test_UpdateList_WhenExecContextReturnsError(t *testing.T) {
 	conRetMock := newConRetryer()
 	logMock := &mocks.Logger{}
 
-	pgRepo := NewMetricsRepo(sqlxDB, conRetMock, logMock)
+	pgRepo := NewMetricsRepo(&config.ServerConfig{}, sqlxDB, conRetMock, logMock)
 	expErr := errors.New("exec context error")
 
 	metrics := generateMetrics(10)
 	query := regexp.QuoteMeta(`
-		INSERT INTO	praktikum.metrics (id, m_type, m_value, m_delta)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (id) DO
-		UPDATE SET
-			m_value = EXCLUDED.m_value,
-			m_delta = $4 + (SELECT m_delta
-			FROM praktikum.metrics
-			WHERE id = $1)`)
+		WITH updated AS (
+			INSERT INTO	praktikum.metrics (id, m_type, m_value, m_delta, m_histogram, m_summary, labels)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (id, labels) DO
+			UPDATE SET
+				m_value = EXCLUDED.m_value,
+				m_delta = praktikum.metrics.m_delta + EXCLUDED.m_delta,
+				m_histogram = EXCLUDED.m_histogram,
+				m_summary = EXCLUDED.m_summary
+			RETURNING id, m_type, m_delta, m_value, labels)
+		INSERT INTO praktikum.samples (id, m_type, m_delta, m_value, labels)
+		SELECT id, m_type, m_delta, m_value, labels FROM updated`)
 
 	err := conRetMock.DoWithRetry(func() error {
 		mockSQL.ExpectBegin()
@@ -417,6 +609,9 @@ func Test_UpdateList_WhenExecContextReturnsError(t *testing.T) {
 		metrics[0].MType,
 		metrics[0].Value,
 		metrics[0].Delta,
+		metrics[0].Histogram,
+		metrics[0].Summary,
+		metrics[0].Labels,
 	).WillReturnError(expErr)
 
 	logMock.EXPECT().Error(mock.Anything).Return()
@@ -438,19 +633,23 @@ func Test_UpdateList_WhenCommitReturnsError(t *testing.T) {
 	conRetMock := newConRetryer()
 	logMock := &mocks.Logger{}
 
-	pgRepo := NewMetricsRepo(sqlxDB, conRetMock, logMock)
+	pgRepo := NewMetricsRepo(&config.ServerConfig{}, sqlxDB, conRetMock, logMock)
 	expErr := errors.New("commit error")
 
 	metrics := generateMetrics(10)
 	query := regexp.QuoteMeta(`
-		INSERT INTO	praktikum.metrics (id, m_type, m_value, m_delta)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (id) DO
-		UPDATE SET
-			m_value = EXCLUDED.m_value,
-			m_delta = $4 + (SELECT m_delta
-			FROM praktikum.metrics
-			WHERE id = $1)`)
+		WITH updated AS (
+			INSERT INTO	praktikum.metrics (id, m_type, m_value, m_delta, m_histogram, m_summary, labels)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (id, labels) DO
+			UPDATE SET
+				m_value = EXCLUDED.m_value,
+				m_delta = praktikum.metrics.m_delta + EXCLUDED.m_delta,
+				m_histogram = EXCLUDED.m_histogram,
+				m_summary = EXCLUDED.m_summary
+			RETURNING id, m_type, m_delta, m_value, labels)
+		INSERT INTO praktikum.samples (id, m_type, m_delta, m_value, labels)
+		SELECT id, m_type, m_delta, m_value, labels FROM updated`)
 
 	err := conRetMock.DoWithRetry(func() error {
 		mockSQL.ExpectBegin()
@@ -465,6 +664,9 @@ func Test_UpdateList_WhenCommitReturnsError(t *testing.T) {
 			m.MType,
 			m.Value,
 			m.Delta,
+			m.Histogram,
+			m.Summary,
+			m.Labels,
 		).WillReturnResult(sqlmock.NewResult(1, 1))
 	}
 
@@ -487,19 +689,23 @@ func Test_UpdateList_WhenRollBackReturnsError(t *testing.T) {
 	conRetMock := newConRetryer()
 	logMock := &mocks.Logger{}
 
-	pgRepo := NewMetricsRepo(sqlxDB, conRetMock, logMock)
+	pgRepo := NewMetricsRepo(&config.ServerConfig{}, sqlxDB, conRetMock, logMock)
 	expErr := errors.New("rollback error")
 
 	metrics := generateMetrics(10)
 	query := regexp.QuoteMeta(`
-		INSERT INTO	praktikum.metrics (id, m_type, m_value, m_delta)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (id) DO
-		UPDATE SET
-			m_value = EXCLUDED.m_value,
-			m_delta = $4 + (SELECT m_delta
-			FROM praktikum.metrics
-			WHERE id = $1)`)
+		WITH updated AS (
+			INSERT INTO	praktikum.metrics (id, m_type, m_value, m_delta, m_histogram, m_summary, labels)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (id, labels) DO
+			UPDATE SET
+				m_value = EXCLUDED.m_value,
+				m_delta = praktikum.metrics.m_delta + EXCLUDED.m_delta,
+				m_histogram = EXCLUDED.m_histogram,
+				m_summary = EXCLUDED.m_summary
+			RETURNING id, m_type, m_delta, m_value, labels)
+		INSERT INTO praktikum.samples (id, m_type, m_delta, m_value, labels)
+		SELECT id, m_type, m_delta, m_value, labels FROM updated`)
 
 	err := conRetMock.DoWithRetry(func() error {
 		mockSQL.ExpectBegin()
@@ -552,3 +758,43 @@ func generateMetrics(count int) []models.Metric {
 
 	return m
 }
+
+func Test_UpdateGauge_PrunesSamples(t *testing.T) {
+	t.Parallel()
+
+	sqlxDB, mockSQL := newSqlxDB(t)
+	defer sqlxDB.Close()
+
+	cfg := &config.ServerConfig{SamplesRetention: config.Duration{Duration: time.Hour}}
+	pgRepo := NewMetricsRepo(cfg, sqlxDB, newConRetryer(), &mocks.Logger{})
+
+	m := models.Metric{ID: "test", MType: "gauge", Value: new(float64)}
+	prune := regexp.QuoteMeta(`DELETE FROM praktikum.samples WHERE ts < $1`)
+
+	mockSQL.ExpectExec(regexp.QuoteMeta(`INSERT INTO	praktikum.metrics`)).
+		WithArgs(m.ID, m.MType, m.Value, m.Labels).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mockSQL.ExpectExec(prune).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	// samples are not pruned again within the prune interval
+	mockSQL.ExpectExec(regexp.QuoteMeta(`INSERT INTO	praktikum.metrics`)).
+		WithArgs(m.ID, m.MType, m.Value, m.Labels).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, pgRepo.UpdateGauge(context.Background(), m))
+	assert.NoError(t, pgRepo.UpdateGauge(context.Background(), m))
+	assert.NoError(t, mockSQL.ExpectationsWereMet())
+
+	// the next prune is due after the interval
+	pgRepo.samplesPrunedAt = time.Now().Add(-samplesPruneInterval)
+	mockSQL.ExpectExec(regexp.QuoteMeta(`INSERT INTO	praktikum.metrics`)).
+		WithArgs(m.ID, m.MType, m.Value, m.Labels).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mockSQL.ExpectExec(prune).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, pgRepo.UpdateGauge(context.Background(), m))
+	assert.NoError(t, mockSQL.ExpectationsWereMet())
+}
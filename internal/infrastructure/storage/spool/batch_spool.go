@@ -0,0 +1,305 @@
+package spool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/config"
+	"github.com/Chystik/runtime-metrics/internal/models"
+	"github.com/Chystik/runtime-metrics/internal/service"
+
+	"go.uber.org/zap"
+)
+
+const (
+	batchExt = ".json"
+	tmpExt   = ".tmp"
+)
+
+var ErrInvalidBatchID = errors.New("invalid batch id")
+
+// entry is a spooled batch file named seq-created-id.json, where seq and the
+// creation time in unix nanoseconds are hex numbers
+type entry struct {
+	seq     uint64
+	created time.Time
+	id      string
+	size    int64
+}
+
+func (e entry) name() string {
+	return fmt.Sprintf("%016x-%x-%s%s", e.seq, e.created.UnixNano(), e.id, batchExt)
+}
+
+// batchSpool keeps batches in files of a directory, so they survive agent restarts.
+// The oldest batches are dropped when the spool exceeds its size or age limits.
+type batchSpool struct {
+	mu      sync.Mutex
+	dir     string
+	maxSize int64
+	maxAge  time.Duration
+	// entries are ordered from the oldest
+	entries []entry
+	size    int64
+	seq     uint64
+	logger  service.AppLogger
+	now     func() time.Time
+}
+
+// New opens the spool directory of the config creating it if needed, batches
+// left by a previous run are kept
+func New(cfg *config.AgentConfig, logger service.AppLogger) (*batchSpool, error) {
+	if cfg.SpoolDir == "" {
+		return nil, fmt.Errorf("spool dir not specified in agent config: %v", cfg)
+	}
+
+	err := os.MkdirAll(cfg.SpoolDir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &batchSpool{
+		dir:     cfg.SpoolDir,
+		maxSize: cfg.SpoolMaxSize,
+		maxAge:  cfg.SpoolMaxAge.Duration,
+		logger:  logger,
+		now:     time.Now,
+	}
+
+	err = s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.prune()
+	s.mu.Unlock()
+
+	return s, nil
+}
+
+// load reads spooled batch names and sizes, files of interrupted writes are removed
+func (s *batchSpool) load() error {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+
+		name := f.Name()
+		if strings.HasSuffix(name, tmpExt) {
+			if err = os.Remove(filepath.Join(s.dir, name)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		e, ok := parseName(name)
+		if !ok {
+			continue
+		}
+
+		info, err := f.Info()
+		if err != nil {
+			return err
+		}
+		e.size = info.Size()
+
+		s.entries = append(s.entries, e)
+		s.size += e.size
+	}
+
+	sort.Slice(s.entries, func(i, j int) bool {
+		return s.entries[i].seq < s.entries[j].seq
+	})
+	if n := len(s.entries); n > 0 {
+		s.seq = s.entries[n-1].seq
+	}
+
+	return nil
+}
+
+func parseName(name string) (entry, bool) {
+	base, ok := strings.CutSuffix(name, batchExt)
+	if !ok {
+		return entry{}, false
+	}
+
+	parts := strings.SplitN(base, "-", 3)
+	if len(parts) != 3 || parts[2] == "" {
+		return entry{}, false
+	}
+
+	seq, err := strconv.ParseUint(parts[0], 16, 64)
+	if err != nil {
+		return entry{}, false
+	}
+	created, err := strconv.ParseInt(parts[1], 16, 64)
+	if err != nil {
+		return entry{}, false
+	}
+
+	return entry{seq: seq, created: time.Unix(0, created), id: parts[2]}, true
+}
+
+// Push writes the batch to a new file, the file is synced before it becomes visible
+func (s *batchSpool) Push(batchID string, metrics map[string]models.Metric) error {
+	if batchID == "" || strings.ContainsAny(batchID, `/\.`) {
+		return fmt.Errorf("%w: %q", ErrInvalidBatchID, batchID)
+	}
+
+	data, err := json.Marshal(metrics)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := entry{
+		seq:     s.seq + 1,
+		created: s.now(),
+		id:      batchID,
+		size:    int64(len(data)),
+	}
+
+	path := filepath.Join(s.dir, e.name())
+	err = writeFile(path+tmpExt, data)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(path+tmpExt, path)
+	if err != nil {
+		return err
+	}
+
+	s.seq = e.seq
+	s.entries = append(s.entries, e)
+	s.size += e.size
+	s.prune()
+
+	return nil
+}
+
+func writeFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+
+	return err
+}
+
+// Oldest returns the oldest batch within the age limit, unreadable batches are dropped
+func (s *batchSpool) Oldest() (string, map[string]models.Metric, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune()
+
+	for len(s.entries) > 0 {
+		e := s.entries[0]
+
+		data, err := os.ReadFile(filepath.Join(s.dir, e.name()))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", nil, false, err
+		}
+
+		var metrics map[string]models.Metric
+		if err == nil {
+			err = json.Unmarshal(data, &metrics)
+		}
+		if err == nil {
+			return e.id, metrics, true, nil
+		}
+
+		s.drop(err.Error())
+	}
+
+	return "", nil, false, nil
+}
+
+// Remove deletes the acknowledged batch
+func (s *batchSpool) Remove(batchID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, e := range s.entries {
+		if e.id != batchID {
+			continue
+		}
+
+		err := os.Remove(filepath.Join(s.dir, e.name()))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		s.entries = append(s.entries[:i], s.entries[i+1:]...)
+		s.size -= e.size
+		return nil
+	}
+
+	return nil
+}
+
+// Len returns the number of spooled batches
+func (s *batchSpool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.entries)
+}
+
+// prune drops the oldest batches exceeding the age or size limits, zero limits are
+// not applied, the caller must hold the lock
+func (s *batchSpool) prune() {
+	now := s.now()
+
+	for len(s.entries) > 0 {
+		switch {
+		case s.maxAge > 0 && now.Sub(s.entries[0].created) > s.maxAge:
+			s.drop("max age exceeded")
+		case s.maxSize > 0 && s.size > s.maxSize:
+			s.drop("max size exceeded")
+		default:
+			return
+		}
+	}
+}
+
+// drop deletes the oldest batch, the caller must hold the lock
+func (s *batchSpool) drop(reason string) {
+	e := s.entries[0]
+	s.entries = s.entries[1:]
+	s.size -= e.size
+
+	err := os.Remove(filepath.Join(s.dir, e.name()))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		s.logger.Error(err.Error())
+	}
+
+	s.logger.Info("spooled batch dropped", zap.String("batch", e.id), zap.String("reason", reason))
+}
@@ -0,0 +1,156 @@
+package spool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Chystik/runtime-metrics/config"
+	"github.com/Chystik/runtime-metrics/internal/models"
+	"github.com/Chystik/runtime-metrics/internal/service/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_WhenDirIsEmpty(t *testing.T) {
+	_, err := New(&config.AgentConfig{}, &mocks.Logger{})
+
+	assert.Error(t, err)
+}
+
+func Test_batchSpool_PushOldestRemove(t *testing.T) {
+	s, err := New(&config.AgentConfig{SpoolDir: t.TempDir()}, &mocks.Logger{})
+	require.NoError(t, err)
+
+	_, _, ok, err := s.Oldest()
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, s.Push("a", batch("PollCount", 1)))
+	require.NoError(t, s.Push("b", batch("PollCount", 2)))
+	assert.Equal(t, 2, s.Len())
+
+	id, metrics, ok, err := s.Oldest()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "a", id)
+	assert.Equal(t, int64(1), *metrics["PollCount"].Delta)
+
+	require.NoError(t, s.Remove("a"))
+
+	id, _, ok, err = s.Oldest()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "b", id)
+	assert.Equal(t, 1, s.Len())
+}
+
+func Test_batchSpool_Push_WhenBatchIDIsInvalid(t *testing.T) {
+	s, err := New(&config.AgentConfig{SpoolDir: t.TempDir()}, &mocks.Logger{})
+	require.NoError(t, err)
+
+	err = s.Push("../a", batch("PollCount", 1))
+
+	assert.ErrorIs(t, err, ErrInvalidBatchID)
+	assert.Equal(t, 0, s.Len())
+}
+
+func TestNew_KeepsBatchesOfPreviousRun(t *testing.T) {
+	cfg := &config.AgentConfig{SpoolDir: t.TempDir()}
+
+	s, err := New(cfg, &mocks.Logger{})
+	require.NoError(t, err)
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, s.Push(id, batch("PollCount", 1)))
+	}
+	// a write interrupted by a crash
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.SpoolDir, "x.json.tmp"), []byte("{"), 0600))
+
+	s, err = New(cfg, &mocks.Logger{})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, s.Len())
+	require.NoError(t, s.Push("d", batch("PollCount", 1)))
+
+	var ids []string
+	for s.Len() > 0 {
+		id, _, ok, err := s.Oldest()
+		require.NoError(t, err)
+		require.True(t, ok)
+		ids = append(ids, id)
+		require.NoError(t, s.Remove(id))
+	}
+	assert.Equal(t, []string{"a", "b", "c", "d"}, ids)
+
+	files, err := os.ReadDir(cfg.SpoolDir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func Test_batchSpool_DropsBatchesAboveMaxSize(t *testing.T) {
+	logger := &mocks.Logger{}
+	logger.On("Info", "spooled batch dropped", mock.Anything, mock.Anything).Once()
+
+	s, err := New(&config.AgentConfig{SpoolDir: t.TempDir()}, logger)
+	require.NoError(t, err)
+
+	require.NoError(t, s.Push("a", batch("PollCount", 1)))
+	s.maxSize = s.size + 1
+
+	require.NoError(t, s.Push("b", batch("PollCount", 2)))
+
+	id, _, ok, err := s.Oldest()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "b", id)
+	assert.Equal(t, 1, s.Len())
+	logger.AssertExpectations(t)
+}
+
+func Test_batchSpool_DropsBatchesAboveMaxAge(t *testing.T) {
+	logger := &mocks.Logger{}
+	logger.On("Info", "spooled batch dropped", mock.Anything, mock.Anything).Once()
+
+	cfg := &config.AgentConfig{SpoolDir: t.TempDir(), SpoolMaxAge: config.Duration{Duration: time.Hour}}
+	s, err := New(cfg, logger)
+	require.NoError(t, err)
+
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	require.NoError(t, s.Push("a", batch("PollCount", 1)))
+
+	now = now.Add(time.Hour / 2)
+	require.NoError(t, s.Push("b", batch("PollCount", 2)))
+
+	now = now.Add(time.Hour * 3 / 4)
+	id, _, ok, err := s.Oldest()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "b", id)
+	logger.AssertExpectations(t)
+}
+
+func Test_batchSpool_Oldest_DropsUnreadableBatch(t *testing.T) {
+	logger := &mocks.Logger{}
+	logger.On("Info", "spooled batch dropped", mock.Anything, mock.Anything).Once()
+
+	s, err := New(&config.AgentConfig{SpoolDir: t.TempDir()}, logger)
+	require.NoError(t, err)
+	require.NoError(t, s.Push("a", batch("PollCount", 1)))
+	require.NoError(t, s.Push("b", batch("PollCount", 2)))
+
+	require.NoError(t, os.WriteFile(filepath.Join(s.dir, s.entries[0].name()), []byte("{"), 0600))
+
+	id, _, ok, err := s.Oldest()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "b", id)
+	logger.AssertExpectations(t)
+}
+
+func batch(id string, delta int64) map[string]models.Metric {
+	return map[string]models.Metric{id: {ID: id, MType: "counter", Delta: &delta}}
+}
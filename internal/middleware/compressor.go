@@ -26,9 +26,11 @@ func (c *compressWriter) Header() http.Header {
 }
 
 func (c *compressWriter) Write(p []byte) (int, error) {
-	// сжимаем данные только для контента с типами application/json и text/html
+	// сжимаем данные только для контента с типами application/json, text/html и text/plain
 	ct := c.w.Header().Get("Content-Type")
-	supportsCTypes := strings.Contains(ct, "application/json") || strings.Contains(ct, "text/html")
+	supportsCTypes := strings.Contains(ct, "application/json") ||
+		strings.Contains(ct, "text/html") ||
+		strings.Contains(ct, "text/plain")
 
 	if !supportsCTypes {
 		return c.w.Write(p)
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// MaxBatchIDLength is the maximum number of characters in a batch ID
+const MaxBatchIDLength = 64
+
+var (
+	ErrInvalidBatchID = errors.New("invalid batch id")
+)
+
+// ValidateBatchID checks that the batch ID fits in MaxBatchIDLength characters
+func ValidateBatchID(id string) error {
+	if n := utf8.RuneCountInString(id); n > MaxBatchIDLength {
+		return fmt.Errorf("%w: %d characters exceed %d", ErrInvalidBatchID, n, MaxBatchIDLength)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var (
+	ErrInvalidHistogram = errors.New("invalid histogram")
+)
+
+// DefaultBuckets are upper bounds used for single observations sent without a bucket layout
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Bucket counts observations greater than the previous bucket bound and less or equal to UpperBound
+type Bucket struct {
+	UpperBound float64 `json:"le"`
+	Count      uint64  `json:"count"`
+}
+
+// Histogram counts observations in buckets with ascending upper bounds.
+// Bucket counts are not cumulative, observations greater than the last bound are counted only in Count.
+type Histogram struct {
+	Buckets []Bucket `json:"buckets"`
+	Sum     float64  `json:"sum"`
+	Count   uint64   `json:"count"`
+}
+
+// NewHistogram returns an empty histogram with the given bucket upper bounds
+func NewHistogram(bounds []float64) *Histogram {
+	b := make([]float64, len(bounds))
+	copy(b, bounds)
+	sort.Float64s(b)
+
+	h := &Histogram{Buckets: make([]Bucket, 0, len(b))}
+	for i := range b {
+		if i > 0 && b[i] == b[i-1] {
+			continue
+		}
+		h.Buckets = append(h.Buckets, Bucket{UpperBound: b[i]})
+	}
+
+	return h
+}
+
+// Observe adds a single value to the histogram
+func (h *Histogram) Observe(v float64) {
+	i := sort.Search(len(h.Buckets), func(i int) bool { return v <= h.Buckets[i].UpperBound })
+	if i < len(h.Buckets) {
+		h.Buckets[i].Count++
+	}
+	h.Sum += v
+	h.Count++
+}
+
+// Merge adds observations of o to the histogram. When bucket layouts differ
+// the histogram is replaced by o, the same way a counter reset is handled.
+func (h *Histogram) Merge(o *Histogram) {
+	if !h.sameBuckets(o) {
+		*h = *o.Copy()
+		return
+	}
+
+	for i := range h.Buckets {
+		h.Buckets[i].Count += o.Buckets[i].Count
+	}
+	h.Sum += o.Sum
+	h.Count += o.Count
+}
+
+// Diff returns observations added to the cumulative histogram since its earlier state prev.
+// When bucket layouts differ or a count decreased the histogram was reset and its copy is returned.
+func (h *Histogram) Diff(prev *Histogram) *Histogram {
+	if prev == nil || !h.sameBuckets(prev) || h.Count < prev.Count {
+		return h.Copy()
+	}
+	for i := range h.Buckets {
+		if h.Buckets[i].Count < prev.Buckets[i].Count {
+			return h.Copy()
+		}
+	}
+
+	d := h.Copy()
+	for i := range d.Buckets {
+		d.Buckets[i].Count -= prev.Buckets[i].Count
+	}
+	d.Sum -= prev.Sum
+	d.Count -= prev.Count
+
+	return d
+}
+
+// Copy returns a deep copy of the histogram
+func (h *Histogram) Copy() *Histogram {
+	c := &Histogram{Sum: h.Sum, Count: h.Count, Buckets: make([]Bucket, len(h.Buckets))}
+	copy(c.Buckets, h.Buckets)
+	return c
+}
+
+// Validate checks that bounds are ascending and bucket counts fit into the total count
+func (h *Histogram) Validate() error {
+	if h == nil {
+		return fmt.Errorf("%w: histogram is empty", ErrInvalidHistogram)
+	}
+
+	var total uint64
+	for i := range h.Buckets {
+		if i > 0 && h.Buckets[i].UpperBound <= h.Buckets[i-1].UpperBound {
+			return fmt.Errorf("%w: bucket bounds must be ascending", ErrInvalidHistogram)
+		}
+		total += h.Buckets[i].Count
+	}
+	if total > h.Count {
+		return fmt.Errorf("%w: buckets count %d exceeds total count %d", ErrInvalidHistogram, total, h.Count)
+	}
+
+	return nil
+}
+
+func (h *Histogram) sameBuckets(o *Histogram) bool {
+	if len(h.Buckets) != len(o.Buckets) {
+		return false
+	}
+	for i := range h.Buckets {
+		if h.Buckets[i].UpperBound != o.Buckets[i].UpperBound {
+			return false
+		}
+	}
+	return true
+}
+
+// Value implements driver.Valuer, histogram is stored as a JSON object
+func (h Histogram) Value() (driver.Value, error) {
+	b, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (h *Histogram) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, h)
+	case string:
+		return json.Unmarshal([]byte(v), h)
+	default:
+		return fmt.Errorf("cannot scan %T into histogram", src)
+	}
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Labels are key/value pairs that, together with the metric ID, identify a metric series.
+type Labels map[string]string
+
+// String returns labels in a canonical form: k1="v1",k2="v2" sorted by key
+func (l Labels) String() string {
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(strconv.Quote(l[k]))
+	}
+
+	return sb.String()
+}
+
+// Value implements driver.Valuer, labels are stored as a JSON object
+func (l Labels) Value() (driver.Value, error) {
+	if l == nil {
+		return "{}", nil
+	}
+
+	b, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (l *Labels) Scan(src any) error {
+	var b []byte
+
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into labels", src)
+	}
+
+	var res Labels
+	if err := json.Unmarshal(b, &res); err != nil {
+		return err
+	}
+	if len(res) == 0 {
+		res = nil
+	}
+	*l = res
+
+	return nil
+}
+
+// ParseLabels parses labels in the form k1=v1,k2=v2
+func ParseLabels(s string) (Labels, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	l := make(Labels)
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("expect labels in a form k1=v1,k2=v2, got %q", s)
+		}
+		l[k] = strings.TrimSpace(v)
+	}
+
+	return l, nil
+}
@@ -1,8 +1,35 @@
 package models
 
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotFoundMetric is returned by repositories for a metric that is not stored
+	ErrNotFoundMetric = errors.New("not found in repository")
+)
+
 type Metric struct {
-	ID    string   `json:"id" db:"id"`
-	MType string   `json:"type" db:"m_type"`
-	Delta *int64   `json:"delta,omitempty" db:"m_delta"`
-	Value *float64 `json:"value,omitempty" db:"m_value"`
+	ID        string     `json:"id" db:"id"`
+	MType     string     `json:"type" db:"m_type"`
+	Delta     *int64     `json:"delta,omitempty" db:"m_delta"`
+	Value     *float64   `json:"value,omitempty" db:"m_value"`
+	Histogram *Histogram `json:"histogram,omitempty" db:"m_histogram"`
+	Summary   *Summary   `json:"summary,omitempty" db:"m_summary"`
+	Labels    Labels     `json:"labels,omitempty" db:"labels"`
+}
+
+// Sample is a metric value accepted by the repository at the given moment.
+type Sample struct {
+	Metric
+	Timestamp time.Time `json:"timestamp" db:"ts"`
+}
+
+// Key identifies the metric series: ID followed by canonical labels, e.g. HeapAlloc{host="a"}
+func (m Metric) Key() string {
+	if len(m.Labels) == 0 {
+		return m.ID
+	}
+	return m.ID + "{" + m.Labels.String() + "}"
 }
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Aggregations applied to the samples falling into one step of a range query
+const (
+	AggregationAvg  = "avg"
+	AggregationMin  = "min"
+	AggregationMax  = "max"
+	AggregationLast = "last"
+	AggregationSum  = "sum"
+)
+
+// maxRangePoints limits the number of steps a single range query can return
+const maxRangePoints = 11000
+
+var (
+	ErrInvalidQuery = errors.New("invalid range query")
+)
+
+// RangeQuery selects metric samples in [Start, End] and aggregates them by Step.
+type RangeQuery struct {
+	ID          string
+	MType       string
+	Labels      Labels
+	Start       time.Time
+	End         time.Time
+	Step        time.Duration
+	Aggregation string
+}
+
+// Point is an aggregated value of a metric, Timestamp is aligned to the query step.
+type Point struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+}
+
+// Validate checks that the query is complete and doesn't produce too many points
+func (q RangeQuery) Validate() error {
+	if q.ID == "" {
+		return fmt.Errorf("%w: metric id is empty", ErrInvalidQuery)
+	}
+	if q.Step <= 0 {
+		return fmt.Errorf("%w: step must be positive", ErrInvalidQuery)
+	}
+	if q.End.Before(q.Start) {
+		return fmt.Errorf("%w: end is before start", ErrInvalidQuery)
+	}
+	if q.End.Sub(q.Start)/q.Step > maxRangePoints {
+		return fmt.Errorf("%w: exceeded maximum resolution of %d points", ErrInvalidQuery, maxRangePoints)
+	}
+
+	switch q.Aggregation {
+	case AggregationAvg, AggregationMin, AggregationMax, AggregationLast, AggregationSum:
+	default:
+		return fmt.Errorf("%w: unknown aggregation %q", ErrInvalidQuery, q.Aggregation)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Chystik/runtime-metrics/pkg/ddsketch"
+)
+
+var (
+	ErrInvalidSummary = errors.New("invalid summary")
+)
+
+// DefaultQuantiles are reported for summaries on read
+var DefaultQuantiles = []float64{0.5, 0.9, 0.99}
+
+// Summary tracks quantiles of observations with a mergeable DDSketch.
+// Quantiles are calculated from the sketch on read and are not stored.
+type Summary struct {
+	Sketch    *ddsketch.Sketch `json:"sketch"`
+	Quantiles []Quantile       `json:"quantiles,omitempty"`
+}
+
+type Quantile struct {
+	Quantile float64 `json:"quantile"`
+	Value    float64 `json:"value"`
+}
+
+// NewSummary returns an empty summary with the default sketch accuracy
+func NewSummary() *Summary {
+	sk, _ := ddsketch.New(ddsketch.DefaultRelativeAccuracy)
+	return &Summary{Sketch: sk}
+}
+
+// Observe adds a single value to the summary
+func (s *Summary) Observe(v float64) {
+	s.Sketch.Add(v)
+}
+
+// Merge adds observations of o to the summary. When sketches are incompatible
+// the summary is replaced by o, the same way a counter reset is handled.
+func (s *Summary) Merge(o *Summary) {
+	if err := s.Sketch.Merge(o.Sketch); err != nil {
+		s.Sketch = o.Sketch.Copy()
+	}
+	s.Quantiles = nil
+}
+
+// Copy returns a deep copy of the summary without calculated quantiles
+func (s *Summary) Copy() *Summary {
+	return &Summary{Sketch: s.Sketch.Copy()}
+}
+
+// Validate checks that the summary has a consistent sketch
+func (s *Summary) Validate() error {
+	if s == nil || s.Sketch == nil {
+		return fmt.Errorf("%w: summary is empty", ErrInvalidSummary)
+	}
+	if err := s.Sketch.Validate(); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidSummary, err.Error())
+	}
+	return nil
+}
+
+// FillQuantiles calculates the given quantiles from the sketch
+func (s *Summary) FillQuantiles(qs []float64) {
+	s.Quantiles = make([]Quantile, 0, len(qs))
+
+	for _, q := range qs {
+		v, err := s.Sketch.Quantile(q)
+		if err != nil {
+			continue
+		}
+		s.Quantiles = append(s.Quantiles, Quantile{Quantile: q, Value: v})
+	}
+}
+
+// Value implements driver.Valuer, only the sketch is stored
+func (s Summary) Value() (driver.Value, error) {
+	b, err := json.Marshal(Summary{Sketch: s.Sketch})
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (s *Summary) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	default:
+		return fmt.Errorf("cannot scan %T into summary", src)
+	}
+}
@@ -0,0 +1,63 @@
+package agentservice
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+	"github.com/Chystik/runtime-metrics/internal/service"
+)
+
+const (
+	reportSuccessID  = "ReportSuccess"
+	reportFailureID  = "ReportFailure"
+	destinationLabel = "destination"
+)
+
+// fanOut stores collected metrics in the caches of destination services, so every
+// destination reports the same metrics and keeps its own deltas and failed batches
+type fanOut struct {
+	names    []string
+	services []*agentService
+}
+
+func NewFanOut() *fanOut {
+	return &fanOut{}
+}
+
+// Add adds the destination service, it must be called before collecting
+func (f *fanOut) Add(name string, s *agentService) {
+	f.names = append(f.names, name)
+	f.services = append(f.services, s)
+}
+
+// Collect polls the collector once and stores its metrics in every destination service
+func (f *fanOut) Collect(ctx context.Context, c service.Collector) error {
+	metrics, err := c.Collect(ctx)
+
+	for _, s := range f.services {
+		s.storeAll(metrics)
+	}
+
+	if err != nil {
+		return fmt.Errorf("collector %s: %w", c.Name(), err)
+	}
+
+	return nil
+}
+
+// RecordReport counts the report to the destination as ReportSuccess or ReportFailure
+// with the destination label, the counters are reported to every destination
+func (f *fanOut) RecordReport(name string, err error) {
+	id := reportSuccessID
+	if err != nil {
+		id = reportFailureID
+	}
+
+	delta := int64(1)
+	m := []models.Metric{{ID: id, MType: "counter", Delta: &delta, Labels: models.Labels{destinationLabel: name}}}
+
+	for _, s := range f.services {
+		s.storeAll(m)
+	}
+}
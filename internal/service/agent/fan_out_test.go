@@ -0,0 +1,52 @@
+package agentservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Chystik/runtime-metrics/internal/models"
+	"github.com/Chystik/runtime-metrics/internal/service/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFanOut_Collect(t *testing.T) {
+	primary, secondary := New(nil, nil, nil, nil), New(nil, nil, nil, nil)
+	fo := NewFanOut()
+	fo.Add("primary", primary)
+	fo.Add("secondary", secondary)
+
+	c := &mocks.Collector{}
+	c.EXPECT().Collect(mock.Anything).Return([]models.Metric{
+		{ID: "PollCount", MType: "counter", Delta: createDelta(1)},
+	}, nil).Once()
+
+	require.NoError(t, fo.Collect(context.Background(), c))
+
+	assert.Equal(t, int64(1), *primary.cache["PollCount"].Delta)
+	assert.Equal(t, int64(1), *secondary.cache["PollCount"].Delta)
+	c.AssertExpectations(t)
+}
+
+func TestFanOut_RecordReport(t *testing.T) {
+	primary, secondary := New(nil, nil, nil, nil), New(nil, nil, nil, nil)
+	fo := NewFanOut()
+	fo.Add("primary", primary)
+	fo.Add("secondary", secondary)
+
+	fo.RecordReport("primary", nil)
+	fo.RecordReport("primary", nil)
+	fo.RecordReport("secondary", errors.New("some error"))
+
+	success := models.Metric{ID: "ReportSuccess", Labels: models.Labels{"destination": "primary"}}.Key()
+	failure := models.Metric{ID: "ReportFailure", Labels: models.Labels{"destination": "secondary"}}.Key()
+	for _, s := range []*agentService{primary, secondary} {
+		require.Contains(t, s.cache, success)
+		require.Contains(t, s.cache, failure)
+		assert.Equal(t, int64(2), *s.cache[success].Delta)
+		assert.Equal(t, int64(1), *s.cache[failure].Delta)
+	}
+}
@@ -2,114 +2,324 @@ package agentservice
 
 import (
 	"context"
-	"math/rand"
-	"reflect"
-	"runtime"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"sync"
+	"time"
 
-	"github.com/Chystik/runtime-metrics/config"
 	"github.com/Chystik/runtime-metrics/internal/models"
 	"github.com/Chystik/runtime-metrics/internal/service"
+)
 
-	"github.com/shirou/gopsutil/v3/cpu"
-	"github.com/shirou/gopsutil/v3/mem"
+const (
+	reportAttemptTimeout = 10 * time.Second
+	replayBackoffMin     = time.Second
+	replayBackoffMax     = 5 * time.Minute
 )
 
+// ErrSpooled means the batch was not delivered and stays in the spool
+var ErrSpooled = errors.New("batch is spooled")
+
 type agentService struct {
-	collectableMetrics config.CollectableMetrics
-	runtimeMetrics     runtime.MemStats
-	memMetrics         *mem.VirtualMemoryStat
-	cpuMetrics         []cpu.InfoStat
-	mu                 sync.RWMutex
-	cache              map[string]models.Metric
-	client             service.AgentAPIClient
+	mu     sync.RWMutex
+	cache  map[string]models.Metric
+	labels models.Labels
+	client service.AgentAPIClient
+	// retrier retries a batch that failed to connect with the same batch ID
+	retrier service.ConnectionRetrier
+	// reportMu keeps one batch in flight, unsent is the last batch that failed
+	// and could not be spooled
+	reportMu sync.Mutex
+	unsent   *batch
+	spool    service.BatchSpool
+	// spooled batches are not replayed before replayAt
+	replayAt time.Time
+	backoff  time.Duration
 }
 
-func New(c service.AgentAPIClient, cm config.CollectableMetrics) *agentService {
-	cache := make(map[string]models.Metric)
+type batch struct {
+	id      string
+	metrics map[string]models.Metric
+}
 
-	cache["PollCount"] = models.Metric{ID: "PollCount", MType: "counter", Delta: new(int64)}
-	cache["RandomValue"] = models.Metric{ID: "RandomValue", MType: "gauge", Value: new(float64)}
-	cache["TotalMemory"] = models.Metric{ID: "TotalMemory", Value: new(float64)}
-	cache["FreeMemory"] = models.Metric{ID: "FreeMemory", Value: new(float64)}
-	cache["CPUutilization1"] = models.Metric{ID: "CPUutilization1", Value: new(float64)}
-	for i := range cm {
-		cache[cm[i]] = models.Metric{ID: cm[i], Value: new(float64)}
+// New returns the agent service, failed batches are kept in the spool if it is not nil
+// and in memory otherwise, sends are retried by r if it is not nil
+func New(c service.AgentAPIClient, labels models.Labels, spool service.BatchSpool, r service.ConnectionRetrier) *agentService {
+	return &agentService{
+		cache:   make(map[string]models.Metric),
+		labels:  labels,
+		client:  c,
+		retrier: r,
+		spool:   spool,
 	}
+}
 
-	return &agentService{
-		collectableMetrics: cm,
-		runtimeMetrics:     runtime.MemStats{},
-		memMetrics:         &mem.VirtualMemoryStat{},
-		cpuMetrics:         []cpu.InfoStat{},
-		cache:              cache,
-		client:             c,
+// Collect polls the collector and stores its metrics in the cache, metrics
+// collected before an error are stored too
+func (as *agentService) Collect(ctx context.Context, c service.Collector) error {
+	metrics, err := c.Collect(ctx)
+
+	as.storeAll(metrics)
+
+	if err != nil {
+		return fmt.Errorf("collector %s: %w", c.Name(), err)
 	}
+
+	return nil
 }
 
-func (as *agentService) UpdateMetrics() {
+func (as *agentService) storeAll(metrics []models.Metric) {
 	as.mu.Lock()
 	defer as.mu.Unlock()
 
-	runtime.ReadMemStats(&as.runtimeMetrics)
+	for i := range metrics {
+		as.store(metrics[i])
+	}
+}
 
-	for i := range as.collectableMetrics {
-		r := reflect.ValueOf(as.runtimeMetrics)
-		f := r.FieldByName(as.collectableMetrics[i])
-		v := f.Interface()
+// store replaces gauges, adds counter increments and merges histogram and summary
+// observations into the cached metric, the caller must hold the lock
+func (as *agentService) store(m models.Metric) {
+	key := m.Key()
+	cached, ok := as.cache[key]
+	if ok && cached.MType != m.MType {
+		ok = false
+	}
 
-		m, ok := as.cache[as.collectableMetrics[i]]
-		if !ok {
-			continue
+	switch m.MType {
+	case "gauge":
+		if m.Value == nil {
+			return
+		}
+		v := *m.Value
+		m.Value = &v
+	case "counter":
+		if m.Delta == nil {
+			return
+		}
+		d := *m.Delta
+		if ok {
+			d += *cached.Delta
+		}
+		m.Delta = &d
+	case "histogram":
+		if m.Histogram == nil {
+			return
+		}
+		h := m.Histogram.Copy()
+		if ok {
+			h = cached.Histogram.Copy()
+			h.Merge(m.Histogram)
+		}
+		m.Histogram = h
+	case "summary":
+		if m.Summary == nil || m.Summary.Sketch == nil {
+			return
+		}
+		s := m.Summary.Copy()
+		if ok {
+			s = cached.Summary.Copy()
+			s.Merge(m.Summary)
 		}
+		m.Summary = s
+	default:
+		return
+	}
+
+	as.cache[key] = m
+}
+
+// ReportMetrics sends the last gauge values and counter increments, histogram and summary
+// observations accumulated since the last acknowledged report. Accumulated values are taken
+// out of the cache into a batch with a random ID. A failed batch may have been applied by
+// the server, so it is kept and resent with the same ID before the next one. A batch rejected
+// by the server is dropped, the error names it.
+func (as *agentService) ReportMetrics(ctx context.Context) error {
+	as.reportMu.Lock()
+	defer as.reportMu.Unlock()
 
-		switch val := v.(type) {
-		case float64:
-			m.MType = "gauge"
-			*m.Value = val
-		case uint64:
-			m.MType = "gauge"
-			*m.Value = float64(val)
-		case uint32:
-			m.MType = "gauge"
-			*m.Value = float64(val)
+	var dropped error
+	if as.unsent != nil {
+		err := as.send(ctx, as.unsent.id, as.unsent.metrics)
+		if err != nil && !errors.Is(err, service.ErrBatchRejected) {
+			return err
 		}
-		m.ID = as.collectableMetrics[i]
-		as.cache[as.collectableMetrics[i]] = m
+		if err != nil {
+			dropped = dropErr(as.unsent.id, as.unsent.metrics, err)
+		}
+		as.unsent = nil
+	}
+
+	b, err := as.takeBatch()
+	if err != nil {
+		return errors.Join(dropped, err)
 	}
 
-	pc, ok := as.cache["PollCount"]
-	if ok {
-		*pc.Delta += 1
-		as.cache["PollCount"] = pc
+	if as.spool != nil {
+		return errors.Join(dropped, as.reportSpooled(ctx, b))
 	}
 
-	rv, ok := as.cache["RandomValue"]
-	if ok {
-		*rv.Value = float64(rand.Intn(1000))
-		as.cache["RandomValue"] = rv
+	err = as.send(ctx, b.id, b.metrics)
+	switch {
+	case errors.Is(err, service.ErrBatchRejected):
+		err = dropErr(b.id, b.metrics, err)
+	case err != nil:
+		as.unsent = &b
 	}
+
+	return errors.Join(dropped, err)
 }
 
-func (as *agentService) UpdateGoPsUtilMetrics() {
+// dropErr describes the batch dropped after the error
+func dropErr(id string, metrics map[string]models.Metric, err error) error {
+	return fmt.Errorf("batch %s of %d metrics dropped: %w", id, len(metrics), err)
+}
+
+// send sends the batch, every attempt has its own timeout
+func (as *agentService) send(ctx context.Context, id string, metrics map[string]models.Metric) error {
+	report := func() error {
+		attemptCtx, cancel := context.WithTimeout(ctx, reportAttemptTimeout)
+		defer cancel()
+		return as.client.ReportMetricsBatch(attemptCtx, id, metrics)
+	}
+
+	if as.retrier == nil {
+		return report()
+	}
+	return as.retrier.DoWithRetry(report)
+}
+
+// takeBatch copies gauges and takes other metrics out of the cache
+func (as *agentService) takeBatch() (batch, error) {
+	id, err := newBatchID()
+	if err != nil {
+		return batch{}, err
+	}
+
 	as.mu.Lock()
 	defer as.mu.Unlock()
 
-	as.memMetrics, _ = mem.VirtualMemory()
-	as.cpuMetrics, _ = cpu.Info()
+	metrics := make(map[string]models.Metric, len(as.cache))
+	for k, m := range as.cache {
+		if m.MType != "gauge" {
+			delete(as.cache, k)
+		}
+		m.Labels = mergeLabels(as.labels, m.Labels)
+		metrics[k] = m
+	}
+
+	return batch{id: id, metrics: metrics}, nil
+}
+
+// reportSpooled sends the batch if the spool is empty and spools it on failure. Otherwise
+// the batch is spooled after older ones and the spool is replayed, after a failure the
+// replay is delayed by a backoff growing up to replayBackoffMax. Rejected batches are not
+// spooled. ErrSpooled is returned while batches stay in the spool.
+func (as *agentService) reportSpooled(ctx context.Context, b batch) error {
+	if as.spool.Len() == 0 {
+		err := as.send(ctx, b.id, b.metrics)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, service.ErrBatchRejected) {
+			return dropErr(b.id, b.metrics, err)
+		}
+		as.delayReplay()
 
-	totalMemory := float64(as.memMetrics.Total)
-	as.cache["TotalMemory"] = models.Metric{ID: "TotalMemory", MType: "gauge", Value: &totalMemory}
-	freeMemory := float64(as.memMetrics.Free)
-	as.cache["FreeMemory"] = models.Metric{ID: "FreeMemory", MType: "gauge", Value: &freeMemory}
+		if serr := as.spool.Push(b.id, b.metrics); serr != nil {
+			as.unsent = &b
+			return errors.Join(err, serr)
+		}
+		return fmt.Errorf("%w: %w", ErrSpooled, err)
+	}
 
-	cpuUtil1 := float64(len(as.cpuMetrics))
-	as.cache["CPUutilization1"] = models.Metric{ID: "CPUutilization1", MType: "gauge", Value: &cpuUtil1}
+	if len(b.metrics) > 0 {
+		if err := as.spool.Push(b.id, b.metrics); err != nil {
+			return err
+		}
+	}
+
+	err := as.replay(ctx)
+	if as.spool.Len() == 0 {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSpooled, err)
+	}
+
+	return ErrSpooled
 }
 
-func (as *agentService) ReportMetrics(ctx context.Context) error {
-	as.mu.RLock()
-	defer as.mu.RUnlock()
+// replay sends spooled batches from the oldest one until the spool is empty, a batch
+// fails or ctx is done. A rejected batch is removed from the spool, so it does not
+// block newer ones.
+func (as *agentService) replay(ctx context.Context) error {
+	if time.Now().Before(as.replayAt) {
+		return nil
+	}
+
+	var errs []error
+	for ctx.Err() == nil {
+		id, metrics, ok, err := as.spool.Oldest()
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		if !ok {
+			as.backoff = 0
+			break
+		}
+
+		err = as.send(ctx, id, metrics)
+		switch {
+		case errors.Is(err, service.ErrBatchRejected):
+			errs = append(errs, dropErr(id, metrics, err))
+		case err != nil:
+			as.delayReplay()
+			return errors.Join(append(errs, err)...)
+		}
+
+		err = as.spool.Remove(id)
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+	}
+
+	return errors.Join(errs...)
+}
 
-	return as.client.ReportMetricsBatch(ctx, as.cache)
+func (as *agentService) delayReplay() {
+	as.backoff *= 2
+	if as.backoff < replayBackoffMin {
+		as.backoff = replayBackoffMin
+	}
+	if as.backoff > replayBackoffMax {
+		as.backoff = replayBackoffMax
+	}
+	as.replayAt = time.Now().Add(as.backoff)
+}
+
+func newBatchID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("batch id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// mergeLabels returns agent labels overridden by the metric ones
+func mergeLabels(agent, metric models.Labels) models.Labels {
+	if len(metric) == 0 {
+		return agent
+	}
+
+	labels := make(models.Labels, len(agent)+len(metric))
+	for k, v := range agent {
+		labels[k] = v
+	}
+	for k, v := range metric {
+		labels[k] = v
+	}
+	return labels
 }
@@ -2,59 +2,352 @@ package agentservice
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"reflect"
 	"testing"
+	"time"
 
-	"github.com/Chystik/runtime-metrics/config"
+	"github.com/Chystik/runtime-metrics/internal/models"
 	"github.com/Chystik/runtime-metrics/internal/service"
 	"github.com/Chystik/runtime-metrics/internal/service/mocks"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_New(t *testing.T) {
 	var c service.AgentAPIClient
-	var m []string
 
-	agentService := New(c, m)
+	agentService := New(c, nil, nil, nil)
 
 	assert.NotNil(t, agentService)
 }
 
-func Test_agentService_UpdateMetrics(t *testing.T) {
-	collectableMetrics := []string{"Alloc", "BuckHashSys", "Frees", "GCCPUFraction", "NumGC"}
-	as := New(nil, collectableMetrics)
+func Test_agentService_Collect(t *testing.T) {
+	as := New(nil, nil, nil, nil)
+	c := &mocks.Collector{}
 
-	tests := []struct {
-		name string
-		as   *agentService
-	}{
-		{
-			name: "get metrics",
-			as:   as,
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			tt.as.UpdateMetrics()
-		})
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			tt.as.UpdateGoPsUtilMetrics()
-		})
-	}
+	h := models.NewHistogram([]float64{1, 10})
+	h.Observe(5)
+
+	c.EXPECT().Collect(mock.Anything).Return([]models.Metric{
+		{ID: "Alloc", MType: "gauge", Value: createValue(1)},
+		{ID: "PollCount", MType: "counter", Delta: createDelta(1)},
+		{ID: "PauseNs", MType: "histogram", Histogram: h},
+	}, nil).Twice()
+
+	assert.NoError(t, as.Collect(context.Background(), c))
+	assert.NoError(t, as.Collect(context.Background(), c))
+
+	assert.Equal(t, 1.0, *as.cache["Alloc"].Value)
+	assert.Equal(t, int64(2), *as.cache["PollCount"].Delta)
+	assert.Equal(t, uint64(2), as.cache["PauseNs"].Histogram.Count)
+	// the collected histogram is not modified
+	assert.Equal(t, uint64(1), h.Count)
+	c.AssertExpectations(t)
+}
+
+func Test_agentService_Collect_WhenCollectorReturnsError(t *testing.T) {
+	as := New(nil, nil, nil, nil)
+	c := &mocks.Collector{}
+
+	c.EXPECT().Name().Return("test")
+	c.EXPECT().Collect(mock.Anything).Return([]models.Metric{
+		{ID: "Alloc", MType: "gauge", Value: createValue(1)},
+	}, errors.New("some error"))
+
+	err := as.Collect(context.Background(), c)
+
+	assert.ErrorContains(t, err, "collector test")
+	assert.Contains(t, as.cache, "Alloc")
 }
 
 func TestReportMetrics_WhenClientRetunNoError(t *testing.T) {
 	c, mks := getAgentServiceMocks()
 
-	mks.client.On("ReportMetricsBatch", mock.Anything, mock.Anything).Return(nil)
+	mks.client.On("ReportMetricsBatch", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	err := c.ReportMetrics(context.Background())
 
 	assert.NoError(t, err)
 }
 
+func TestReportMetrics_AttachesLabels(t *testing.T) {
+	client := &mocks.AgentAPIClient{}
+	labels := models.Labels{"host": "a", "instance": "1"}
+	as := New(client, labels, nil, nil)
+	as.cache["Alloc"] = models.Metric{ID: "Alloc", MType: "gauge", Value: createValue(1)}
+	as.cache[`Disk{device="sda"}`] = models.Metric{ID: "Disk", MType: "gauge", Value: createValue(1), Labels: models.Labels{"device": "sda"}}
+
+	client.On("ReportMetricsBatch", mock.Anything, mock.Anything, mock.MatchedBy(func(m map[string]models.Metric) bool {
+		return reflect.DeepEqual(labels, m["Alloc"].Labels) &&
+			reflect.DeepEqual(models.Labels{"host": "a", "instance": "1", "device": "sda"}, m[`Disk{device="sda"}`].Labels)
+	})).Return(nil)
+
+	err := as.ReportMetrics(context.Background())
+
+	assert.NoError(t, err)
+	client.AssertExpectations(t)
+	assert.Empty(t, as.cache["Alloc"].Labels)
+}
+
+func TestReportMetrics_ResetsAcknowledgedDeltas(t *testing.T) {
+	client := &mocks.AgentAPIClient{}
+	as := New(client, nil, nil, nil)
+	as.cache["Alloc"] = models.Metric{ID: "Alloc", MType: "gauge", Value: createValue(1)}
+	as.cache["PollCount"] = models.Metric{ID: "PollCount", MType: "counter", Delta: createDelta(5)}
+
+	client.On("ReportMetricsBatch", mock.Anything, mock.Anything, mock.MatchedBy(func(m map[string]models.Metric) bool {
+		return len(m) == 2 && *m["PollCount"].Delta == 5
+	})).Return(nil).Once()
+
+	err := as.ReportMetrics(context.Background())
+
+	assert.NoError(t, err)
+	assert.NotContains(t, as.cache, "PollCount")
+	assert.Equal(t, 1.0, *as.cache["Alloc"].Value)
+	client.AssertExpectations(t)
+}
+
+func TestReportMetrics_ResendsFailedBatch(t *testing.T) {
+	client := &mocks.AgentAPIClient{}
+	as := New(client, nil, nil, nil)
+	c := &mocks.Collector{}
+
+	h := models.NewHistogram([]float64{1})
+	h.Observe(1)
+	c.EXPECT().Collect(mock.Anything).Return([]models.Metric{
+		{ID: "PollCount", MType: "counter", Delta: createDelta(2)},
+		{ID: "PauseNs", MType: "histogram", Histogram: h},
+	}, nil)
+	require.NoError(t, as.Collect(context.Background(), c))
+
+	// a collect happens while the batch is sent
+	var failedID string
+	client.On("ReportMetricsBatch", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		failedID = args.String(1)
+		require.NoError(t, as.Collect(context.Background(), c))
+	}).Return(errors.New("some error")).Once()
+
+	err := as.ReportMetrics(context.Background())
+
+	assert.Error(t, err)
+	assert.Len(t, failedID, 32)
+	assert.Equal(t, int64(2), *as.cache["PollCount"].Delta)
+
+	sent := make(map[string]int64)
+	client.On("ReportMetricsBatch", mock.Anything, mock.Anything, mock.MatchedBy(func(m map[string]models.Metric) bool {
+		return *m["PollCount"].Delta == 2 && m["PauseNs"].Histogram.Count == 1
+	})).Run(func(args mock.Arguments) {
+		sent[args.String(1)] = *args.Get(2).(map[string]models.Metric)["PollCount"].Delta
+	}).Return(nil).Twice()
+
+	assert.NoError(t, as.ReportMetrics(context.Background()))
+	assert.Empty(t, as.cache)
+	assert.Len(t, sent, 2)
+	assert.Contains(t, sent, failedID)
+	client.AssertExpectations(t)
+}
+
+func TestReportMetrics_DropsRejectedBatch(t *testing.T) {
+	client := &mocks.AgentAPIClient{}
+	as := New(client, nil, nil, nil)
+	as.cache["PollCount"] = models.Metric{ID: "PollCount", MType: "counter", Delta: createDelta(2)}
+
+	// the failed batch is resent and rejected
+	var failedID string
+	client.On("ReportMetricsBatch", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		failedID = args.String(1)
+	}).Return(errors.New("some error")).Once()
+	require.Error(t, as.ReportMetrics(context.Background()))
+
+	client.On("ReportMetricsBatch", mock.Anything, failedID, mock.Anything).
+		Return(fmt.Errorf("%w: resp status code: 400", service.ErrBatchRejected)).Once()
+
+	as.cache["PollCount"] = models.Metric{ID: "PollCount", MType: "counter", Delta: createDelta(3)}
+	var newID string
+	client.On("ReportMetricsBatch", mock.Anything, mock.Anything, mock.MatchedBy(func(m map[string]models.Metric) bool {
+		return *m["PollCount"].Delta == 3
+	})).Run(func(args mock.Arguments) {
+		newID = args.String(1)
+	}).Return(nil).Once()
+
+	err := as.ReportMetrics(context.Background())
+
+	assert.ErrorIs(t, err, service.ErrBatchRejected)
+	assert.ErrorContains(t, err, failedID)
+	assert.NotEqual(t, failedID, newID)
+	assert.Nil(t, as.unsent)
+	client.AssertExpectations(t)
+
+	// a rejected new batch is not resent
+	as.cache["PollCount"] = models.Metric{ID: "PollCount", MType: "counter", Delta: createDelta(4)}
+	client.On("ReportMetricsBatch", mock.Anything, mock.Anything, mock.Anything).
+		Return(service.ErrBatchRejected).Once()
+
+	assert.ErrorIs(t, as.ReportMetrics(context.Background()), service.ErrBatchRejected)
+	assert.Nil(t, as.unsent)
+}
+
+func TestReportMetrics_RetriesSameBatch(t *testing.T) {
+	client := &mocks.AgentAPIClient{}
+	retrier := &mocks.ConnectionRetrier{}
+	as := New(client, nil, nil, retrier)
+	as.cache["PollCount"] = models.Metric{ID: "PollCount", MType: "counter", Delta: createDelta(5)}
+
+	retrier.EXPECT().DoWithRetry(mock.Anything).RunAndReturn(func(fn func() error) error {
+		err := fn()
+		for i := 0; i < 2 && err != nil; i++ {
+			err = fn()
+		}
+		return err
+	})
+
+	ids := make(map[string]int)
+	client.On("ReportMetricsBatch", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		ids[args.String(1)]++
+	}).Return(errors.New("some error")).Twice()
+	client.On("ReportMetricsBatch", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		ids[args.String(1)]++
+	}).Return(nil).Once()
+
+	err := as.ReportMetrics(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, ids, 1)
+	for _, n := range ids {
+		assert.Equal(t, 3, n)
+	}
+	client.AssertExpectations(t)
+}
+
+func TestReportMetrics_SpoolsFailedBatch(t *testing.T) {
+	client := &mocks.AgentAPIClient{}
+	spool := &mocks.BatchSpool{}
+	as := New(client, nil, spool, nil)
+	as.cache["PollCount"] = models.Metric{ID: "PollCount", MType: "counter", Delta: createDelta(5)}
+
+	var id string
+	spool.On("Len").Return(0)
+	client.On("ReportMetricsBatch", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		id = args.String(1)
+	}).Return(errors.New("some error")).Once()
+	spool.On("Push", mock.Anything, mock.MatchedBy(func(m map[string]models.Metric) bool {
+		return *m["PollCount"].Delta == 5
+	})).Return(nil).Once()
+
+	err := as.ReportMetrics(context.Background())
+
+	assert.ErrorIs(t, err, ErrSpooled)
+	spool.AssertCalled(t, "Push", id, mock.Anything)
+	assert.Nil(t, as.unsent)
+	assert.True(t, as.replayAt.After(time.Now()))
+	spool.AssertExpectations(t)
+}
+
+func TestReportMetrics_ReplaysSpoolInOrder(t *testing.T) {
+	client := &mocks.AgentAPIClient{}
+	spool := &mocks.BatchSpool{}
+	as := New(client, nil, spool, nil)
+	as.cache["PollCount"] = models.Metric{ID: "PollCount", MType: "counter", Delta: createDelta(5)}
+	as.backoff = replayBackoffMin
+
+	spooled := map[string]models.Metric{"PollCount": {ID: "PollCount", MType: "counter", Delta: createDelta(1)}}
+	var sent []string
+
+	spool.On("Len").Return(1).Once()
+	spool.On("Len").Return(0).Once()
+	spool.On("Push", mock.Anything, mock.Anything).Return(nil).Once()
+	spool.On("Oldest").Return("old", spooled, true, nil).Once()
+	spool.On("Remove", "old").Return(nil).Once()
+	spool.On("Oldest").Return("new", spooled, true, nil).Once()
+	spool.On("Remove", "new").Return(nil).Once()
+	spool.On("Oldest").Return("", nil, false, nil).Once()
+	client.On("ReportMetricsBatch", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		sent = append(sent, args.String(1))
+	}).Return(nil)
+
+	err := as.ReportMetrics(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"old", "new"}, sent)
+	assert.Zero(t, as.backoff)
+	spool.AssertExpectations(t)
+}
+
+func TestReportMetrics_RemovesRejectedSpooledBatch(t *testing.T) {
+	client := &mocks.AgentAPIClient{}
+	spool := &mocks.BatchSpool{}
+	as := New(client, nil, spool, nil)
+	as.cache["PollCount"] = models.Metric{ID: "PollCount", MType: "counter", Delta: createDelta(5)}
+
+	spooled := map[string]models.Metric{"PollCount": {ID: "PollCount", MType: "counter", Delta: createDelta(1)}}
+	var sent []string
+
+	spool.On("Len").Return(2).Once()
+	spool.On("Push", mock.Anything, mock.Anything).Return(nil).Once()
+	spool.On("Oldest").Return("rejected", spooled, true, nil).Once()
+	spool.On("Remove", "rejected").Return(nil).Once()
+	spool.On("Oldest").Return("new", spooled, true, nil).Once()
+	spool.On("Remove", "new").Return(nil).Once()
+	spool.On("Oldest").Return("", nil, false, nil).Once()
+	spool.On("Len").Return(0).Once()
+	client.On("ReportMetricsBatch", mock.Anything, "rejected", mock.Anything).Run(func(args mock.Arguments) {
+		sent = append(sent, args.String(1))
+	}).Return(service.ErrBatchRejected).Once()
+	client.On("ReportMetricsBatch", mock.Anything, "new", mock.Anything).Run(func(args mock.Arguments) {
+		sent = append(sent, args.String(1))
+	}).Return(nil).Once()
+
+	err := as.ReportMetrics(context.Background())
+
+	assert.ErrorIs(t, err, service.ErrBatchRejected)
+	assert.NotErrorIs(t, err, ErrSpooled)
+	assert.Equal(t, []string{"rejected", "new"}, sent)
+	assert.Zero(t, as.backoff)
+	spool.AssertExpectations(t)
+	client.AssertExpectations(t)
+}
+
+func TestReportMetrics_DoesNotSpoolRejectedBatch(t *testing.T) {
+	client := &mocks.AgentAPIClient{}
+	spool := &mocks.BatchSpool{}
+	as := New(client, nil, spool, nil)
+	as.cache["PollCount"] = models.Metric{ID: "PollCount", MType: "counter", Delta: createDelta(5)}
+
+	spool.On("Len").Return(0)
+	client.On("ReportMetricsBatch", mock.Anything, mock.Anything, mock.Anything).Return(service.ErrBatchRejected).Once()
+
+	err := as.ReportMetrics(context.Background())
+
+	assert.ErrorIs(t, err, service.ErrBatchRejected)
+	assert.NotErrorIs(t, err, ErrSpooled)
+	spool.AssertNotCalled(t, "Push", mock.Anything, mock.Anything)
+	assert.Nil(t, as.unsent)
+}
+
+func TestReportMetrics_DelaysReplayAfterFailure(t *testing.T) {
+	client := &mocks.AgentAPIClient{}
+	spool := &mocks.BatchSpool{}
+	as := New(client, nil, spool, nil)
+	as.cache["PollCount"] = models.Metric{ID: "PollCount", MType: "counter", Delta: createDelta(5)}
+
+	spool.On("Len").Return(1)
+	spool.On("Push", mock.Anything, mock.Anything).Return(nil).Once()
+	spool.On("Oldest").Return("old", map[string]models.Metric{}, true, nil).Once()
+	client.On("ReportMetricsBatch", mock.Anything, "old", mock.Anything).Return(errors.New("some error")).Once()
+
+	assert.ErrorIs(t, as.ReportMetrics(context.Background()), ErrSpooled)
+	assert.Equal(t, replayBackoffMin, as.backoff)
+
+	// the batch without counters is not spooled and the replay is delayed
+	assert.ErrorIs(t, as.ReportMetrics(context.Background()), ErrSpooled)
+
+	spool.AssertExpectations(t)
+	client.AssertExpectations(t)
+}
+
 type agentServiceMocks struct {
 	client *mocks.AgentAPIClient
 }
@@ -64,6 +357,14 @@ func getAgentServiceMocks() (service.AgentService, *agentServiceMocks) {
 		client: &mocks.AgentAPIClient{},
 	}
 
-	as := New(mks.client, config.CollectableMetrics{})
+	as := New(mks.client, nil, nil, nil)
 	return as, mks
 }
+
+func createValue(x float64) *float64 {
+	return &x
+}
+
+func createDelta(x int64) *int64 {
+	return &x
+}
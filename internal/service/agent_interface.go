@@ -2,18 +2,45 @@ package service
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/Chystik/runtime-metrics/internal/models"
 )
 
+// ErrBatchRejected is returned by AgentAPIClient when the server rejects the batch itself,
+// so resending the same batch fails again
+var ErrBatchRejected = errors.New("batch rejected by server")
+
 type AgentAPIClient interface {
 	ReportMetrics(ctx context.Context, metrics map[string]interface{}) error
 	ReportMetricsJSON(ctx context.Context, metrics map[string]models.Metric) error
-	ReportMetricsBatch(ctx context.Context, metrics map[string]models.Metric) error
+	// ReportMetricsBatch sends metrics with the batch ID, the server applies a batch with
+	// the same ID once, so a batch may be resent if it is unknown whether it was applied.
+	// ErrBatchRejected is returned when the batch must not be resent.
+	ReportMetricsBatch(ctx context.Context, batchID string, metrics map[string]models.Metric) error
+}
+
+// BatchSpool persists batches that failed to be sent until they are acknowledged,
+// batches are replayed from the oldest one
+type BatchSpool interface {
+	Push(batchID string, metrics map[string]models.Metric) error
+	// Oldest returns the oldest spooled batch, ok is false when the spool is empty
+	Oldest() (batchID string, metrics map[string]models.Metric, ok bool, err error)
+	Remove(batchID string) error
+	Len() int
 }
 
 type AgentService interface {
-	UpdateMetrics()
-	UpdateGoPsUtilMetrics()
+	Collect(context.Context, Collector) error
 	ReportMetrics(context.Context) error
 }
+
+// Collector is a source of agent metrics polled every Interval. Collect returns
+// current gauge values, counter increments and histogram and summary observations
+// since the previous call. Collect is not called concurrently for the same collector.
+type Collector interface {
+	Name() string
+	Interval() time.Duration
+	Collect(ctx context.Context) ([]models.Metric, error)
+}
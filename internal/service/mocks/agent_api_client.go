@@ -65,13 +65,13 @@ func (_c *AgentAPIClient_ReportMetrics_Call) RunAndReturn(run func(context.Conte
 	return _c
 }
 
-// ReportMetricsBatch provides a mock function with given fields: ctx, metrics
-func (_m *AgentAPIClient) ReportMetricsBatch(ctx context.Context, metrics map[string]models.Metric) error {
-	ret := _m.Called(ctx, metrics)
+// ReportMetricsBatch provides a mock function with given fields: ctx, batchID, metrics
+func (_m *AgentAPIClient) ReportMetricsBatch(ctx context.Context, batchID string, metrics map[string]models.Metric) error {
+	ret := _m.Called(ctx, batchID, metrics)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, map[string]models.Metric) error); ok {
-		r0 = rf(ctx, metrics)
+	if rf, ok := ret.Get(0).(func(context.Context, string, map[string]models.Metric) error); ok {
+		r0 = rf(ctx, batchID, metrics)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -86,14 +86,15 @@ type AgentAPIClient_ReportMetricsBatch_Call struct {
 
 // ReportMetricsBatch is a helper method to define mock.On call
 //   - ctx context.Context
+//   - batchID string
 //   - metrics map[string]models.Metric
-func (_e *AgentAPIClient_Expecter) ReportMetricsBatch(ctx interface{}, metrics interface{}) *AgentAPIClient_ReportMetricsBatch_Call {
-	return &AgentAPIClient_ReportMetricsBatch_Call{Call: _e.mock.On("ReportMetricsBatch", ctx, metrics)}
+func (_e *AgentAPIClient_Expecter) ReportMetricsBatch(ctx interface{}, batchID interface{}, metrics interface{}) *AgentAPIClient_ReportMetricsBatch_Call {
+	return &AgentAPIClient_ReportMetricsBatch_Call{Call: _e.mock.On("ReportMetricsBatch", ctx, batchID, metrics)}
 }
 
-func (_c *AgentAPIClient_ReportMetricsBatch_Call) Run(run func(ctx context.Context, metrics map[string]models.Metric)) *AgentAPIClient_ReportMetricsBatch_Call {
+func (_c *AgentAPIClient_ReportMetricsBatch_Call) Run(run func(ctx context.Context, batchID string, metrics map[string]models.Metric)) *AgentAPIClient_ReportMetricsBatch_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(map[string]models.Metric))
+		run(args[0].(context.Context), args[1].(string), args[2].(map[string]models.Metric))
 	})
 	return _c
 }
@@ -103,7 +104,7 @@ func (_c *AgentAPIClient_ReportMetricsBatch_Call) Return(_a0 error) *AgentAPICli
 	return _c
 }
 
-func (_c *AgentAPIClient_ReportMetricsBatch_Call) RunAndReturn(run func(context.Context, map[string]models.Metric) error) *AgentAPIClient_ReportMetricsBatch_Call {
+func (_c *AgentAPIClient_ReportMetricsBatch_Call) RunAndReturn(run func(context.Context, string, map[string]models.Metric) error) *AgentAPIClient_ReportMetricsBatch_Call {
 	_c.Call.Return(run)
 	return _c
 }
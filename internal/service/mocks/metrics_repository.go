@@ -4,6 +4,7 @@ package mocks
 
 import (
 	context "context"
+	time "time"
 
 	models "github.com/Chystik/runtime-metrics/internal/models"
 	mock "github.com/stretchr/testify/mock"
@@ -129,6 +130,107 @@ func (_c *MetricsRepository_GetAll_Call) RunAndReturn(run func(context.Context)
 	return _c
 }
 
+// GetRange provides a mock function with given fields: ctx, metric, from, to
+func (_m *MetricsRepository) GetRange(ctx context.Context, metric models.Metric, from time.Time, to time.Time) ([]models.Sample, error) {
+	ret := _m.Called(ctx, metric, from, to)
+
+	var r0 []models.Sample
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Metric, time.Time, time.Time) ([]models.Sample, error)); ok {
+		return rf(ctx, metric, from, to)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.Metric, time.Time, time.Time) []models.Sample); ok {
+		r0 = rf(ctx, metric, from, to)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Sample)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.Metric, time.Time, time.Time) error); ok {
+		r1 = rf(ctx, metric, from, to)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MetricsRepository_GetRange_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetRange'
+type MetricsRepository_GetRange_Call struct {
+	*mock.Call
+}
+
+// GetRange is a helper method to define mock.On call
+//   - ctx context.Context
+//   - metric models.Metric
+//   - from time.Time
+//   - to time.Time
+func (_e *MetricsRepository_Expecter) GetRange(ctx interface{}, metric interface{}, from interface{}, to interface{}) *MetricsRepository_GetRange_Call {
+	return &MetricsRepository_GetRange_Call{Call: _e.mock.On("GetRange", ctx, metric, from, to)}
+}
+
+func (_c *MetricsRepository_GetRange_Call) Run(run func(ctx context.Context, metric models.Metric, from time.Time, to time.Time)) *MetricsRepository_GetRange_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.Metric), args[2].(time.Time), args[3].(time.Time))
+	})
+	return _c
+}
+
+func (_c *MetricsRepository_GetRange_Call) Return(_a0 []models.Sample, _a1 error) *MetricsRepository_GetRange_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MetricsRepository_GetRange_Call) RunAndReturn(run func(context.Context, models.Metric, time.Time, time.Time) ([]models.Sample, error)) *MetricsRepository_GetRange_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateBatch provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MetricsRepository) UpdateBatch(_a0 context.Context, _a1 string, _a2 []models.Metric) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []models.Metric) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MetricsRepository_UpdateBatch_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateBatch'
+type MetricsRepository_UpdateBatch_Call struct {
+	*mock.Call
+}
+
+// UpdateBatch is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 string
+//   - _a2 []models.Metric
+func (_e *MetricsRepository_Expecter) UpdateBatch(_a0 interface{}, _a1 interface{}, _a2 interface{}) *MetricsRepository_UpdateBatch_Call {
+	return &MetricsRepository_UpdateBatch_Call{Call: _e.mock.On("UpdateBatch", _a0, _a1, _a2)}
+}
+
+func (_c *MetricsRepository_UpdateBatch_Call) Run(run func(_a0 context.Context, _a1 string, _a2 []models.Metric)) *MetricsRepository_UpdateBatch_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].([]models.Metric))
+	})
+	return _c
+}
+
+func (_c *MetricsRepository_UpdateBatch_Call) Return(_a0 error) *MetricsRepository_UpdateBatch_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MetricsRepository_UpdateBatch_Call) RunAndReturn(run func(context.Context, string, []models.Metric) error) *MetricsRepository_UpdateBatch_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // UpdateCounter provides a mock function with given fields: _a0, _a1
 func (_m *MetricsRepository) UpdateCounter(_a0 context.Context, _a1 models.Metric) error {
 	ret := _m.Called(_a0, _a1)
@@ -215,6 +317,49 @@ func (_c *MetricsRepository_UpdateGauge_Call) RunAndReturn(run func(context.Cont
 	return _c
 }
 
+// UpdateHistogram provides a mock function with given fields: _a0, _a1
+func (_m *MetricsRepository) UpdateHistogram(_a0 context.Context, _a1 models.Metric) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Metric) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MetricsRepository_UpdateHistogram_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateHistogram'
+type MetricsRepository_UpdateHistogram_Call struct {
+	*mock.Call
+}
+
+// UpdateHistogram is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 models.Metric
+func (_e *MetricsRepository_Expecter) UpdateHistogram(_a0 interface{}, _a1 interface{}) *MetricsRepository_UpdateHistogram_Call {
+	return &MetricsRepository_UpdateHistogram_Call{Call: _e.mock.On("UpdateHistogram", _a0, _a1)}
+}
+
+func (_c *MetricsRepository_UpdateHistogram_Call) Run(run func(_a0 context.Context, _a1 models.Metric)) *MetricsRepository_UpdateHistogram_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.Metric))
+	})
+	return _c
+}
+
+func (_c *MetricsRepository_UpdateHistogram_Call) Return(_a0 error) *MetricsRepository_UpdateHistogram_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MetricsRepository_UpdateHistogram_Call) RunAndReturn(run func(context.Context, models.Metric) error) *MetricsRepository_UpdateHistogram_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // UpdateList provides a mock function with given fields: _a0, _a1
 func (_m *MetricsRepository) UpdateList(_a0 context.Context, _a1 []models.Metric) error {
 	ret := _m.Called(_a0, _a1)
@@ -258,6 +403,49 @@ func (_c *MetricsRepository_UpdateList_Call) RunAndReturn(run func(context.Conte
 	return _c
 }
 
+// UpdateSummary provides a mock function with given fields: _a0, _a1
+func (_m *MetricsRepository) UpdateSummary(_a0 context.Context, _a1 models.Metric) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Metric) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MetricsRepository_UpdateSummary_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateSummary'
+type MetricsRepository_UpdateSummary_Call struct {
+	*mock.Call
+}
+
+// UpdateSummary is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 models.Metric
+func (_e *MetricsRepository_Expecter) UpdateSummary(_a0 interface{}, _a1 interface{}) *MetricsRepository_UpdateSummary_Call {
+	return &MetricsRepository_UpdateSummary_Call{Call: _e.mock.On("UpdateSummary", _a0, _a1)}
+}
+
+func (_c *MetricsRepository_UpdateSummary_Call) Run(run func(_a0 context.Context, _a1 models.Metric)) *MetricsRepository_UpdateSummary_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.Metric))
+	})
+	return _c
+}
+
+func (_c *MetricsRepository_UpdateSummary_Call) Return(_a0 error) *MetricsRepository_UpdateSummary_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MetricsRepository_UpdateSummary_Call) RunAndReturn(run func(context.Context, models.Metric) error) *MetricsRepository_UpdateSummary_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMetricsRepository creates a new instance of MetricsRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMetricsRepository(t interface {
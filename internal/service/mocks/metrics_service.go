@@ -129,6 +129,105 @@ func (_c *MetricsService_GetAll_Call) RunAndReturn(run func(context.Context) ([]
 	return _c
 }
 
+// QueryRange provides a mock function with given fields: _a0, _a1
+func (_m *MetricsService) QueryRange(_a0 context.Context, _a1 models.RangeQuery) ([]models.Point, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []models.Point
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.RangeQuery) ([]models.Point, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.RangeQuery) []models.Point); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Point)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.RangeQuery) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MetricsService_QueryRange_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'QueryRange'
+type MetricsService_QueryRange_Call struct {
+	*mock.Call
+}
+
+// QueryRange is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 models.RangeQuery
+func (_e *MetricsService_Expecter) QueryRange(_a0 interface{}, _a1 interface{}) *MetricsService_QueryRange_Call {
+	return &MetricsService_QueryRange_Call{Call: _e.mock.On("QueryRange", _a0, _a1)}
+}
+
+func (_c *MetricsService_QueryRange_Call) Run(run func(_a0 context.Context, _a1 models.RangeQuery)) *MetricsService_QueryRange_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.RangeQuery))
+	})
+	return _c
+}
+
+func (_c *MetricsService_QueryRange_Call) Return(_a0 []models.Point, _a1 error) *MetricsService_QueryRange_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MetricsService_QueryRange_Call) RunAndReturn(run func(context.Context, models.RangeQuery) ([]models.Point, error)) *MetricsService_QueryRange_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateBatch provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MetricsService) UpdateBatch(_a0 context.Context, _a1 string, _a2 []models.Metric) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []models.Metric) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MetricsService_UpdateBatch_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateBatch'
+type MetricsService_UpdateBatch_Call struct {
+	*mock.Call
+}
+
+// UpdateBatch is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 string
+//   - _a2 []models.Metric
+func (_e *MetricsService_Expecter) UpdateBatch(_a0 interface{}, _a1 interface{}, _a2 interface{}) *MetricsService_UpdateBatch_Call {
+	return &MetricsService_UpdateBatch_Call{Call: _e.mock.On("UpdateBatch", _a0, _a1, _a2)}
+}
+
+func (_c *MetricsService_UpdateBatch_Call) Run(run func(_a0 context.Context, _a1 string, _a2 []models.Metric)) *MetricsService_UpdateBatch_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].([]models.Metric))
+	})
+	return _c
+}
+
+func (_c *MetricsService_UpdateBatch_Call) Return(_a0 error) *MetricsService_UpdateBatch_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MetricsService_UpdateBatch_Call) RunAndReturn(run func(context.Context, string, []models.Metric) error) *MetricsService_UpdateBatch_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // UpdateCounter provides a mock function with given fields: _a0, _a1
 func (_m *MetricsService) UpdateCounter(_a0 context.Context, _a1 models.Metric) error {
 	ret := _m.Called(_a0, _a1)
@@ -215,6 +314,49 @@ func (_c *MetricsService_UpdateGauge_Call) RunAndReturn(run func(context.Context
 	return _c
 }
 
+// UpdateHistogram provides a mock function with given fields: _a0, _a1
+func (_m *MetricsService) UpdateHistogram(_a0 context.Context, _a1 models.Metric) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Metric) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MetricsService_UpdateHistogram_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateHistogram'
+type MetricsService_UpdateHistogram_Call struct {
+	*mock.Call
+}
+
+// UpdateHistogram is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 models.Metric
+func (_e *MetricsService_Expecter) UpdateHistogram(_a0 interface{}, _a1 interface{}) *MetricsService_UpdateHistogram_Call {
+	return &MetricsService_UpdateHistogram_Call{Call: _e.mock.On("UpdateHistogram", _a0, _a1)}
+}
+
+func (_c *MetricsService_UpdateHistogram_Call) Run(run func(_a0 context.Context, _a1 models.Metric)) *MetricsService_UpdateHistogram_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.Metric))
+	})
+	return _c
+}
+
+func (_c *MetricsService_UpdateHistogram_Call) Return(_a0 error) *MetricsService_UpdateHistogram_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MetricsService_UpdateHistogram_Call) RunAndReturn(run func(context.Context, models.Metric) error) *MetricsService_UpdateHistogram_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // UpdateList provides a mock function with given fields: _a0, _a1
 func (_m *MetricsService) UpdateList(_a0 context.Context, _a1 []models.Metric) error {
 	ret := _m.Called(_a0, _a1)
@@ -258,6 +400,49 @@ func (_c *MetricsService_UpdateList_Call) RunAndReturn(run func(context.Context,
 	return _c
 }
 
+// UpdateSummary provides a mock function with given fields: _a0, _a1
+func (_m *MetricsService) UpdateSummary(_a0 context.Context, _a1 models.Metric) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Metric) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MetricsService_UpdateSummary_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateSummary'
+type MetricsService_UpdateSummary_Call struct {
+	*mock.Call
+}
+
+// UpdateSummary is a helper method to define mock.On call
+//   - _a0 context.Context
+//   - _a1 models.Metric
+func (_e *MetricsService_Expecter) UpdateSummary(_a0 interface{}, _a1 interface{}) *MetricsService_UpdateSummary_Call {
+	return &MetricsService_UpdateSummary_Call{Call: _e.mock.On("UpdateSummary", _a0, _a1)}
+}
+
+func (_c *MetricsService_UpdateSummary_Call) Run(run func(_a0 context.Context, _a1 models.Metric)) *MetricsService_UpdateSummary_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.Metric))
+	})
+	return _c
+}
+
+func (_c *MetricsService_UpdateSummary_Call) Return(_a0 error) *MetricsService_UpdateSummary_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MetricsService_UpdateSummary_Call) RunAndReturn(run func(context.Context, models.Metric) error) *MetricsService_UpdateSummary_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMetricsService creates a new instance of MetricsService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMetricsService(t interface {
@@ -2,6 +2,8 @@ package metricsservice
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/Chystik/runtime-metrics/internal/models"
 	"github.com/Chystik/runtime-metrics/internal/service"
@@ -9,6 +11,7 @@ import (
 
 type metricsService struct {
 	metricsRepo service.MetricsRepository
+	mergeMu     sync.Mutex // serializes read-merge-write of histograms and summaries
 }
 
 func New(mr service.MetricsRepository) *metricsService {
@@ -23,14 +26,233 @@ func (ss *metricsService) UpdateCounter(ctx context.Context, metric models.Metri
 	return ss.metricsRepo.UpdateCounter(ctx, metric)
 }
 
+// UpdateHistogram merges observations of the metric into the stored histogram
+func (ss *metricsService) UpdateHistogram(ctx context.Context, metric models.Metric) error {
+	if err := metric.Histogram.Validate(); err != nil {
+		return err
+	}
+
+	ss.mergeMu.Lock()
+	defer ss.mergeMu.Unlock()
+
+	return ss.metricsRepo.UpdateHistogram(ctx, ss.mergeStored(ctx, metric))
+}
+
+// UpdateSummary merges observations of the metric into the stored summary
+func (ss *metricsService) UpdateSummary(ctx context.Context, metric models.Metric) error {
+	if err := metric.Summary.Validate(); err != nil {
+		return err
+	}
+
+	ss.mergeMu.Lock()
+	defer ss.mergeMu.Unlock()
+
+	return ss.metricsRepo.UpdateSummary(ctx, ss.mergeStored(ctx, metric))
+}
+
+// Get returns the stored metric, quantiles of summaries are calculated on read
 func (ss *metricsService) Get(ctx context.Context, metric models.Metric) (models.Metric, error) {
-	return ss.metricsRepo.Get(ctx, metric)
+	m, err := ss.metricsRepo.Get(ctx, metric)
+	if err != nil {
+		return m, err
+	}
+
+	if m.Summary != nil && m.Summary.Sketch != nil {
+		m.Summary = m.Summary.Copy()
+		m.Summary.FillQuantiles(models.DefaultQuantiles)
+	}
+
+	return m, nil
 }
 
 func (ss *metricsService) GetAll(ctx context.Context) ([]models.Metric, error) {
 	return ss.metricsRepo.GetAll(ctx)
 }
 
+// UpdateList stores the metrics, histograms and summaries are merged into stored ones before the update
 func (ss *metricsService) UpdateList(ctx context.Context, metrics []models.Metric) error {
-	return ss.metricsRepo.UpdateList(ctx, metrics)
+	return ss.updateList(ctx, "", metrics)
+}
+
+// UpdateBatch stores the metrics like UpdateList unless a batch with the same ID was stored
+// recently, so a batch resent after a lost response is not applied twice. The batch without
+// an ID is always stored. An ID longer than models.MaxBatchIDLength is rejected.
+func (ss *metricsService) UpdateBatch(ctx context.Context, batchID string, metrics []models.Metric) error {
+	if err := models.ValidateBatchID(batchID); err != nil {
+		return err
+	}
+	return ss.updateList(ctx, batchID, metrics)
+}
+
+func (ss *metricsService) updateList(ctx context.Context, batchID string, metrics []models.Metric) error {
+	var hasMergeable bool
+
+	for i := range metrics {
+		switch metrics[i].MType {
+		case "histogram":
+			if err := metrics[i].Histogram.Validate(); err != nil {
+				return err
+			}
+		case "summary":
+			if err := metrics[i].Summary.Validate(); err != nil {
+				return err
+			}
+		default:
+			continue
+		}
+		hasMergeable = true
+	}
+	if !hasMergeable {
+		return ss.store(ctx, batchID, metrics)
+	}
+
+	ss.mergeMu.Lock()
+	defer ss.mergeMu.Unlock()
+
+	merged := make([]models.Metric, 0, len(metrics))
+	seen := make(map[string]int)
+
+	for _, m := range metrics {
+		if m.MType != "histogram" && m.MType != "summary" {
+			merged = append(merged, m)
+			continue
+		}
+		// the same metric can be sent several times in one batch
+		if i, ok := seen[m.Key()]; ok && merged[i].MType == m.MType {
+			merged[i] = merge(merged[i], m)
+			continue
+		}
+		seen[m.Key()] = len(merged)
+		merged = append(merged, ss.mergeStored(ctx, m))
+	}
+
+	return ss.store(ctx, batchID, merged)
+}
+
+func (ss *metricsService) store(ctx context.Context, batchID string, metrics []models.Metric) error {
+	if batchID == "" {
+		return ss.metricsRepo.UpdateList(ctx, metrics)
+	}
+	return ss.metricsRepo.UpdateBatch(ctx, batchID, metrics)
+}
+
+// mergeStored returns the metric merged into the stored one of the same type,
+// the caller must hold mergeMu
+func (ss *metricsService) mergeStored(ctx context.Context, metric models.Metric) models.Metric {
+	stored, err := ss.metricsRepo.Get(ctx, metric)
+	if err != nil || stored.MType != metric.MType {
+		stored = models.Metric{ID: metric.ID, MType: metric.MType, Labels: metric.Labels}
+	}
+
+	return merge(stored, metric)
+}
+
+// merge returns dst with observations of src added, dst is not modified
+func merge(dst, src models.Metric) models.Metric {
+	switch src.MType {
+	case "histogram":
+		h := src.Histogram.Copy()
+		if dst.Histogram != nil {
+			h = dst.Histogram.Copy()
+			h.Merge(src.Histogram)
+		}
+		src.Histogram = h
+	case "summary":
+		s := src.Summary.Copy()
+		if dst.Summary != nil && dst.Summary.Sketch != nil {
+			s = dst.Summary.Copy()
+			s.Merge(src.Summary)
+		}
+		src.Summary = s
+	}
+
+	return src
+}
+
+// QueryRange reads samples of the metric in the requested range and aggregates
+// them into points aligned to multiples of the query step. Steps without samples are skipped.
+func (ss *metricsService) QueryRange(ctx context.Context, q models.RangeQuery) ([]models.Point, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	samples, err := ss.metricsRepo.GetRange(ctx, models.Metric{ID: q.ID, MType: q.MType, Labels: q.Labels}, q.Start, q.End)
+	if err != nil {
+		return nil, err
+	}
+
+	aggregate := aggregations[q.Aggregation]
+	points := make([]models.Point, 0)
+	values := make([]float64, 0)
+
+	var stepStart time.Time
+	for _, s := range samples {
+		var v float64
+
+		switch {
+		case s.Value != nil:
+			v = *s.Value
+		case s.Delta != nil:
+			v = float64(*s.Delta)
+		default:
+			continue
+		}
+
+		ts := alignTime(s.Timestamp, q.Step)
+		if !ts.Equal(stepStart) && len(values) > 0 {
+			points = append(points, models.Point{Timestamp: stepStart, Value: aggregate(values)})
+			values = values[:0]
+		}
+		stepStart = ts
+		values = append(values, v)
+	}
+	if len(values) > 0 {
+		points = append(points, models.Point{Timestamp: stepStart, Value: aggregate(values)})
+	}
+
+	return points, nil
+}
+
+var aggregations = map[string]func([]float64) float64{
+	models.AggregationAvg: func(v []float64) float64 {
+		var sum float64
+		for i := range v {
+			sum += v[i]
+		}
+		return sum / float64(len(v))
+	},
+	models.AggregationMin: func(v []float64) float64 {
+		min := v[0]
+		for i := range v {
+			if v[i] < min {
+				min = v[i]
+			}
+		}
+		return min
+	},
+	models.AggregationMax: func(v []float64) float64 {
+		max := v[0]
+		for i := range v {
+			if v[i] > max {
+				max = v[i]
+			}
+		}
+		return max
+	},
+	models.AggregationLast: func(v []float64) float64 {
+		return v[len(v)-1]
+	},
+	models.AggregationSum: func(v []float64) float64 {
+		var sum float64
+		for i := range v {
+			sum += v[i]
+		}
+		return sum
+	},
+}
+
+// alignTime rounds t down to a multiple of step since the Unix epoch
+func alignTime(t time.Time, step time.Duration) time.Time {
+	ns := t.UnixNano()
+	return time.Unix(0, ns-ns%int64(step)).UTC()
 }
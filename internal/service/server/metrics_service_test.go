@@ -3,7 +3,9 @@ package metricsservice
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Chystik/runtime-metrics/internal/models"
 	"github.com/Chystik/runtime-metrics/internal/service/mocks"
@@ -135,6 +137,175 @@ func TestUpdateList_WhenRepoReturnsResult(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestUpdateBatch(t *testing.T) {
+	t.Parallel()
+	service, mks := getMetricsServiceMocks()
+
+	metrics := []models.Metric{{ID: "test", MType: "counter", Delta: createDelta(1)}}
+
+	mks.repo.EXPECT().UpdateBatch(mock.Anything, "batch-1", metrics).Return(nil).Once()
+	mks.repo.EXPECT().UpdateList(mock.Anything, metrics).Return(nil).Once()
+
+	assert.NoError(t, service.UpdateBatch(context.Background(), "batch-1", metrics))
+	// a batch without an ID is always stored
+	assert.NoError(t, service.UpdateBatch(context.Background(), "", metrics))
+	// an ID that does not fit in the storage is rejected
+	err := service.UpdateBatch(context.Background(), strings.Repeat("a", models.MaxBatchIDLength+1), metrics)
+	assert.ErrorIs(t, err, models.ErrInvalidBatchID)
+	mks.repo.AssertExpectations(t)
+}
+
+func TestUpdateHistogram_MergesWithStored(t *testing.T) {
+	t.Parallel()
+	service, mks := getMetricsServiceMocks()
+
+	stored := models.Metric{ID: "test", MType: "histogram", Histogram: createHistogram(0.5, 2)}
+	update := models.Metric{ID: "test", MType: "histogram", Histogram: createHistogram(0.7)}
+
+	mks.repo.EXPECT().Get(mock.Anything, update).Return(stored, nil)
+	mks.repo.EXPECT().UpdateHistogram(mock.Anything, mock.MatchedBy(func(m models.Metric) bool {
+		return m.Histogram.Count == 3 && m.Histogram.Buckets[0].Count == 2 && m.Histogram.Sum == 3.2
+	})).Return(nil)
+
+	err := service.UpdateHistogram(context.Background(), update)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), stored.Histogram.Count, "stored histogram must not be modified")
+}
+
+func TestUpdateHistogram_WhenHistogramIsInvalid(t *testing.T) {
+	t.Parallel()
+	service, _ := getMetricsServiceMocks()
+
+	err := service.UpdateHistogram(context.Background(), models.Metric{ID: "test", MType: "histogram"})
+	assert.ErrorIs(t, err, models.ErrInvalidHistogram)
+
+	h := createHistogram(1)
+	h.Count = 0
+	err = service.UpdateHistogram(context.Background(), models.Metric{ID: "test", MType: "histogram", Histogram: h})
+	assert.ErrorIs(t, err, models.ErrInvalidHistogram)
+}
+
+func TestUpdateList_MergesHistograms(t *testing.T) {
+	t.Parallel()
+	service, mks := getMetricsServiceMocks()
+
+	metrics := []models.Metric{
+		{ID: "test", MType: "histogram", Histogram: createHistogram(0.5)},
+		{ID: "test2", MType: "gauge", Value: createValue(3.2)},
+		{ID: "test", MType: "histogram", Histogram: createHistogram(5)},
+	}
+
+	mks.repo.EXPECT().Get(mock.Anything, mock.Anything).Return(models.Metric{}, errors.New("not found")).Once()
+	mks.repo.EXPECT().UpdateList(mock.Anything, mock.MatchedBy(func(m []models.Metric) bool {
+		return len(m) == 2 && m[0].Histogram.Count == 2 && m[0].Histogram.Sum == 5.5
+	})).Return(nil)
+
+	err := service.UpdateList(context.Background(), metrics)
+	assert.NoError(t, err)
+}
+
+func TestUpdateSummary_MergesWithStored(t *testing.T) {
+	t.Parallel()
+	service, mks := getMetricsServiceMocks()
+
+	stored := models.Metric{ID: "test", MType: "summary", Summary: models.NewSummary()}
+	stored.Summary.Observe(1)
+	update := models.Metric{ID: "test", MType: "summary", Summary: models.NewSummary()}
+	update.Summary.Observe(3)
+
+	mks.repo.EXPECT().Get(mock.Anything, update).Return(stored, nil)
+	mks.repo.EXPECT().UpdateSummary(mock.Anything, mock.MatchedBy(func(m models.Metric) bool {
+		return m.Summary.Sketch.Count == 2 && m.Summary.Sketch.Sum == 4
+	})).Return(nil)
+
+	err := service.UpdateSummary(context.Background(), update)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), stored.Summary.Sketch.Count, "stored summary must not be modified")
+
+	err = service.UpdateSummary(context.Background(), models.Metric{ID: "test", MType: "summary"})
+	assert.ErrorIs(t, err, models.ErrInvalidSummary)
+}
+
+func TestGetMetric_FillsSummaryQuantiles(t *testing.T) {
+	t.Parallel()
+	service, mks := getMetricsServiceMocks()
+
+	stored := models.Metric{ID: "test", MType: "summary", Summary: models.NewSummary()}
+	for i := 1; i <= 100; i++ {
+		stored.Summary.Observe(float64(i))
+	}
+
+	mks.repo.EXPECT().Get(mock.Anything, mock.Anything).Return(stored, nil)
+
+	m, err := service.Get(context.Background(), models.Metric{ID: "test"})
+	assert.NoError(t, err)
+	if assert.Len(t, m.Summary.Quantiles, len(models.DefaultQuantiles)) {
+		assert.InDelta(t, 50, m.Summary.Quantiles[0].Value, 1)
+		assert.InDelta(t, 99, m.Summary.Quantiles[2].Value, 1)
+	}
+	assert.Empty(t, stored.Summary.Quantiles)
+}
+
+func TestQueryRange(t *testing.T) {
+	t.Parallel()
+
+	start := time.Unix(1700000040, 0).UTC()
+	samples := []models.Sample{
+		{Metric: models.Metric{ID: "test", Value: createValue(1)}, Timestamp: start.Add(time.Second)},
+		{Metric: models.Metric{ID: "test", Value: createValue(3)}, Timestamp: start.Add(30 * time.Second)},
+		{Metric: models.Metric{ID: "test", Value: createValue(5)}, Timestamp: start.Add(150 * time.Second)},
+	}
+
+	tests := []struct {
+		aggregation string
+		want        []float64
+	}{
+		{aggregation: models.AggregationAvg, want: []float64{2, 5}},
+		{aggregation: models.AggregationMin, want: []float64{1, 5}},
+		{aggregation: models.AggregationMax, want: []float64{3, 5}},
+		{aggregation: models.AggregationLast, want: []float64{3, 5}},
+		{aggregation: models.AggregationSum, want: []float64{4, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.aggregation, func(t *testing.T) {
+			service, mks := getMetricsServiceMocks()
+			mks.repo.EXPECT().GetRange(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(samples, nil)
+
+			points, err := service.QueryRange(context.Background(), models.RangeQuery{
+				ID:          "test",
+				Start:       start,
+				End:         start.Add(5 * time.Minute),
+				Step:        time.Minute,
+				Aggregation: tt.aggregation,
+			})
+
+			assert.NoError(t, err)
+			if assert.Len(t, points, len(tt.want)) {
+				assert.Equal(t, start, points[0].Timestamp)
+				assert.Equal(t, start.Add(2*time.Minute), points[1].Timestamp)
+				for i := range tt.want {
+					assert.Equal(t, tt.want[i], points[i].Value)
+				}
+			}
+		})
+	}
+}
+
+func TestQueryRange_WhenQueryIsInvalid(t *testing.T) {
+	t.Parallel()
+	service, _ := getMetricsServiceMocks()
+
+	_, err := service.QueryRange(context.Background(), models.RangeQuery{
+		ID:          "test",
+		Start:       time.Now(),
+		End:         time.Now().Add(time.Hour),
+		Step:        time.Minute,
+		Aggregation: "median",
+	})
+
+	assert.ErrorIs(t, err, models.ErrInvalidQuery)
+}
+
 type metricsServiceMocks struct {
 	repo *mocks.MetricsRepository
 }
@@ -155,3 +326,11 @@ func createValue(x float64) *float64 {
 func createDelta(x int64) *int64 {
 	return &x
 }
+
+func createHistogram(observations ...float64) *models.Histogram {
+	h := models.NewHistogram([]float64{1, 2})
+	for _, o := range observations {
+		h.Observe(o)
+	}
+	return h
+}
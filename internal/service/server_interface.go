@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/Chystik/runtime-metrics/internal/models"
 )
@@ -9,17 +10,27 @@ import (
 type MetricsService interface {
 	UpdateGauge(context.Context, models.Metric) error
 	UpdateCounter(context.Context, models.Metric) error
+	UpdateHistogram(context.Context, models.Metric) error
+	UpdateSummary(context.Context, models.Metric) error
 	UpdateList(context.Context, []models.Metric) error
+	// UpdateBatch stores the metrics once per batch ID, a batch with a recently stored ID is ignored
+	UpdateBatch(ctx context.Context, batchID string, metrics []models.Metric) error
 	Get(context.Context, models.Metric) (models.Metric, error)
 	GetAll(context.Context) ([]models.Metric, error)
+	QueryRange(context.Context, models.RangeQuery) ([]models.Point, error)
 }
 
 type MetricsRepository interface {
 	UpdateGauge(context.Context, models.Metric) error
 	UpdateCounter(context.Context, models.Metric) error
+	UpdateHistogram(context.Context, models.Metric) error
+	UpdateSummary(context.Context, models.Metric) error
 	UpdateList(context.Context, []models.Metric) error
+	// UpdateBatch stores the metrics once per batch ID, a batch with a recently stored ID is ignored
+	UpdateBatch(ctx context.Context, batchID string, metrics []models.Metric) error
 	Get(context.Context, models.Metric) (models.Metric, error)
 	GetAll(context.Context) ([]models.Metric, error)
+	GetRange(ctx context.Context, metric models.Metric, from, to time.Time) ([]models.Sample, error)
 }
 
 type MetricsStorage interface {
@@ -60,6 +60,26 @@ func (s *syncer) UpdateCounter(ctx context.Context, metric models.Metric) error
 	return nil
 }
 
+func (s *syncer) UpdateHistogram(ctx context.Context, metric models.Metric) error {
+	err := s.src.UpdateHistogram(ctx, metric)
+	if err != nil {
+		return err
+	}
+
+	s.sync()
+	return nil
+}
+
+func (s *syncer) UpdateSummary(ctx context.Context, metric models.Metric) error {
+	err := s.src.UpdateSummary(ctx, metric)
+	if err != nil {
+		return err
+	}
+
+	s.sync()
+	return nil
+}
+
 func (s *syncer) Get(ctx context.Context, metric models.Metric) (models.Metric, error) {
 	return s.src.Get(ctx, metric)
 }
@@ -68,6 +88,10 @@ func (s *syncer) GetAll(ctx context.Context) ([]models.Metric, error) {
 	return s.src.GetAll(ctx)
 }
 
+func (s *syncer) GetRange(ctx context.Context, metric models.Metric, from, to time.Time) ([]models.Sample, error) {
+	return s.src.GetRange(ctx, metric, from, to)
+}
+
 func (s *syncer) UpdateList(ctx context.Context, metrics []models.Metric) error {
 	err := s.src.UpdateList(ctx, metrics)
 	if err != nil {
@@ -78,6 +102,16 @@ func (s *syncer) UpdateList(ctx context.Context, metrics []models.Metric) error
 	return nil
 }
 
+func (s *syncer) UpdateBatch(ctx context.Context, batchID string, metrics []models.Metric) error {
+	err := s.src.UpdateBatch(ctx, batchID, metrics)
+	if err != nil {
+		return err
+	}
+
+	s.sync()
+	return nil
+}
+
 func (s *syncer) Shutdown(ctx context.Context) error {
 	once.Do(func() {
 		close(s.tick)
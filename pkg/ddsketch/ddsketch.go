@@ -0,0 +1,226 @@
+// Package ddsketch implements DDSketch, a quantile sketch with relative-error guarantees.
+// Sketches with the same relative accuracy can be merged without losing accuracy.
+//
+// See "DDSketch: A Fast and Fully-Mergeable Quantile Sketch with Relative-Error Guarantees", Masson et al., 2019.
+package ddsketch
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	// DefaultRelativeAccuracy keeps quantiles within 1% of the exact value
+	DefaultRelativeAccuracy = 0.01
+	// maxBins limits the number of bins per store, the lowest bins are collapsed first
+	maxBins = 2048
+	// minIndexableValue is the smallest magnitude tracked, smaller values are counted as zero
+	minIndexableValue = 1e-9
+)
+
+var (
+	ErrIncompatible = errors.New("sketches have different relative accuracy")
+	ErrInvalid      = errors.New("invalid sketch")
+)
+
+// Sketch counts values in logarithmically sized bins: bin i holds values in (gamma^(i-1), gamma^i].
+type Sketch struct {
+	RelativeAccuracy float64        `json:"relative_accuracy"`
+	Positive         map[int]uint64 `json:"positive,omitempty"`
+	Negative         map[int]uint64 `json:"negative,omitempty"`
+	Zero             uint64         `json:"zero,omitempty"`
+	Count            uint64         `json:"count"`
+	Sum              float64        `json:"sum"`
+	Min              float64        `json:"min"`
+	Max              float64        `json:"max"`
+}
+
+// New returns an empty sketch, relativeAccuracy must be in (0, 1)
+func New(relativeAccuracy float64) (*Sketch, error) {
+	if relativeAccuracy <= 0 || relativeAccuracy >= 1 {
+		return nil, fmt.Errorf("%w: relative accuracy must be in (0, 1), got %v", ErrInvalid, relativeAccuracy)
+	}
+
+	return &Sketch{
+		RelativeAccuracy: relativeAccuracy,
+		Positive:         make(map[int]uint64),
+		Negative:         make(map[int]uint64),
+	}, nil
+}
+
+// Add inserts a value into the sketch, NaN values are ignored
+func (s *Sketch) Add(v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+
+	switch {
+	case v > minIndexableValue:
+		s.add(&s.Positive, s.index(v), 1)
+	case v < -minIndexableValue:
+		s.add(&s.Negative, s.index(-v), 1)
+	default:
+		s.Zero++
+	}
+
+	if s.Count == 0 || v < s.Min {
+		s.Min = v
+	}
+	if s.Count == 0 || v > s.Max {
+		s.Max = v
+	}
+	s.Count++
+	s.Sum += v
+}
+
+// Merge adds all values of o to the sketch
+func (s *Sketch) Merge(o *Sketch) error {
+	if s.RelativeAccuracy != o.RelativeAccuracy {
+		return ErrIncompatible
+	}
+	if o.Count == 0 {
+		return nil
+	}
+
+	for i, c := range o.Positive {
+		s.add(&s.Positive, i, c)
+	}
+	for i, c := range o.Negative {
+		s.add(&s.Negative, i, c)
+	}
+	s.Zero += o.Zero
+
+	if s.Count == 0 || o.Min < s.Min {
+		s.Min = o.Min
+	}
+	if s.Count == 0 || o.Max > s.Max {
+		s.Max = o.Max
+	}
+	s.Count += o.Count
+	s.Sum += o.Sum
+
+	return nil
+}
+
+// Quantile returns the approximate value at quantile q in [0, 1], zero for an empty sketch
+func (s *Sketch) Quantile(q float64) (float64, error) {
+	if q < 0 || q > 1 {
+		return 0, fmt.Errorf("quantile must be in [0, 1], got %v", q)
+	}
+	if s.Count == 0 {
+		return 0, nil
+	}
+
+	rank := uint64(q * float64(s.Count-1))
+	var v float64
+
+	switch {
+	case rank < s.negativeCount():
+		// the most negative values have the highest indexes
+		neg := sortedIndexes(s.Negative)
+		var n uint64
+		for i := len(neg) - 1; i >= 0; i-- {
+			n += s.Negative[neg[i]]
+			if n > rank {
+				v = -s.value(neg[i])
+				break
+			}
+		}
+	case rank < s.negativeCount()+s.Zero:
+		v = 0
+	default:
+		n := s.negativeCount() + s.Zero
+		for _, i := range sortedIndexes(s.Positive) {
+			n += s.Positive[i]
+			if n > rank {
+				v = s.value(i)
+				break
+			}
+		}
+	}
+
+	return math.Max(s.Min, math.Min(s.Max, v)), nil
+}
+
+// Copy returns a deep copy of the sketch
+func (s *Sketch) Copy() *Sketch {
+	c := *s
+	c.Positive = make(map[int]uint64, len(s.Positive))
+	for i, n := range s.Positive {
+		c.Positive[i] = n
+	}
+	c.Negative = make(map[int]uint64, len(s.Negative))
+	for i, n := range s.Negative {
+		c.Negative[i] = n
+	}
+	return &c
+}
+
+// Validate checks that the sketch parameters are valid and bins add up to the total count
+func (s *Sketch) Validate() error {
+	if s.RelativeAccuracy <= 0 || s.RelativeAccuracy >= 1 {
+		return fmt.Errorf("%w: relative accuracy must be in (0, 1), got %v", ErrInvalid, s.RelativeAccuracy)
+	}
+	if s.negativeCount()+s.Zero+count(s.Positive) != s.Count {
+		return fmt.Errorf("%w: bins don't add up to the total count %d", ErrInvalid, s.Count)
+	}
+	return nil
+}
+
+func (s *Sketch) gamma() float64 {
+	return (1 + s.RelativeAccuracy) / (1 - s.RelativeAccuracy)
+}
+
+func (s *Sketch) index(v float64) int {
+	return int(math.Ceil(math.Log(v) / math.Log(s.gamma())))
+}
+
+// value returns the bin representative with the relative error bounded by the accuracy
+func (s *Sketch) value(i int) float64 {
+	g := s.gamma()
+	return 2 * math.Pow(g, float64(i)) / (g + 1)
+}
+
+func (s *Sketch) negativeCount() uint64 {
+	return count(s.Negative)
+}
+
+func (s *Sketch) add(store *map[int]uint64, i int, n uint64) {
+	if *store == nil {
+		*store = make(map[int]uint64)
+	}
+	(*store)[i] += n
+
+	if len(*store) > maxBins {
+		collapseLowest(*store, len(*store)-maxBins)
+	}
+}
+
+// collapseLowest merges the n+1 lowest bins into one, losing accuracy only for the smallest values
+func collapseLowest(store map[int]uint64, n int) {
+	idx := sortedIndexes(store)
+	target := idx[n]
+	for _, i := range idx[:n] {
+		store[target] += store[i]
+		delete(store, i)
+	}
+}
+
+func sortedIndexes(store map[int]uint64) []int {
+	idx := make([]int, 0, len(store))
+	for i := range store {
+		idx = append(idx, i)
+	}
+	sort.Ints(idx)
+	return idx
+}
+
+func count(store map[int]uint64) uint64 {
+	var n uint64
+	for _, c := range store {
+		n += c
+	}
+	return n
+}
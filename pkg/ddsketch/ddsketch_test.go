@@ -0,0 +1,103 @@
+package ddsketch
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_WhenAccuracyIsInvalid(t *testing.T) {
+	_, err := New(0)
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = New(1)
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestSketch_Quantile(t *testing.T) {
+	s, err := New(DefaultRelativeAccuracy)
+	require.NoError(t, err)
+
+	r := rand.New(rand.NewSource(1))
+	values := make([]float64, 10000)
+	for i := range values {
+		values[i] = r.ExpFloat64()*100 - 20
+		s.Add(values[i])
+	}
+	sort.Float64s(values)
+
+	for _, q := range []float64{0, 0.5, 0.9, 0.99, 1} {
+		got, err := s.Quantile(q)
+		require.NoError(t, err)
+
+		exp := values[int(q*float64(len(values)-1))]
+		assert.InDelta(t, exp, got, math.Abs(exp)*DefaultRelativeAccuracy+1e-9, "quantile %v", q)
+	}
+
+	assert.Equal(t, uint64(len(values)), s.Count)
+	assert.NoError(t, s.Validate())
+}
+
+func TestSketch_Merge(t *testing.T) {
+	a, _ := New(DefaultRelativeAccuracy)
+	b, _ := New(DefaultRelativeAccuracy)
+	all, _ := New(DefaultRelativeAccuracy)
+
+	for i := 1; i <= 1000; i++ {
+		if i%2 == 0 {
+			a.Add(float64(i))
+		} else {
+			b.Add(float64(i))
+		}
+		all.Add(float64(i))
+	}
+
+	require.NoError(t, a.Merge(b))
+	assert.Equal(t, all.Count, a.Count)
+	assert.Equal(t, all.Sum, a.Sum)
+	assert.Equal(t, 1.0, a.Min)
+	assert.Equal(t, 1000.0, a.Max)
+
+	for _, q := range []float64{0.5, 0.9, 0.99} {
+		exp, _ := all.Quantile(q)
+		got, _ := a.Quantile(q)
+		assert.Equal(t, exp, got)
+	}
+
+	c, _ := New(0.05)
+	assert.ErrorIs(t, a.Merge(c), ErrIncompatible)
+}
+
+func TestSketch_JSON(t *testing.T) {
+	s, _ := New(DefaultRelativeAccuracy)
+	s.Add(-1)
+	s.Add(0)
+	s.Add(42)
+
+	b, err := json.Marshal(s)
+	require.NoError(t, err)
+
+	var got Sketch
+	require.NoError(t, json.Unmarshal(b, &got))
+	assert.Equal(t, s, &got)
+	assert.NoError(t, got.Validate())
+}
+
+func TestSketch_CollapsesLowestBins(t *testing.T) {
+	s, _ := New(DefaultRelativeAccuracy)
+
+	for i := 0; i < maxBins*2; i++ {
+		s.Add(math.Pow(1.05, float64(i)))
+	}
+
+	assert.LessOrEqual(t, len(s.Positive), maxBins)
+	assert.NoError(t, s.Validate())
+
+	got, _ := s.Quantile(1)
+	assert.Equal(t, s.Max, got)
+}
@@ -23,6 +23,7 @@ const (
 	MetricsService_UpdateMetric_FullMethodName  = "/pb.MetricsService/UpdateMetric"
 	MetricsService_GetMetric_FullMethodName     = "/pb.MetricsService/GetMetric"
 	MetricsService_PingDB_FullMethodName        = "/pb.MetricsService/PingDB"
+	MetricsService_QueryRange_FullMethodName    = "/pb.MetricsService/QueryRange"
 )
 
 // MetricsServiceClient is the client API for MetricsService service.
@@ -33,6 +34,7 @@ type MetricsServiceClient interface {
 	UpdateMetric(ctx context.Context, in *UpdateMetricRequest, opts ...grpc.CallOption) (*UpdateMetricResponse, error)
 	GetMetric(ctx context.Context, in *GetMetricRequest, opts ...grpc.CallOption) (*GetMetricResponse, error)
 	PingDB(ctx context.Context, in *PingDBRequest, opts ...grpc.CallOption) (*PingDBResponse, error)
+	QueryRange(ctx context.Context, in *QueryRangeRequest, opts ...grpc.CallOption) (*QueryRangeResponse, error)
 }
 
 type metricsServiceClient struct {
@@ -79,6 +81,15 @@ func (c *metricsServiceClient) PingDB(ctx context.Context, in *PingDBRequest, op
 	return out, nil
 }
 
+func (c *metricsServiceClient) QueryRange(ctx context.Context, in *QueryRangeRequest, opts ...grpc.CallOption) (*QueryRangeResponse, error) {
+	out := new(QueryRangeResponse)
+	err := c.cc.Invoke(ctx, MetricsService_QueryRange_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MetricsServiceServer is the server API for MetricsService service.
 // All implementations must embed UnimplementedMetricsServiceServer
 // for forward compatibility
@@ -87,6 +98,7 @@ type MetricsServiceServer interface {
 	UpdateMetric(context.Context, *UpdateMetricRequest) (*UpdateMetricResponse, error)
 	GetMetric(context.Context, *GetMetricRequest) (*GetMetricResponse, error)
 	PingDB(context.Context, *PingDBRequest) (*PingDBResponse, error)
+	QueryRange(context.Context, *QueryRangeRequest) (*QueryRangeResponse, error)
 	mustEmbedUnimplementedMetricsServiceServer()
 }
 
@@ -106,6 +118,9 @@ func (UnimplementedMetricsServiceServer) GetMetric(context.Context, *GetMetricRe
 func (UnimplementedMetricsServiceServer) PingDB(context.Context, *PingDBRequest) (*PingDBResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method PingDB not implemented")
 }
+func (UnimplementedMetricsServiceServer) QueryRange(context.Context, *QueryRangeRequest) (*QueryRangeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryRange not implemented")
+}
 func (UnimplementedMetricsServiceServer) mustEmbedUnimplementedMetricsServiceServer() {}
 
 // UnsafeMetricsServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -191,6 +206,24 @@ func _MetricsService_PingDB_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MetricsService_QueryRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsServiceServer).QueryRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetricsService_QueryRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsServiceServer).QueryRange(ctx, req.(*QueryRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MetricsService_ServiceDesc is the grpc.ServiceDesc for MetricsService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -214,6 +247,10 @@ var MetricsService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "PingDB",
 			Handler:    _MetricsService_PingDB_Handler,
 		},
+		{
+			MethodName: "QueryRange",
+			Handler:    _MetricsService_QueryRange_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "protobuf/runtime_metrics.proto",
@@ -2,13 +2,18 @@ package run
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"sync"
 	"time"
 
 	"github.com/Chystik/runtime-metrics/config"
 	grpcclient "github.com/Chystik/runtime-metrics/internal/adapters/grpc_client"
 	agentapiclient "github.com/Chystik/runtime-metrics/internal/adapters/http_client"
+	"github.com/Chystik/runtime-metrics/internal/collector"
+	batchspool "github.com/Chystik/runtime-metrics/internal/infrastructure/storage/spool"
+	"github.com/Chystik/runtime-metrics/internal/models"
 	"github.com/Chystik/runtime-metrics/internal/service"
 	agentservice "github.com/Chystik/runtime-metrics/internal/service/agent"
 	"github.com/Chystik/runtime-metrics/pkg/httpclient"
@@ -19,9 +24,8 @@ import (
 )
 
 const (
-	httpClientTimeout    = 20 * time.Second
-	reportMetricsTimeout = 10 * time.Second
-	loggerLevel          = "info"
+	httpClientTimeout = 20 * time.Second
+	loggerLevel       = "info"
 )
 
 func Agent(ctx context.Context, cfg *config.AgentConfig) {
@@ -30,94 +34,109 @@ func Agent(ctx context.Context, cfg *config.AgentConfig) {
 		panic(err)
 	}
 
-	var agentClient service.AgentAPIClient
+	labels, err := agentLabels(cfg)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
 
-	if cfg.TransportType == config.HTTP {
-		httpClient, err := httpclient.NewClient(
-			httpclient.Timeout(httpClientTimeout),
-			httpclient.ExtractOutboundIP("X-Real-IP"),
-		)
-		if err != nil {
-			logger.Fatal(err.Error())
-		}
+	collectors, err := agentCollectors(cfg)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
 
-		if cfg.CryptoKey != "" {
-			err = httpClient.AddOption(httpclient.WithEncryption(cfg.CryptoKey))
-			if err != nil {
-				logger.Fatal(err.Error())
-			}
-		}
+	destinations, err := cfg.ReportDestinations()
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
 
-		agentClient = agentapiclient.New(httpClient, cfg)
-	} else if cfg.TransportType == config.GRPC {
-		grpcClient, err := grpcclient.New(cfg.Address)
-		if err != nil {
-			logger.Fatal(err.Error())
-		}
-		defer grpcClient.ConnClose()
+	// collected metrics are stored for every destination, each one is reported
+	// by its own workers
+	fanOut := agentservice.NewFanOut()
+	reporters := make([]*reporter, 0, len(destinations))
+	destNames := make([]string, 0, len(destinations))
 
-		agentClient = grpcClient
-	} else {
-		logger.Fatal(fmt.Sprintf("Unknown transport type: %s", cfg.TransportType))
-	}
+	for _, d := range destinations {
+		dcfg := cfg.WithDestination(d)
 
-	agentService := agentservice.New(agentClient, cfg.CollectableMetrics)
+		client, closeClient, err := newAgentClient(dcfg)
+		if err != nil {
+			logger.Fatal(fmt.Sprintf("destination %s: %s", d.Name, err))
+		}
+		defer closeClient()
 
-	p, r := cfg.PollInterval.Duration, cfg.ReportInterval.Duration
+		spool, err := newBatchSpool(dcfg, logger)
+		if err != nil {
+			logger.Fatal(fmt.Sprintf("destination %s: %s", d.Name, err))
+		}
 
-	reportMetrics := retryer.NewConnRetryerFn(
-		3,
-		time.Duration(time.Second),
-		time.Duration(2*time.Second),
-		logger,
-		func() error {
-			reportCtx, cancel := context.WithTimeout(context.Background(), reportMetricsTimeout)
-			defer cancel()
-			return agentService.ReportMetrics(reportCtx)
-		},
-	)
+		// a batch failed to connect is retried with the same batch ID
+		r := retryer.NewConnRetryer(
+			3,
+			time.Duration(time.Second),
+			time.Duration(2*time.Second),
+			logger,
+		)
 
-	updateTicker := time.NewTicker(p)
-	reportTicker := time.NewTicker(r)
+		as := agentservice.New(client, labels, spool, r)
+		fanOut.Add(d.Name, as)
 
-	numJobs := cfg.RateLimit
+		reporters = append(reporters, &reporter{
+			rateLimit:     dcfg.RateLimit,
+			jobs:          make(chan struct{}, 1),
+			reportMetrics: newReport(d.Name, as, fanOut),
+		})
+		destNames = append(destNames, fmt.Sprintf("%s=%s://%s", d.Name, dcfg.TransportType, dcfg.Address))
+	}
 
-	jobs := make(chan struct{}, 1)
+	reportTicker := time.NewTicker(cfg.ReportInterval.Duration)
 
 	logger.Info(
 		"agent started",
-		zap.String("Address", cfg.Address),
+		zap.Strings("Destinations", destNames),
 		zap.Duration("Poll interval", cfg.PollInterval.Duration),
 		zap.Duration("Report interval", cfg.ReportInterval.Duration),
 		zap.Int("Rate limit", cfg.RateLimit),
+		zap.String("Labels", labels.String()),
+		zap.Strings("Collectors", collectors.Names()),
 	)
 
 	var wg sync.WaitGroup
 
-	// init and run N workers, where N = RATE_LIMIT
-	for w := 1; w < numJobs+1; w++ {
+	// run collectors, each one on its own interval
+	for _, c := range collectors.Collectors() {
 		wg.Add(1)
-		go func(i int) {
-			worker(i, reportMetrics, jobs, logger)
+		go func(c service.Collector) {
+			runCollector(ctx, c, fanOut, logger)
 			wg.Done()
-		}(w)
+		}(c)
+	}
+
+	// init and run N workers per destination, where N = its rate limit
+	for _, r := range reporters {
+		for w := 1; w < r.rateLimit+1; w++ {
+			wg.Add(1)
+			go func(r *reporter, i int) {
+				worker(i, r.reportMetrics, r.jobs, logger)
+				wg.Done()
+			}(r, w)
+		}
 	}
 
 loop:
 	for {
 		select {
-		case <-updateTicker.C:
-			go agentService.UpdateMetrics()
-			go agentService.UpdateGoPsUtilMetrics()
 		case <-reportTicker.C:
-			if len(jobs) < cap(jobs) {
-				jobs <- struct{}{}
+			for _, r := range reporters {
+				if len(r.jobs) < cap(r.jobs) {
+					r.jobs <- struct{}{}
+				}
 			}
 		case <-ctx.Done():
 			logger.Info("Interrupt signal. Shutting down.")
-			updateTicker.Stop()
 			reportTicker.Stop()
-			close(jobs)
+			for _, r := range reporters {
+				close(r.jobs)
+			}
 			break loop
 		}
 	}
@@ -126,10 +145,186 @@ loop:
 	wg.Wait()
 }
 
-func worker(w int, fn service.ConnectionRetrierFn, jobs chan struct{}, logger service.AppLogger) {
+// reporter runs reports of one destination by its workers
+type reporter struct {
+	rateLimit     int
+	jobs          chan struct{}
+	reportMetrics func() error
+}
+
+type reportRecorder interface {
+	RecordReport(destination string, err error)
+}
+
+type metricsCollector interface {
+	Collect(context.Context, service.Collector) error
+}
+
+// newReport reports metrics of the destination, the result of every report is recorded
+func newReport(name string, as service.AgentService, rr reportRecorder) func() error {
+	return func() error {
+		err := as.ReportMetrics(context.Background())
+		rr.RecordReport(name, err)
+		if err != nil {
+			return fmt.Errorf("destination %s: %w", name, err)
+		}
+		return nil
+	}
+}
+
+// newBatchSpool returns nil if the spool directory is not set, failed batches are kept in memory then
+func newBatchSpool(cfg *config.AgentConfig, logger service.AppLogger) (service.BatchSpool, error) {
+	if cfg.SpoolDir == "" {
+		return nil, nil
+	}
+
+	return batchspool.New(cfg, logger)
+}
+
+// newAgentClient creates the client of the config transport, the returned func closes it
+func newAgentClient(cfg *config.AgentConfig) (service.AgentAPIClient, func(), error) {
+	switch cfg.TransportType {
+	case config.HTTP:
+		httpClient, err := httpclient.NewClient(
+			httpclient.Timeout(httpClientTimeout),
+			httpclient.ExtractOutboundIP("X-Real-IP"),
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if cfg.CryptoKey != "" {
+			err = httpClient.AddOption(httpclient.WithEncryption(cfg.CryptoKey))
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		return agentapiclient.New(httpClient, cfg), func() {}, nil
+	case config.GRPC:
+		grpcClient, err := grpcclient.New(cfg.Address)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return grpcClient, func() { grpcClient.ConnClose() }, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown transport type: %s", cfg.TransportType)
+	}
+}
+
+// agentLabels combines configured labels with the instance and host labels
+func agentLabels(cfg *config.AgentConfig) (models.Labels, error) {
+	labels := make(models.Labels, len(cfg.Labels)+2)
+
+	for k, v := range cfg.Labels {
+		labels[k] = v
+	}
+	if cfg.Instance != "" {
+		labels["instance"] = cfg.Instance
+	}
+	if cfg.HostLabel {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+		labels["host"] = host
+	}
+
+	if len(labels) == 0 {
+		return nil, nil
+	}
+
+	return labels, nil
+}
+
+// agentCollectors registers built-in collectors enabled in the config
+func agentCollectors(cfg *config.AgentConfig) (*collector.Registry, error) {
+	r := collector.NewRegistry(cfg.Collectors, cfg.PollInterval.Duration)
+
+	err := errors.Join(
+		r.Register(collector.MemStatsName, func(interval time.Duration) (service.Collector, error) {
+			return collector.NewMemStats(interval, cfg.CollectableMetrics, cfg.GCPauseBuckets), nil
+		}),
+		r.Register(collector.SystemName, func(interval time.Duration) (service.Collector, error) {
+			return collector.NewSystem(interval), nil
+		}),
+		r.Register(collector.RuntimeName, func(interval time.Duration) (service.Collector, error) {
+			return collector.NewRuntime(interval, cfg.CollectableMetrics), nil
+		}),
+		r.Register(collector.DiskIOName, func(interval time.Duration) (service.Collector, error) {
+			return collector.NewDiskIO(interval), nil
+		}),
+		r.Register(collector.FilesystemName, func(interval time.Duration) (service.Collector, error) {
+			return collector.NewFilesystem(interval), nil
+		}),
+		r.Register(collector.NetworkName, func(interval time.Duration) (service.Collector, error) {
+			return collector.NewNetwork(interval), nil
+		}),
+		r.Register(collector.LoadName, func(interval time.Duration) (service.Collector, error) {
+			return collector.NewLoad(interval), nil
+		}),
+		r.Register(collector.SwapName, func(interval time.Duration) (service.Collector, error) {
+			return collector.NewSwap(interval), nil
+		}),
+		r.Register(collector.CgroupName, func(interval time.Duration) (service.Collector, error) {
+			return collector.NewCgroup(interval, cfg.CgroupPath), nil
+		}),
+		r.Register(collector.ScrapeName, func(interval time.Duration) (service.Collector, error) {
+			return collector.NewScrape(interval, cfg.ScrapeTargets)
+		}),
+		r.Register(collector.ExecName, func(interval time.Duration) (service.Collector, error) {
+			return collector.NewExec(interval, cfg.Exec)
+		}),
+		r.Register(collector.LogTailName, func(interval time.Duration) (service.Collector, error) {
+			return collector.NewLogTail(interval, cfg.LogFiles)
+		}),
+		r.Register(collector.ProbeName, func(interval time.Duration) (service.Collector, error) {
+			return collector.NewProbe(interval, cfg.Probes)
+		}),
+		r.Register(collector.ProcessName, func(interval time.Duration) (service.Collector, error) {
+			targets := make([]collector.ProcessTarget, len(cfg.Processes))
+			for i := range cfg.Processes {
+				t, err := collector.ParseProcessTarget(cfg.Processes[i])
+				if err != nil {
+					return nil, err
+				}
+				targets[i] = t
+			}
+			return collector.NewProcess(interval, targets), nil
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, r.Validate()
+}
+
+// runCollector polls the collector every its interval until ctx is done
+func runCollector(ctx context.Context, c service.Collector, mc metricsCollector, logger service.AppLogger) {
+	t := time.NewTicker(c.Interval())
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			collectCtx, cancel := context.WithTimeout(ctx, c.Interval())
+			err := mc.Collect(collectCtx, c)
+			cancel()
+			if err != nil {
+				logger.Error(err.Error())
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func worker(w int, report func() error, jobs chan struct{}, logger service.AppLogger) {
 	for range jobs {
 		logger.Debug(fmt.Sprintf("Worker %d started job", w))
-		err := fn.DoWithRetryFn()
+		err := report()
 		if err != nil {
 			logger.Error(err.Error())
 		}
@@ -9,8 +9,10 @@ import (
 	"time"
 
 	"github.com/Chystik/runtime-metrics/config"
+	"github.com/Chystik/runtime-metrics/internal/adapters/graphite"
 	grpcapihandlers "github.com/Chystik/runtime-metrics/internal/adapters/grpc_api_handlers"
 	handlers "github.com/Chystik/runtime-metrics/internal/adapters/rest_api_handlers"
+	"github.com/Chystik/runtime-metrics/internal/adapters/statsd"
 	"github.com/Chystik/runtime-metrics/internal/interceptors"
 	pb "github.com/Chystik/runtime-metrics/protobuf"
 	"google.golang.org/grpc"
@@ -34,6 +36,12 @@ const (
 	logHTTPServerStop              = "Stopped serving new HTTP connections"
 	logGRPCServerStart             = "gRPC server started on port: %s"
 	logGRPCServerStop              = "Stopped serving new gRPC connections"
+	logStatsDServerStart           = "StatsD server started on: %s"
+	logStatsDServerStop            = "Stopped serving new StatsD packets"
+	logGracefulStatsDShutdown      = "Graceful shutdown of StatsD Server complete."
+	logGraphiteServerStart         = "Graphite server started on: %s"
+	logGraphiteServerStop          = "Stopped serving new Graphite connections"
+	logGracefulGraphiteShutdown    = "Graceful shutdown of Graphite Server complete."
 	logSignalInterrupt             = "Interrupt signal. Shutdown"
 	logGracefulHTTPServerShutdown  = "Graceful shutdown of HTTP Server complete."
 	logGracefulGRPCServerShutdown  = "Graceful shutdown of gRPC Server complete."
@@ -89,7 +97,7 @@ func Server(ctx context.Context, cfg *config.ServerConfig) {
 			logger,
 		)
 
-		meticsRepository = postgresrepo.NewMetricsRepo(pgClient.DB, r, logger)
+		meticsRepository = postgresrepo.NewMetricsRepo(cfg, pgClient.DB, r, logger)
 	} else if cfg.FileStoragePath != "" {
 		// fs storage
 		localStorage, err := localfs.NewMetricsStorage(cfg, inMemRepo)
@@ -160,6 +168,39 @@ func Server(ctx context.Context, cfg *config.ServerConfig) {
 		logger.Info(logGRPCServerStop)
 	}()
 
+	// statsd server
+	var statsdServer *statsd.Server
+	if cfg.StatsDAddress != "" {
+		if cfg.StatsDFlushInterval.Duration <= 0 {
+			logger.Fatal(fmt.Sprintf("StatsD flush interval must be positive, got %s", cfg.StatsDFlushInterval))
+		}
+		statsdServer = statsd.NewServer(cfg.StatsDAddress, metricsService, logger, cfg.StatsDFlushInterval.Duration)
+		go func() {
+			logger.Info(fmt.Sprintf(logStatsDServerStart, cfg.StatsDAddress))
+			if err := statsdServer.ListenAndServe(); !errors.Is(err, statsd.ErrServerClosed) {
+				logger.Fatal(err.Error())
+			}
+			logger.Info(logStatsDServerStop)
+		}()
+	}
+
+	// graphite server
+	var graphiteServer *graphite.Server
+	if cfg.GraphiteAddress != "" {
+		templates, err := graphite.ParseTemplates(cfg.GraphiteTemplates)
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		graphiteServer = graphite.NewServer(cfg.GraphiteAddress, metricsService, logger, templates)
+		go func() {
+			logger.Info(fmt.Sprintf(logGraphiteServerStart, cfg.GraphiteAddress))
+			if err := graphiteServer.ListenAndServe(); !errors.Is(err, graphite.ErrServerClosed) {
+				logger.Fatal(err.Error())
+			}
+			logger.Info(logGraphiteServerStop)
+		}()
+	}
+
 	// interrupt signal
 	<-ctx.Done()
 
@@ -167,6 +208,22 @@ func Server(ctx context.Context, cfg *config.ServerConfig) {
 	ctxShutdown, shutdown := context.WithTimeout(context.Background(), 5*time.Second)
 	defer shutdown()
 
+	// Graceful shutdown StatsD Server, flushes buffered metrics before the storage is closed
+	if statsdServer != nil {
+		if err := statsdServer.Shutdown(ctxShutdown); err != nil {
+			logger.Error(err.Error())
+		}
+		logger.Info(logGracefulStatsDShutdown)
+	}
+
+	// Graceful shutdown Graphite Server
+	if graphiteServer != nil {
+		if err := graphiteServer.Shutdown(ctxShutdown); err != nil {
+			logger.Error(err.Error())
+		}
+		logger.Info(logGracefulGraphiteShutdown)
+	}
+
 	// Graceful shutdown syncer
 	if cfg.DBDsn == "" {
 		if err := repoWithSyncer.Shutdown(ctxShutdown); err != nil {
@@ -0,0 +1 @@
+drop table if exists praktikum.samples
\ No newline at end of file
@@ -0,0 +1,9 @@
+create table if not exists praktikum.samples (
+    id varchar(50) not null,
+    m_type varchar(10) not null,
+    m_delta bigint,
+    m_value double precision,
+    ts timestamptz not null default now()
+);
+
+create index if not exists samples_id_ts_idx on praktikum.samples (id, ts);
@@ -0,0 +1,9 @@
+drop index if exists praktikum.samples_id_labels_ts_idx;
+create index if not exists samples_id_ts_idx on praktikum.samples (id, ts);
+
+alter table praktikum.samples drop column if exists labels;
+
+alter table praktikum.metrics drop constraint if exists metrics_id_labels_key;
+delete from praktikum.metrics where labels <> '{}';
+alter table praktikum.metrics drop column if exists labels;
+alter table praktikum.metrics add primary key (id);
\ No newline at end of file
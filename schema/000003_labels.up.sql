@@ -0,0 +1,10 @@
+alter table praktikum.metrics add column if not exists labels jsonb not null default '{}';
+
+alter table praktikum.metrics drop constraint if exists metrics_pkey;
+alter table praktikum.metrics drop constraint if exists metrics_id_key;
+alter table praktikum.metrics add constraint metrics_id_labels_key unique (id, labels);
+
+alter table praktikum.samples add column if not exists labels jsonb not null default '{}';
+
+drop index if exists praktikum.samples_id_ts_idx;
+create index if not exists samples_id_labels_ts_idx on praktikum.samples (id, labels, ts);
\ No newline at end of file
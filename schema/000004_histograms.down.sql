@@ -0,0 +1,2 @@
+delete from praktikum.metrics where m_type = 'histogram';
+alter table praktikum.metrics drop column if exists m_histogram;
\ No newline at end of file
@@ -0,0 +1 @@
+alter table praktikum.metrics add column if not exists m_histogram jsonb;
\ No newline at end of file
@@ -0,0 +1,2 @@
+delete from praktikum.metrics where m_type = 'summary';
+alter table praktikum.metrics drop column if exists m_summary;
\ No newline at end of file
@@ -0,0 +1 @@
+alter table praktikum.metrics add column if not exists m_summary jsonb;
\ No newline at end of file
@@ -0,0 +1 @@
+drop table if exists praktikum.batches;
\ No newline at end of file
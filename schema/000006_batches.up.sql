@@ -0,0 +1,6 @@
+create table if not exists praktikum.batches (
+    id varchar(64) primary key,
+    created_at timestamptz not null default now()
+);
+
+create index if not exists batches_created_at_idx on praktikum.batches (created_at);
\ No newline at end of file
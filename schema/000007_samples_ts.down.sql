@@ -0,0 +1 @@
+drop index if exists praktikum.samples_ts_idx;
\ No newline at end of file
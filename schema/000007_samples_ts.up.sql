@@ -0,0 +1 @@
+create index if not exists samples_ts_idx on praktikum.samples (ts);
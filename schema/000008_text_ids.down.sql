@@ -0,0 +1,3 @@
+alter table praktikum.batches alter column id type varchar(64);
+alter table praktikum.samples alter column id type varchar(50);
+alter table praktikum.metrics alter column id type varchar(50);
\ No newline at end of file
@@ -0,0 +1,3 @@
+alter table praktikum.metrics alter column id type text;
+alter table praktikum.samples alter column id type text;
+alter table praktikum.batches alter column id type text;